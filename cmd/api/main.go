@@ -14,6 +14,7 @@ import (
 	"github.com/qolby/sports-booking-api/internal/config"
 	"github.com/qolby/sports-booking-api/internal/database"
 	"github.com/qolby/sports-booking-api/internal/handlers"
+	"github.com/qolby/sports-booking-api/internal/jobs"
 	"github.com/qolby/sports-booking-api/internal/middleware"
 	"github.com/qolby/sports-booking-api/internal/services"
 )
@@ -50,25 +51,80 @@ func main() {
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, Idempotency-Key, X-Tenant",
 		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
 	}))
 
 	// Initialize services
 	db := database.GetDB()
 	authService := services.NewAuthService(db, cfg)
-	fieldService := services.NewFieldService(db)
-	bookingService := services.NewBookingService(db)
+	fieldService := services.NewFieldService(db, cfg)
+	bookingService := services.NewBookingService(db, cfg)
 	paymentService := services.NewPaymentService(db)
+	reviewService := services.NewReviewService(db)
+	favoriteService := services.NewFavoriteService(db)
+	savedSearchService := services.NewSavedSearchService(db)
+	notificationService := services.NewNotificationService(db)
+	waitlistService := services.NewWaitlistService(db)
+	orderService := services.NewOrderService(db, cfg)
+	splitService := services.NewSplitService(db)
+	idempotencyService := services.NewIdempotencyService(db)
+	promotionService := services.NewPromotionService(db, cfg)
+	walletService := services.NewWalletService(db, cfg)
+	membershipService := services.NewMembershipService(db, cfg)
+	invoiceService := services.NewInvoiceService(db, cfg)
+	taxService := services.NewTaxService(db)
+	ledgerService := services.NewLedgerService(db)
+	reconciliationService := services.NewReconciliationService(db)
+	payoutService := services.NewPayoutService(db, cfg)
+	tenantService := services.NewTenantService(db)
+	checkInService := services.NewCheckInService(db, cfg)
+	quotaService := services.NewQuotaService(db, cfg)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	fieldHandler := handlers.NewFieldHandler(fieldService)
 	bookingHandler := handlers.NewBookingHandler(bookingService)
 	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
+	favoriteHandler := handlers.NewFavoriteHandler(favoriteService)
+	savedSearchHandler := handlers.NewSavedSearchHandler(savedSearchService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	waitlistHandler := handlers.NewWaitlistHandler(waitlistService)
+	orderHandler := handlers.NewOrderHandler(orderService)
+	splitHandler := handlers.NewSplitHandler(splitService)
+	promotionHandler := handlers.NewPromotionHandler(promotionService)
+	walletHandler := handlers.NewWalletHandler(walletService)
+	membershipHandler := handlers.NewMembershipHandler(membershipService)
+	invoiceHandler := handlers.NewInvoiceHandler(invoiceService)
+	taxHandler := handlers.NewTaxHandler(taxService)
+	ledgerHandler := handlers.NewLedgerHandler(ledgerService)
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciliationService)
+	payoutHandler := handlers.NewPayoutHandler(payoutService)
+	tenantHandler := handlers.NewTenantHandler(tenantService)
+	checkInHandler := handlers.NewCheckInHandler(checkInService)
+	quotaHandler := handlers.NewQuotaHandler(quotaService)
+
+	// Background jobs
+	scheduler := jobs.NewScheduler()
+	scheduler.Every("expire-holds", cfg.Jobs.Interval, jobs.ExpireHolds(bookingService, cfg.Booking.HoldTTL))
+	scheduler.Every("no-shows", cfg.Jobs.Interval, jobs.MarkNoShows(checkInService))
+	scheduler.Every("complete-bookings", cfg.Jobs.Interval, jobs.CompleteBookings(bookingService))
+	scheduler.Every("waitlist", cfg.Jobs.Interval, jobs.ProcessWaitlist(waitlistService, cfg.Booking.WaitlistClaimWindow))
+	scheduler.Every("split-deadlines", cfg.Jobs.Interval, jobs.SettleSplitDeadlines(splitService))
+	scheduler.Every("availability-alerts", cfg.Jobs.Interval, jobs.MatchAvailabilityAlerts(savedSearchService))
+	scheduler.Every("renew-subscriptions", cfg.Jobs.Interval, jobs.RenewSubscriptions(membershipService))
+	scheduler.Every("purge-idempotency-keys", cfg.Jobs.Interval, jobs.PurgeIdempotencyKeys(idempotencyService))
+	scheduler.Every("payout-batches", cfg.Jobs.Interval, jobs.GeneratePayouts(payoutService))
+	if cfg.Reconciliation.SettlementDir != "" {
+		scheduler.Every("reconcile-settlements", cfg.Jobs.Interval, jobs.ReconcileSettlements(reconciliationService, cfg.Reconciliation.SettlementDir))
+	}
+	scheduler.Start()
+	defer scheduler.Stop()
 
 	// Routes
-	setupRoutes(app, cfg, authHandler, fieldHandler, bookingHandler, paymentHandler)
+	setupRoutes(app, cfg, authHandler, fieldHandler, bookingHandler, paymentHandler, reviewHandler,
+		favoriteHandler, savedSearchHandler, notificationHandler, waitlistHandler, orderHandler, splitHandler, promotionHandler, walletHandler, membershipHandler, invoiceHandler, taxHandler, ledgerHandler, reconciliationHandler, payoutHandler, tenantHandler, checkInHandler, quotaHandler, idempotencyService, tenantService)
 
 	// Start server
 	port := fmt.Sprintf(":%s", cfg.Server.Port)
@@ -86,12 +142,35 @@ func setupRoutes(
 	fieldHandler *handlers.FieldHandler,
 	bookingHandler *handlers.BookingHandler,
 	paymentHandler *handlers.PaymentHandler,
+	reviewHandler *handlers.ReviewHandler,
+	favoriteHandler *handlers.FavoriteHandler,
+	savedSearchHandler *handlers.SavedSearchHandler,
+	notificationHandler *handlers.NotificationHandler,
+	waitlistHandler *handlers.WaitlistHandler,
+	orderHandler *handlers.OrderHandler,
+	splitHandler *handlers.SplitHandler,
+	promotionHandler *handlers.PromotionHandler,
+	walletHandler *handlers.WalletHandler,
+	membershipHandler *handlers.MembershipHandler,
+	invoiceHandler *handlers.InvoiceHandler,
+	taxHandler *handlers.TaxHandler,
+	ledgerHandler *handlers.LedgerHandler,
+	reconciliationHandler *handlers.ReconciliationHandler,
+	payoutHandler *handlers.PayoutHandler,
+	tenantHandler *handlers.TenantHandler,
+	checkInHandler *handlers.CheckInHandler,
+	quotaHandler *handlers.QuotaHandler,
+	idempotencyService *services.IdempotencyService,
+	tenantService *services.TenantService,
 ) {
 	// Swagger route
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
-	// API v1
-	api := app.Group("/api/v1")
+	// API v1, scoped to the tenant named by the subdomain or X-Tenant header
+	api := app.Group("/api/v1", middleware.ResolveTenant(tenantService, cfg))
+
+	// Retries of mutating requests that carry an Idempotency-Key are replayed
+	idempotent := middleware.Idempotency(idempotencyService, cfg.Idempotency.KeyTTL, cfg.Idempotency.LockTimeout)
 
 	// Health check
 	api.Get("/health", func(c *fiber.Ctx) error {
@@ -108,8 +187,9 @@ func setupRoutes(
 
 	// Field routes
 	fields := api.Group("/fields")
-	fields.Get("/", fieldHandler.GetAllFields)    // Public
-	fields.Get("/:id", fieldHandler.GetFieldByID) // Public
+	fields.Get("/", fieldHandler.GetAllFields)                // Public
+	fields.Get("/:id", fieldHandler.GetFieldByID)             // Public
+	fields.Get("/:id/reviews", reviewHandler.GetFieldReviews) // Public
 
 	// Protected field routes (admin only)
 	fields.Post("/",
@@ -129,12 +209,126 @@ func setupRoutes(
 	)
 
 	// Booking routes (authenticated users)
-	bookings := api.Group("/bookings", middleware.AuthRequired(cfg))
+	bookings := api.Group("/bookings", middleware.AuthRequired(cfg), idempotent)
 	bookings.Post("/", bookingHandler.CreateBooking)
 	bookings.Get("/", bookingHandler.GetUserBookings)
+	bookings.Post("/quote", bookingHandler.QuoteBooking)
+	bookings.Post("/waitlist", waitlistHandler.JoinWaitlist)
+	bookings.Get("/waitlist", waitlistHandler.GetUserWaitlist)
+	bookings.Delete("/waitlist/:id", waitlistHandler.LeaveWaitlist)
 	bookings.Get("/:id", bookingHandler.GetBookingByID)
+	bookings.Get("/:id/history", bookingHandler.GetBookingHistory)
+	bookings.Get("/:id/qr", checkInHandler.GetBookingQR)
+	bookings.Post("/:id/cancel", bookingHandler.CancelBooking)
+	bookings.Post("/:id/reschedule", bookingHandler.RescheduleBooking)
+	bookings.Post("/:id/split", splitHandler.CreateSplit)
+	bookings.Get("/:id/split", splitHandler.GetSplit)
 
-	// Payment routes (authenticated users)
-	payments := api.Group("/payments", middleware.AuthRequired(cfg))
+	// Admin booking routes (admin only)
+	adminBookings := api.Group("/admin/bookings", middleware.AuthRequired(cfg), middleware.AdminOnly(), idempotent)
+	adminBookings.Get("/", bookingHandler.GetAllBookings)
+	adminBookings.Post("/", bookingHandler.CreateBookingForCustomer)
+
+	// Order routes (authenticated users)
+	orders := api.Group("/orders", middleware.AuthRequired(cfg), idempotent)
+	orders.Post("/", orderHandler.CreateOrder)
+	orders.Get("/", orderHandler.GetUserOrders)
+	orders.Get("/:id", orderHandler.GetOrderByID)
+	orders.Post("/:id/cancel", orderHandler.CancelOrder)
+
+	// Payment routes (authenticated users, refunds admin only, invoices for the payer or admins)
+	payments := api.Group("/payments", middleware.AuthRequired(cfg), idempotent)
 	payments.Post("/", paymentHandler.ProcessPayment)
+	payments.Post("/:id/refunds", middleware.AdminOnly(), paymentHandler.RefundPayment)
+	payments.Get("/:id/refunds", middleware.AdminOnly(), paymentHandler.GetPaymentRefunds)
+	payments.Get("/:id/invoice", invoiceHandler.GetPaymentInvoice)
+	payments.Get("/:id/invoices", invoiceHandler.GetPaymentInvoices)
+
+	// Check-in routes (front desk staff and admins)
+	checkins := api.Group("/checkins", middleware.AuthRequired(cfg), middleware.StaffOnly())
+	checkins.Post("/", checkInHandler.CheckIn)
+
+	// Promotion routes (admin only)
+	promotions := api.Group("/promotions", middleware.AuthRequired(cfg), middleware.AdminOnly())
+	promotions.Post("/", promotionHandler.CreatePromotion)
+	promotions.Get("/", promotionHandler.GetPromotions)
+	promotions.Put("/:id", promotionHandler.UpdatePromotion)
+
+	// Tax rate routes (admin only)
+	taxRates := api.Group("/tax-rates", middleware.AuthRequired(cfg), middleware.AdminOnly())
+	taxRates.Post("/", taxHandler.CreateTaxRate)
+	taxRates.Get("/", taxHandler.GetTaxRates)
+	taxRates.Put("/:id", taxHandler.UpdateTaxRate)
+
+	// Tenant routes (platform admin only)
+	tenants := api.Group("/tenants", middleware.AuthRequired(cfg), middleware.AdminOnly(), middleware.PlatformOnly())
+	tenants.Post("/", tenantHandler.CreateTenant)
+	tenants.Get("/", tenantHandler.GetTenants)
+	tenants.Put("/:id", tenantHandler.UpdateTenant)
+
+	// Ledger routes (platform admin only)
+	ledger := api.Group("/ledger", middleware.AuthRequired(cfg), middleware.AdminOnly(), middleware.PlatformOnly())
+	ledger.Get("/trial-balance", ledgerHandler.GetTrialBalance)
+
+	// Reconciliation routes (platform admin only)
+	reconciliation := api.Group("/reconciliation", middleware.AuthRequired(cfg), middleware.AdminOnly(), middleware.PlatformOnly())
+	reconciliation.Get("/summary", reconciliationHandler.GetSummary)
+	reconciliation.Get("/issues", reconciliationHandler.GetIssues)
+	reconciliation.Post("/issues/:id/resolve", reconciliationHandler.ResolveIssue)
+
+	// Payout batch routes (platform admin only)
+	payoutBatches := api.Group("/payout-batches", middleware.AuthRequired(cfg), middleware.AdminOnly(), middleware.PlatformOnly())
+	payoutBatches.Post("/", payoutHandler.GeneratePayoutBatch)
+	payoutBatches.Get("/", payoutHandler.GetPayoutBatches)
+	payoutBatches.Get("/:id/export", payoutHandler.ExportPayoutBatch)
+	payoutBatches.Post("/:id/paid", payoutHandler.MarkPayoutBatchPaid)
+
+	// Venue owner routes
+	owner := api.Group("/owner", middleware.AuthRequired(cfg), middleware.OwnerOnly())
+	owner.Get("/earnings", payoutHandler.GetEarnings)
+	owner.Get("/payouts", payoutHandler.GetPayouts)
+	owner.Put("/payout-account", payoutHandler.SetPayoutAccount)
+
+	// Plan routes (public listing, admin management)
+	plans := api.Group("/plans")
+	plans.Get("/", membershipHandler.GetPlans)
+	plans.Post("/", middleware.AuthRequired(cfg), middleware.AdminOnly(), membershipHandler.CreatePlan)
+
+	// Review routes (authenticated users, moderation admin only)
+	reviews := api.Group("/reviews", middleware.AuthRequired(cfg))
+	reviews.Post("/", reviewHandler.CreateReview)
+	reviews.Get("/", middleware.AdminOnly(), reviewHandler.GetReviews)
+	reviews.Put("/:id/moderate", middleware.AdminOnly(), reviewHandler.ModerateReview)
+
+	// Current user routes (authenticated users)
+	me := api.Group("/users/me", middleware.AuthRequired(cfg))
+	me.Get("/favorites", favoriteHandler.GetFavorites)
+	me.Post("/favorites/:fieldId", favoriteHandler.AddFavorite)
+	me.Delete("/favorites/:fieldId", favoriteHandler.RemoveFavorite)
+	me.Get("/saved-searches", savedSearchHandler.GetSavedSearches)
+	me.Post("/saved-searches", savedSearchHandler.CreateSavedSearch)
+	me.Delete("/saved-searches/:id", savedSearchHandler.DeleteSavedSearch)
+	me.Get("/payment-shares", splitHandler.GetUserShares)
+	me.Get("/notifications", notificationHandler.GetNotifications)
+	me.Put("/notifications/:id/read", notificationHandler.MarkAsRead)
+	me.Get("/wallet", walletHandler.GetWallet)
+	me.Get("/wallet/transactions", walletHandler.GetTransactions)
+	me.Post("/wallet/top-ups", idempotent, walletHandler.TopUp)
+	me.Get("/quotas", quotaHandler.GetMyQuotas)
+	me.Get("/subscriptions", membershipHandler.GetSubscriptions)
+	me.Post("/subscriptions", idempotent, membershipHandler.Subscribe)
+	me.Post("/subscriptions/:id/cancel", membershipHandler.CancelRenewal)
+
+	// User administration routes (admin only). Registered per route, since a
+	// /users group middleware would also run for /users/me
+	api.Post("/users/:id/wallet/adjustments",
+		middleware.AuthRequired(cfg),
+		middleware.AdminOnly(),
+		walletHandler.AdjustBalance,
+	)
+	api.Put("/users/:id/role",
+		middleware.AuthRequired(cfg),
+		middleware.AdminOnly(),
+		authHandler.SetUserRole,
+	)
 }
@@ -0,0 +1,52 @@
+// Command reconcile matches gateway settlement reports against recorded
+// payments once and exits. Pass report files (.csv or .json) as arguments,
+// or none to process the SETTLEMENT_REPORTS_DIR directory like the
+// scheduled job does. Discrepancies are stored as reconciliation issues.
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/database"
+	"github.com/qolby/sports-booking-api/internal/services"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if err := database.Connect(cfg); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	reconciliationService := services.NewReconciliationService(database.GetDB())
+
+	files := os.Args[1:]
+	if len(files) == 0 {
+		if cfg.Reconciliation.SettlementDir == "" {
+			log.Fatal("Usage: reconcile REPORT... (or set SETTLEMENT_REPORTS_DIR)")
+		}
+		issues, err := reconciliationService.ReconcileDirectory(cfg.Reconciliation.SettlementDir)
+		log.Printf("Flagged %d reconciliation issues", issues)
+		if err != nil {
+			log.Fatalf("Reconciliation failed: %v", err)
+		}
+		return
+	}
+
+	for _, file := range files {
+		records, err := services.ParseSettlementFile(file)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", file, err)
+		}
+		result, err := reconciliationService.Reconcile(filepath.Base(file), records)
+		if err != nil {
+			log.Fatalf("Failed to reconcile %s: %v", file, err)
+		}
+		log.Printf("%s: %d records, %d matched, %d new issues", file, result.Records, result.Matched, result.Issues)
+	}
+}
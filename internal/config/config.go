@@ -3,15 +3,26 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DB     DatabaseConfig
-	JWT    JWTConfig
-	Server ServerConfig
+	DB             DatabaseConfig
+	JWT            JWTConfig
+	Server         ServerConfig
+	Booking        BookingConfig
+	Jobs           JobsConfig
+	Idempotency    IdempotencyConfig
+	Invoice        InvoiceConfig
+	Currency       CurrencyConfig
+	Reconciliation ReconciliationConfig
+	Payout         PayoutConfig
+	Tenant         TenantConfig
+	CheckIn        CheckInConfig
+	Quota          QuotaConfig
 }
 
 type DatabaseConfig struct {
@@ -33,12 +44,127 @@ type ServerConfig struct {
 	Env  string
 }
 
+type BookingConfig struct {
+	HoldTTL              time.Duration
+	WaitlistClaimWindow  time.Duration
+	RescheduleMinNotice  time.Duration
+	MaxReschedules       int
+	AdvanceWindow        time.Duration
+	SessionRestoreNotice time.Duration
+	// DefaultTimezone is the IANA timezone of fields created without one,
+	// and of weeks that belong to no field
+	DefaultTimezone string
+}
+
+type JobsConfig struct {
+	Interval time.Duration
+}
+
+type IdempotencyConfig struct {
+	KeyTTL time.Duration
+	// LockTimeout is how long a request holds its key before a retry may
+	// take it over
+	LockTimeout time.Duration
+}
+
+type InvoiceConfig struct {
+	SellerName    string
+	SellerAddress string
+	SellerTaxID   string
+}
+
+type CurrencyConfig struct {
+	// Default is the ISO 4217 code used when a price is given without one
+	Default string
+}
+
+type ReconciliationConfig struct {
+	// SettlementDir is polled for gateway settlement reports; empty disables
+	// the job
+	SettlementDir string
+}
+
+type PayoutConfig struct {
+	// CommissionRate is the default platform commission on owned fields, in
+	// basis points
+	CommissionRate int
+	// Debtor* identify the platform bank account SEPA payouts are sent from
+	DebtorName string
+	DebtorIBAN string
+	DebtorBIC  string
+}
+
+type TenantConfig struct {
+	// BaseDomain is the domain tenants are served below, as <slug>.<domain>;
+	// empty resolves tenants from the X-Tenant header and tokens only
+	BaseDomain string
+	// DefaultSlug and DefaultName describe the tenant created for data from
+	// before the deployment was shared
+	DefaultSlug string
+	DefaultName string
+}
+
+type CheckInConfig struct {
+	// Secret signs booking QR codes
+	Secret string
+	// OpensBefore is how long before the start players can check in
+	OpensBefore time.Duration
+	// NoShowGrace is how long after the start a booking without check-in
+	// becomes a no-show; 0 turns no-show tracking off
+	NoShowGrace time.Duration
+	// BanThreshold no-shows within BanWindow suspend booking for
+	// BanDuration; 0 turns bans off
+	BanThreshold int
+	BanWindow    time.Duration
+	BanDuration  time.Duration
+}
+
+// QuotaConfig holds the fair-use limits on how much one player books; 0
+// turns a limit off. Membership plans can raise them, and staff and admins
+// are exempt.
+type QuotaConfig struct {
+	// MaxActiveBookings caps the bookings a player has not played yet
+	MaxActiveBookings int
+	// MaxWeeklyFieldHours and MaxWeeklySportHours cap the hours booked in
+	// one week on a field, and on all fields of a sport
+	MaxWeeklyFieldHours int
+	MaxWeeklySportHours int
+	// MaxWeeklyPrimeTime caps the bookings per week that overlap prime
+	// time, from PrimeTimeStart to PrimeTimeEnd ("HH:MM")
+	MaxWeeklyPrimeTime int
+	PrimeTimeStart     string
+	PrimeTimeEnd       string
+}
+
 func Load() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		fmt.Println("No .env file found, using environment variables")
 	}
 
 	jwtExpiry, _ := time.ParseDuration(getEnv("JWT_EXPIRY", "24h"))
+	holdTTL, _ := time.ParseDuration(getEnv("BOOKING_HOLD_TTL", "15m"))
+	claimWindow, _ := time.ParseDuration(getEnv("WAITLIST_CLAIM_WINDOW", "30m"))
+	rescheduleMinNotice, _ := time.ParseDuration(getEnv("RESCHEDULE_MIN_NOTICE", "24h"))
+	maxReschedules, _ := strconv.Atoi(getEnv("RESCHEDULE_MAX_COUNT", "2"))
+	advanceWindow, _ := time.ParseDuration(getEnv("BOOKING_ADVANCE_WINDOW", "720h"))
+	sessionRestoreNotice, _ := time.ParseDuration(getEnv("PACKAGE_RESTORE_NOTICE", "24h"))
+	defaultTimezone := getEnv("DEFAULT_TIMEZONE", "UTC")
+	if _, err := time.LoadLocation(defaultTimezone); err != nil {
+		return nil, fmt.Errorf("invalid DEFAULT_TIMEZONE: %w", err)
+	}
+	jobsInterval, _ := time.ParseDuration(getEnv("JOBS_INTERVAL", "1m"))
+	idempotencyKeyTTL, _ := time.ParseDuration(getEnv("IDEMPOTENCY_KEY_TTL", "24h"))
+	idempotencyLockTimeout, _ := time.ParseDuration(getEnv("IDEMPOTENCY_LOCK_TIMEOUT", "1m"))
+	commissionRate, _ := strconv.Atoi(getEnv("PLATFORM_COMMISSION_RATE", "1000"))
+	checkInOpensBefore, _ := time.ParseDuration(getEnv("CHECKIN_OPENS_BEFORE", "30m"))
+	noShowGrace, _ := time.ParseDuration(getEnv("NO_SHOW_GRACE", "15m"))
+	noShowBanThreshold, _ := strconv.Atoi(getEnv("NO_SHOW_BAN_THRESHOLD", "3"))
+	noShowBanWindow, _ := time.ParseDuration(getEnv("NO_SHOW_BAN_WINDOW", "2160h"))
+	noShowBanDuration, _ := time.ParseDuration(getEnv("NO_SHOW_BAN_DURATION", "336h"))
+	quotaActiveBookings, _ := strconv.Atoi(getEnv("QUOTA_MAX_ACTIVE_BOOKINGS", "10"))
+	quotaFieldHours, _ := strconv.Atoi(getEnv("QUOTA_WEEKLY_FIELD_HOURS", "0"))
+	quotaSportHours, _ := strconv.Atoi(getEnv("QUOTA_WEEKLY_SPORT_HOURS", "0"))
+	quotaPrimeTime, _ := strconv.Atoi(getEnv("QUOTA_WEEKLY_PRIME_TIME", "3"))
 
 	return &Config{
 		DB: DatabaseConfig{
@@ -57,6 +183,60 @@ func Load() (*Config, error) {
 			Port: getEnv("APP_PORT", "3000"),
 			Env:  getEnv("APP_ENV", "development"),
 		},
+		Booking: BookingConfig{
+			HoldTTL:              holdTTL,
+			WaitlistClaimWindow:  claimWindow,
+			RescheduleMinNotice:  rescheduleMinNotice,
+			MaxReschedules:       maxReschedules,
+			AdvanceWindow:        advanceWindow,
+			SessionRestoreNotice: sessionRestoreNotice,
+			DefaultTimezone:      defaultTimezone,
+		},
+		Jobs: JobsConfig{
+			Interval: jobsInterval,
+		},
+		Idempotency: IdempotencyConfig{
+			KeyTTL:      idempotencyKeyTTL,
+			LockTimeout: idempotencyLockTimeout,
+		},
+		Invoice: InvoiceConfig{
+			SellerName:    getEnv("INVOICE_SELLER_NAME", "Sports Field Booking"),
+			SellerAddress: getEnv("INVOICE_SELLER_ADDRESS", ""),
+			SellerTaxID:   getEnv("INVOICE_SELLER_TAX_ID", ""),
+		},
+		Currency: CurrencyConfig{
+			Default: getEnv("DEFAULT_CURRENCY", getEnv("INVOICE_CURRENCY", "IDR")),
+		},
+		Reconciliation: ReconciliationConfig{
+			SettlementDir: getEnv("SETTLEMENT_REPORTS_DIR", ""),
+		},
+		Payout: PayoutConfig{
+			CommissionRate: commissionRate,
+			DebtorName:     getEnv("PAYOUT_DEBTOR_NAME", getEnv("INVOICE_SELLER_NAME", "Sports Field Booking")),
+			DebtorIBAN:     getEnv("PAYOUT_DEBTOR_IBAN", ""),
+			DebtorBIC:      getEnv("PAYOUT_DEBTOR_BIC", ""),
+		},
+		Tenant: TenantConfig{
+			BaseDomain:  getEnv("TENANT_BASE_DOMAIN", ""),
+			DefaultSlug: getEnv("DEFAULT_TENANT_SLUG", "default"),
+			DefaultName: getEnv("DEFAULT_TENANT_NAME", getEnv("INVOICE_SELLER_NAME", "Sports Field Booking")),
+		},
+		CheckIn: CheckInConfig{
+			Secret:       getEnv("CHECKIN_SECRET", getEnv("JWT_SECRET", "your-secret-key")),
+			OpensBefore:  checkInOpensBefore,
+			NoShowGrace:  noShowGrace,
+			BanThreshold: noShowBanThreshold,
+			BanWindow:    noShowBanWindow,
+			BanDuration:  noShowBanDuration,
+		},
+		Quota: QuotaConfig{
+			MaxActiveBookings:   quotaActiveBookings,
+			MaxWeeklyFieldHours: quotaFieldHours,
+			MaxWeeklySportHours: quotaSportHours,
+			MaxWeeklyPrimeTime:  quotaPrimeTime,
+			PrimeTimeStart:      getEnv("PRIME_TIME_START", "17:00"),
+			PrimeTimeEnd:        getEnv("PRIME_TIME_END", "22:00"),
+		},
 	}, nil
 }
 
@@ -7,6 +7,7 @@ import (
 
 	"github.com/qolby/sports-booking-api/internal/config"
 	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/tenant"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -16,7 +17,7 @@ var DB *gorm.DB
 
 func Connect(cfg *config.Config) error {
 	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC",
 		cfg.DB.Host,
 		cfg.DB.User,
 		cfg.DB.Password,
@@ -28,11 +29,18 @@ func Connect(cfg *config.Config) error {
 	var err error
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
+		// Times are stored in UTC; fields know their local timezone
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Scope queries to the tenant of the request they are made for
+	if err := tenant.Register(DB); err != nil {
+		return fmt.Errorf("failed to register tenant scoping: %w", err)
+	}
+
 	// Configure connection pool
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -47,20 +55,288 @@ func Connect(cfg *config.Config) error {
 	log.Println("Database connected successfully with connection pooling")
 
 	// Auto migrate models
-	if err := autoMigrate(); err != nil {
+	if err := autoMigrate(cfg); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
 	return nil
 }
 
-func autoMigrate() error {
-	return DB.AutoMigrate(
+// moneyColumns lists the amounts that were plain integer columns before they
+// became models.Money, stored as <column>_amount and <column>_currency.
+var moneyColumns = map[string][]string{
+	"fields":                {"price_per_hour"},
+	"orders":                {"total_amount"},
+	"bookings":              {"subtotal", "discount_amount", "plan_discount", "net_amount", "tax_amount", "total_price"},
+	"payments":              {"amount", "net_amount", "tax_amount"},
+	"payment_adjustments":   {"amount"},
+	"refunds":               {"amount"},
+	"wallets":               {"balance"},
+	"wallet_transactions":   {"amount", "balance_after"},
+	"membership_plans":      {"price"},
+	"invoices":              {"subtotal", "discount_amount", "net_amount", "tax_amount", "total"},
+	"invoice_lines":         {"unit_price", "amount", "discount", "net", "tax", "total"},
+	"promotion_redemptions": {"amount"},
+	"payment_shares":        {"amount"},
+	"saved_searches":        {"max_price_per_hour"},
+}
+
+// tenantUniqueIndexes were unique across the deployment before tenants
+// existed, and are now unique per tenant under a new name.
+var tenantUniqueIndexes = []struct {
+	model interface{}
+	index string
+}{
+	{&models.User{}, "idx_users_email"},
+	{&models.Promotion{}, "idx_promotions_code"},
+	{&models.Invoice{}, "idx_invoices_number"},
+	{&models.Invoice{}, "idx_invoice_sequence"},
+}
+
+// tenantParents give the tenant of rows that predate their tenant_id: the
+// tenant of the row they belong to, rather than the default one.
+var tenantParents = []struct {
+	model  interface{}
+	parent string
+}{
+	{&models.SavedSearch{}, "SELECT tenant_id FROM users WHERE users.id = saved_searches.user_id"},
+	{&models.BookingEvent{}, "SELECT tenant_id FROM bookings WHERE bookings.id = booking_events.booking_id"},
+	{&models.PaymentAdjustment{}, "SELECT tenant_id FROM payments WHERE payments.id = payment_adjustments.payment_id"},
+	{&models.Refund{}, "SELECT tenant_id FROM payments WHERE payments.id = refunds.payment_id"},
+	{&models.Wallet{}, "SELECT tenant_id FROM users WHERE users.id = wallets.user_id"},
+	{&models.WalletTransaction{}, "SELECT tenant_id FROM wallets WHERE wallets.id = wallet_transactions.wallet_id"},
+	{&models.Subscription{}, "SELECT tenant_id FROM users WHERE users.id = subscriptions.user_id"},
+	{&models.OwnerEarning{}, "SELECT tenant_id FROM fields WHERE fields.id = owner_earnings.field_id"},
+	{&models.PayoutAccount{}, "SELECT tenant_id FROM users WHERE users.id = payout_accounts.owner_id"},
+	{&models.Payout{}, "SELECT tenant_id FROM users WHERE users.id = payouts.owner_id"},
+	{&models.PromotionRedemption{}, "SELECT tenant_id FROM bookings WHERE bookings.id = promotion_redemptions.booking_id"},
+	{&models.PaymentSplit{}, "SELECT tenant_id FROM bookings WHERE bookings.id = payment_splits.booking_id"},
+	{&models.PaymentShare{}, "SELECT tenant_id FROM bookings WHERE bookings.id = payment_shares.booking_id"},
+	{&models.FavoriteField{}, "SELECT tenant_id FROM users WHERE users.id = favorite_fields.user_id"},
+	{&models.Notification{}, "SELECT tenant_id FROM users WHERE users.id = notifications.user_id"},
+	{&models.WaitlistEntry{}, "SELECT tenant_id FROM fields WHERE fields.id = waitlist_entries.field_id"},
+}
+
+func autoMigrate(cfg *config.Config) error {
+	// Payments used to be strictly one per booking. Drop the old unique
+	// index so a booking-level index can replace it next to order payments.
+	if DB.Migrator().HasIndex(&models.Payment{}, "idx_payments_booking_id") {
+		if err := DB.Migrator().DropIndex(&models.Payment{}, "idx_payments_booking_id"); err != nil {
+			return err
+		}
+	}
+
+	// Payments used to have exactly one invoice. Follow-up charges and
+	// refunds now add invoices and credit notes for the same payment.
+	if DB.Migrator().HasIndex(&models.Invoice{}, "idx_invoices_payment_id") {
+		if err := DB.Migrator().DropIndex(&models.Invoice{}, "idx_invoices_payment_id"); err != nil {
+			return err
+		}
+	}
+
+	// Reconciliation issues used to be unique whether open or resolved, which
+	// kept a resolved discrepancy from ever being flagged again
+	if DB.Migrator().HasIndex(&models.ReconciliationIssue{}, "idx_reconciliation_issue") {
+		if err := DB.Migrator().DropIndex(&models.ReconciliationIssue{}, "idx_reconciliation_issue"); err != nil {
+			return err
+		}
+	}
+
+	for _, unique := range tenantUniqueIndexes {
+		if DB.Migrator().HasIndex(unique.model, unique.index) {
+			if err := DB.Migrator().DropIndex(unique.model, unique.index); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Invoice numbers used to run across every tenant. The sequences are
+	// rebuilt per tenant from the invoices once the table is migrated.
+	rebuildSequences := DB.Migrator().HasTable(&models.InvoiceSequence{}) && !DB.Migrator().HasColumn(&models.InvoiceSequence{}, "tenant_id")
+	if rebuildSequences {
+		if err := DB.Migrator().DropTable(&models.InvoiceSequence{}); err != nil {
+			return err
+		}
+	}
+
+	var backfillTenants []int
+	for i, owned := range tenantParents {
+		if DB.Migrator().HasTable(owned.model) && !DB.Migrator().HasColumn(owned.model, "tenant_id") {
+			backfillTenants = append(backfillTenants, i)
+		}
+	}
+
+	// Existing rows join the default tenant through the tenant_id column
+	// default, so that tenant has to exist first
+	if err := DB.AutoMigrate(&models.Tenant{}); err != nil {
+		return err
+	}
+	if err := ensureDefaultTenant(cfg.Tenant); err != nil {
+		return err
+	}
+	if err := backfillSellerDetails(cfg.Invoice); err != nil {
+		return err
+	}
+
+	// Keep existing amounts when moving them into Money columns
+	for table, columns := range moneyColumns {
+		for _, column := range columns {
+			if DB.Migrator().HasColumn(table, column) && !DB.Migrator().HasColumn(table, column+"_amount") {
+				if err := DB.Migrator().RenameColumn(table, column, column+"_amount"); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := DB.AutoMigrate(
 		&models.User{},
 		&models.Field{},
+		&models.Order{},
 		&models.Booking{},
+		&models.BookingEvent{},
+		&models.BookingReschedule{},
 		&models.Payment{},
-	)
+		&models.PaymentAdjustment{},
+		&models.Refund{},
+		&models.Wallet{},
+		&models.WalletTransaction{},
+		&models.MembershipPlan{},
+		&models.Subscription{},
+		&models.Invoice{},
+		&models.InvoiceLine{},
+		&models.InvoiceSequence{},
+		&models.TaxRate{},
+		&models.JournalEntry{},
+		&models.JournalLine{},
+		&models.ReconciliationIssue{},
+		&models.OwnerEarning{},
+		&models.PayoutAccount{},
+		&models.PayoutBatch{},
+		&models.Payout{},
+		&models.Promotion{},
+		&models.PromotionRedemption{},
+		&models.PaymentSplit{},
+		&models.PaymentShare{},
+		&models.Review{},
+		&models.FavoriteField{},
+		&models.SavedSearch{},
+		&models.Notification{},
+		&models.WaitlistEntry{},
+		&models.IdempotencyKey{},
+	); err != nil {
+		return err
+	}
+
+	if err := backfillCurrencies(cfg.Currency.Default); err != nil {
+		return err
+	}
+	if err := backfillTimezones(cfg.Booking.DefaultTimezone); err != nil {
+		return err
+	}
+	if rebuildSequences {
+		if err := rebuildInvoiceSequences(); err != nil {
+			return err
+		}
+	}
+	if err := DB.Model(&models.ReconciliationIssue{}).
+		Where("resolved_at IS NOT NULL AND status = ?", models.IssueOpen).
+		Update("status", models.IssueResolved).Error; err != nil {
+		return err
+	}
+	for _, i := range backfillTenants {
+		owned := tenantParents[i]
+		if err := DB.Model(owned.model).Unscoped().Where("tenant_id = ?", models.DefaultTenantID).
+			Update("tenant_id", gorm.Expr("COALESCE(("+owned.parent+"), ?)", models.DefaultTenantID)).Error; err != nil {
+			return err
+		}
+	}
+	return confirmSplitBookings()
+}
+
+// confirmSplitBookings moves bookings held by an open payment split, which
+// used to stay pending, to the confirmed status that keeps them from expiring.
+func confirmSplitBookings() error {
+	openSplits := DB.Model(&models.PaymentSplit{}).Select("booking_id").Where("status = ?", models.SplitOpen)
+	return DB.Model(&models.Booking{}).
+		Where("status = ? AND id IN (?)", models.StatusPending, openSplits).
+		Update("status", models.StatusConfirmed).Error
+}
+
+// rebuildInvoiceSequences continues each tenant's invoice numbers from the
+// last invoice it issued in each year.
+func rebuildInvoiceSequences() error {
+	return DB.Exec(`INSERT INTO invoice_sequences (tenant_id, year, last_number)
+		SELECT tenant_id, year, MAX(sequence) FROM invoices GROUP BY tenant_id, year`).Error
+}
+
+// ensureDefaultTenant creates the default tenant as the first one.
+func ensureDefaultTenant(cfg config.TenantConfig) error {
+	var count int64
+	if err := DB.Model(&models.Tenant{}).Count(&count).Error; err != nil || count > 0 {
+		return err
+	}
+
+	defaultTenant := models.Tenant{Slug: cfg.DefaultSlug, Name: cfg.DefaultName}
+	if err := DB.Create(&defaultTenant).Error; err != nil {
+		return err
+	}
+	if defaultTenant.ID != models.DefaultTenantID {
+		return fmt.Errorf("default tenant was created with ID %d", defaultTenant.ID)
+	}
+	return nil
+}
+
+// backfillSellerDetails gives the default tenant the seller details its
+// invoices were printed with before each tenant had its own.
+func backfillSellerDetails(seller config.InvoiceConfig) error {
+	return DB.Model(&models.Tenant{}).
+		Where("id = ? AND (seller_name IS NULL OR seller_name = '')", models.DefaultTenantID).
+		Updates(map[string]interface{}{"seller_name": seller.SellerName, "seller_address": seller.SellerAddress, "seller_tax_id": seller.SellerTaxID}).Error
+}
+
+// backfillCurrencies gives amounts stored before currencies existed the
+// default currency, which is the only one they could have been in.
+func backfillCurrencies(currency string) error {
+	for table, columns := range moneyColumns {
+		for _, column := range columns {
+			query := DB.Table(table).Where(fmt.Sprintf("%[1]s_currency IS NULL OR %[1]s_currency = ''", column))
+			if table == "saved_searches" {
+				// A search without a price cap has no currency
+				query = query.Where(column + "_amount > 0")
+			}
+			if err := query.Update(column+"_currency", currency).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return DB.Model(&models.Promotion{}).
+		Where("(currency IS NULL OR currency = '') AND (discount_type = ? OR min_spend > 0)", models.DiscountFixed).
+		Update("currency", currency).Error
+}
+
+// backfillTimezones gives fields created before timezones existed the
+// default timezone, bookings the timezone of their field and invoice lines
+// that of their booking.
+func backfillTimezones(timezone string) error {
+	if err := DB.Model(&models.Field{}).Unscoped().
+		Where("timezone IS NULL OR timezone = ''").
+		Update("timezone", timezone).Error; err != nil {
+		return err
+	}
+
+	fieldTimezone := DB.Model(&models.Field{}).Unscoped().Select("timezone").Where("fields.id = bookings.field_id")
+	if err := DB.Model(&models.Booking{}).Unscoped().
+		Where("timezone IS NULL OR timezone = ''").
+		Update("timezone", fieldTimezone).Error; err != nil {
+		return err
+	}
+
+	bookingTimezone := DB.Model(&models.Booking{}).Unscoped().Select("timezone").Where("bookings.id = invoice_lines.booking_id")
+	return DB.Model(&models.InvoiceLine{}).
+		Where("(timezone IS NULL OR timezone = '') AND booking_id IS NOT NULL").
+		Update("timezone", bookingTimezone).Error
 }
 
 func GetDB() *gorm.DB {
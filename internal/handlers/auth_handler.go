@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/qolby/sports-booking-api/internal/services"
 	"github.com/qolby/sports-booking-api/internal/utils"
@@ -30,7 +32,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
-	result, err := h.authService.Register(req)
+	result, err := h.authService.WithContext(c.UserContext()).Register(req)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Registration failed", err)
 	}
@@ -54,10 +56,43 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
-	result, err := h.authService.Login(req)
+	result, err := h.authService.WithContext(c.UserContext()).Login(req)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Login failed", err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Login successful", result)
 }
+
+// SetUserRole godoc
+// @Summary Set user role
+// @Description Make a player front desk staff, who can check players in, or a player again. Takes effect at the user's next login (Admin only)
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body services.SetRoleRequest true "New role"
+// @Success 200 {object} utils.Response{data=models.User}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /users/{id}/role [put]
+func (h *AuthHandler) SetUserRole(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+
+	var req services.SetRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	user, err := h.authService.WithContext(c.UserContext()).SetUserRole(uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to set role", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Role updated successfully", user)
+}
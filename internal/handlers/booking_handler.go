@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -24,7 +25,18 @@ func (h *BookingHandler) CreateBooking(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
-	booking, err := h.bookingService.CreateBooking(userID, req)
+	booking, err := h.bookingService.WithContext(c.UserContext()).CreateBooking(userID, req)
+	if errors.Is(err, services.ErrSlotTaken) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Time slot is taken, you can join the waitlist", err)
+	}
+	var quotaErr *services.QuotaError
+	if errors.As(err, &quotaErr) {
+		return utils.ErrorDetailsResponse(c, fiber.StatusForbidden, "Booking quota exceeded", err, quotaErr)
+	}
+	var ruleErr *services.BookingRuleError
+	if errors.As(err, &ruleErr) {
+		return utils.ValidationErrorResponse(c, "Time slot breaks the field's booking rules", err, ruleErr.Violations)
+	}
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create booking", err)
 	}
@@ -32,15 +44,40 @@ func (h *BookingHandler) CreateBooking(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, fiber.StatusCreated, "Booking created successfully", booking)
 }
 
+func (h *BookingHandler) QuoteBooking(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req services.CreateBookingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	quote, err := h.bookingService.WithContext(c.UserContext()).QuoteBooking(userID, req)
+	var ruleErr *services.BookingRuleError
+	if errors.As(err, &ruleErr) {
+		return utils.ValidationErrorResponse(c, "Time slot breaks the field's booking rules", err, ruleErr.Violations)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to quote booking", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Booking quoted successfully", quote)
+}
+
 func (h *BookingHandler) GetUserBookings(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(uint)
 
-	bookings, err := h.bookingService.GetUserBookings(userID)
+	q, err := listQuery(c)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch bookings", err)
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
 	}
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "Bookings retrieved successfully", bookings)
+	bookings, page, err := h.bookingService.WithContext(c.UserContext()).GetUserBookings(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch bookings", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Bookings retrieved successfully", bookings, page)
 }
 
 func (h *BookingHandler) GetBookingByID(c *fiber.Ctx) error {
@@ -49,10 +86,120 @@ func (h *BookingHandler) GetBookingByID(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid booking ID", err)
 	}
 
-	booking, err := h.bookingService.GetBookingByID(uint(id))
+	booking, err := h.bookingService.WithContext(c.UserContext()).GetBookingByID(uint(id))
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Booking not found", err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Booking retrieved successfully", booking)
 }
+
+func (h *BookingHandler) GetBookingHistory(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	isAdmin := c.Locals("userRole").(string) == "admin"
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid booking ID", err)
+	}
+
+	events, err := h.bookingService.WithContext(c.UserContext()).GetBookingHistory(userID, isAdmin, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Booking not found", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Booking history retrieved successfully", events)
+}
+
+func (h *BookingHandler) CancelBooking(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid booking ID", err)
+	}
+
+	booking, err := h.bookingService.WithContext(c.UserContext()).CancelBooking(userID, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to cancel booking", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Booking cancelled successfully", booking)
+}
+
+func (h *BookingHandler) RescheduleBooking(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid booking ID", err)
+	}
+
+	var req services.RescheduleBookingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	booking, err := h.bookingService.WithContext(c.UserContext()).RescheduleBooking(userID, uint(id), req)
+	if errors.Is(err, services.ErrSlotTaken) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Time slot is taken", err)
+	}
+	var quotaErr *services.QuotaError
+	if errors.As(err, &quotaErr) {
+		return utils.ErrorDetailsResponse(c, fiber.StatusForbidden, "Booking quota exceeded", err, quotaErr)
+	}
+	var ruleErr *services.BookingRuleError
+	if errors.As(err, &ruleErr) {
+		return utils.ValidationErrorResponse(c, "Time slot breaks the field's booking rules", err, ruleErr.Violations)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to reschedule booking", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Booking rescheduled successfully", booking)
+}
+
+func (h *BookingHandler) GetAllBookings(c *fiber.Ctx) error {
+	var filter services.AdminBookingFilter
+	if err := c.QueryParser(&filter); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	bookings, page, err := h.bookingService.WithContext(c.UserContext()).ListBookings(filter, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch bookings", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Bookings retrieved successfully", bookings, page)
+}
+
+func (h *BookingHandler) CreateBookingForCustomer(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	var req services.CustomerBookingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	booking, err := h.bookingService.WithContext(c.UserContext()).CreateBookingForCustomer(adminID, req)
+	if errors.Is(err, services.ErrSlotTaken) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Time slot is taken", err)
+	}
+	var quotaErr *services.QuotaError
+	if errors.As(err, &quotaErr) {
+		return utils.ErrorDetailsResponse(c, fiber.StatusForbidden, "Booking quota exceeded", err, quotaErr)
+	}
+	var ruleErr *services.BookingRuleError
+	if errors.As(err, &ruleErr) {
+		return utils.ValidationErrorResponse(c, "Time slot breaks the field's booking rules", err, ruleErr.Violations)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create booking", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Booking created successfully", booking)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type CheckInHandler struct {
+	checkInService *services.CheckInService
+}
+
+func NewCheckInHandler(checkInService *services.CheckInService) *CheckInHandler {
+	return &CheckInHandler{checkInService: checkInService}
+}
+
+// GetBookingQR godoc
+// @Summary Get booking QR code
+// @Description Get the signed check-in QR code of a paid booking as a PNG or SVG image (booking owner or admin)
+// @Tags Check-ins
+// @Produce png
+// @Produce image/svg+xml
+// @Security BearerAuth
+// @Param id path int true "Booking ID"
+// @Param format query string false "png (default) or svg"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /bookings/{id}/qr [get]
+func (h *CheckInHandler) GetBookingQR(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	isAdmin := c.Locals("userRole").(string) == "admin"
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid booking ID", err)
+	}
+
+	image, contentType, err := h.checkInService.WithContext(c.UserContext()).BookingQR(userID, isAdmin, uint(id), c.Query("format", "png"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to get QR code", err)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(image)
+}
+
+// CheckIn godoc
+// @Summary Check in a booking
+// @Description Check a player in by the code read from their booking QR code, from CHECKIN_OPENS_BEFORE the start until the no-show grace period ends (Staff or admin only)
+// @Tags Check-ins
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CheckInRequest true "Scanned code"
+// @Success 200 {object} utils.Response{data=models.Booking}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /checkins [post]
+func (h *CheckInHandler) CheckIn(c *fiber.Ctx) error {
+	staffID := c.Locals("userID").(uint)
+
+	var req services.CheckInRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	booking, err := h.checkInService.WithContext(c.UserContext()).CheckIn(staffID, req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Check-in failed", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Checked in successfully", booking)
+}
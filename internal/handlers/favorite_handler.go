@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type FavoriteHandler struct {
+	favoriteService *services.FavoriteService
+}
+
+func NewFavoriteHandler(favoriteService *services.FavoriteService) *FavoriteHandler {
+	return &FavoriteHandler{favoriteService: favoriteService}
+}
+
+// GetFavorites godoc
+// @Summary Get favorite fields
+// @Description Get the fields starred by the current user
+// @Tags Favorites
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Success 200 {object} utils.Response{data=[]models.FavoriteField}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/favorites [get]
+func (h *FavoriteHandler) GetFavorites(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	favorites, page, err := h.favoriteService.WithContext(c.UserContext()).GetFavorites(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch favorites", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Favorites retrieved successfully", favorites, page)
+}
+
+// AddFavorite godoc
+// @Summary Star a field
+// @Description Add a field to the current user's favorites
+// @Tags Favorites
+// @Produce json
+// @Security BearerAuth
+// @Param fieldId path int true "Field ID"
+// @Success 201 {object} utils.Response{data=models.FavoriteField}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/favorites/{fieldId} [post]
+func (h *FavoriteHandler) AddFavorite(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	fieldID, err := strconv.ParseUint(c.Params("fieldId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid field ID", err)
+	}
+
+	favorite, err := h.favoriteService.WithContext(c.UserContext()).AddFavorite(userID, uint(fieldID))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to add favorite", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Favorite added successfully", favorite)
+}
+
+// RemoveFavorite godoc
+// @Summary Unstar a field
+// @Description Remove a field from the current user's favorites
+// @Tags Favorites
+// @Security BearerAuth
+// @Param fieldId path int true "Field ID"
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /users/me/favorites/{fieldId} [delete]
+func (h *FavoriteHandler) RemoveFavorite(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	fieldID, err := strconv.ParseUint(c.Params("fieldId"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid field ID", err)
+	}
+
+	if err := h.favoriteService.WithContext(c.UserContext()).RemoveFavorite(userID, uint(fieldID)); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Failed to remove favorite", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Favorite removed successfully", nil)
+}
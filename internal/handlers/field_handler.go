@@ -35,7 +35,7 @@ func (h *FieldHandler) CreateField(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
-	field, err := h.fieldService.CreateField(req)
+	field, err := h.fieldService.WithContext(c.UserContext()).CreateField(req)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create field", err)
 	}
@@ -48,15 +48,25 @@ func (h *FieldHandler) CreateField(c *fiber.Ctx) error {
 // @Description Get list of all available sports fields
 // @Tags Fields
 // @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
 // @Success 200 {object} utils.Response{data=[]models.Field}
+// @Failure 400 {object} utils.Response
 // @Router /fields [get]
 func (h *FieldHandler) GetAllFields(c *fiber.Ctx) error {
-	fields, err := h.fieldService.GetAllFields()
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	fields, page, err := h.fieldService.WithContext(c.UserContext()).GetAllFields(q)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch fields", err)
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch fields", err)
 	}
 
-	return utils.SuccessResponse(c, fiber.StatusOK, "Fields retrieved successfully", fields)
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Fields retrieved successfully", fields, page)
 }
 
 // GetFieldByID godoc
@@ -74,7 +84,7 @@ func (h *FieldHandler) GetFieldByID(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid field ID", err)
 	}
 
-	field, err := h.fieldService.GetFieldByID(uint(id))
+	field, err := h.fieldService.WithContext(c.UserContext()).GetFieldByID(uint(id))
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Field not found", err)
 	}
@@ -107,7 +117,7 @@ func (h *FieldHandler) UpdateField(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
-	field, err := h.fieldService.UpdateField(uint(id), req)
+	field, err := h.fieldService.WithContext(c.UserContext()).UpdateField(uint(id), req)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update field", err)
 	}
@@ -131,7 +141,7 @@ func (h *FieldHandler) DeleteField(c *fiber.Ctx) error {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid field ID", err)
 	}
 
-	if err := h.fieldService.DeleteField(uint(id)); err != nil {
+	if err := h.fieldService.WithContext(c.UserContext()).DeleteField(uint(id)); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete field", err)
 	}
 
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type InvoiceHandler struct {
+	invoiceService *services.InvoiceService
+}
+
+func NewInvoiceHandler(invoiceService *services.InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{invoiceService: invoiceService}
+}
+
+// GetPaymentInvoices godoc
+// @Summary List invoices of a payment
+// @Description List the invoice, top-up invoices and credit notes of a payment in the order they were issued (payer or admin)
+// @Tags Payments
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /payments/{id}/invoices [get]
+func (h *InvoiceHandler) GetPaymentInvoices(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	isAdmin := c.Locals("userRole").(string) == "admin"
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payment ID", err)
+	}
+
+	invoices, err := h.invoiceService.WithContext(c.UserContext()).GetPaymentInvoices(userID, isAdmin, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to get invoices", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Invoices retrieved successfully", invoices)
+}
+
+// GetPaymentInvoice godoc
+// @Summary Download invoice
+// @Description Download the invoice of a payment as a PDF, or as HTML with format=html (payer or admin). Pass number for a top-up invoice or credit note of the payment.
+// @Tags Payments
+// @Produce application/pdf
+// @Produce html
+// @Security BearerAuth
+// @Param id path int true "Payment ID"
+// @Param format query string false "pdf (default) or html"
+// @Param number query string false "Invoice or credit note number (default: the payment's invoice)"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /payments/{id}/invoice [get]
+func (h *InvoiceHandler) GetPaymentInvoice(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+	isAdmin := c.Locals("userRole").(string) == "admin"
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payment ID", err)
+	}
+
+	invoice, err := h.invoiceService.WithContext(c.UserContext()).GetPaymentInvoice(userID, isAdmin, uint(id), c.Query("number"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Invoice not found", err)
+	}
+
+	switch c.Query("format", "pdf") {
+	case "pdf":
+		body, err := h.invoiceService.WithContext(c.UserContext()).RenderPDF(invoice)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to render invoice", err)
+		}
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.pdf"`, invoice.Number))
+		return c.Send(body)
+	case "html":
+		body, err := h.invoiceService.WithContext(c.UserContext()).RenderHTML(invoice)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to render invoice", err)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(body)
+	default:
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "format must be pdf or html", nil)
+	}
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type LedgerHandler struct {
+	ledgerService *services.LedgerService
+}
+
+func NewLedgerHandler(ledgerService *services.LedgerService) *LedgerHandler {
+	return &LedgerHandler{ledgerService: ledgerService}
+}
+
+// GetTrialBalance godoc
+// @Summary Get trial balance
+// @Description Total debits and credits of every ledger account, per currency (Admin only)
+// @Tags Ledger
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=[]services.TrialBalance}
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /ledger/trial-balance [get]
+func (h *LedgerHandler) GetTrialBalance(c *fiber.Ctx) error {
+	balances, err := h.ledgerService.WithContext(c.UserContext()).TrialBalance()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to compute trial balance", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Trial balance retrieved successfully", balances)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+)
+
+// listQuery reads the paging, sorting and filtering parameters every list
+// endpoint takes.
+func listQuery(c *fiber.Ctx) (services.ListQuery, error) {
+	var q services.ListQuery
+	err := c.QueryParser(&q)
+	return q, err
+}
+
+// listErrorStatus answers 400 for a list query the list cannot run, and
+// status for anything else.
+func listErrorStatus(err error, status int) int {
+	if errors.Is(err, services.ErrInvalidListQuery) {
+		return fiber.StatusBadRequest
+	}
+	return status
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type MembershipHandler struct {
+	membershipService *services.MembershipService
+}
+
+func NewMembershipHandler(membershipService *services.MembershipService) *MembershipHandler {
+	return &MembershipHandler{membershipService: membershipService}
+}
+
+// CreatePlan godoc
+// @Summary Create plan
+// @Description Define a membership or a session package for sale (Admin only)
+// @Tags Memberships
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreatePlanRequest true "Plan details"
+// @Success 201 {object} utils.Response{data=models.MembershipPlan}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /plans [post]
+func (h *MembershipHandler) CreatePlan(c *fiber.Ctx) error {
+	var req services.CreatePlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	plan, err := h.membershipService.WithContext(c.UserContext()).CreatePlan(req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create plan", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Plan created successfully", plan)
+}
+
+// GetPlans godoc
+// @Summary List plans
+// @Description List the memberships and session packages on sale
+// @Tags Memberships
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Success 200 {object} utils.Response{data=[]models.MembershipPlan}
+// @Failure 400 {object} utils.Response
+// @Router /plans [get]
+func (h *MembershipHandler) GetPlans(c *fiber.Ctx) error {
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	plans, page, err := h.membershipService.WithContext(c.UserContext()).GetPlans(q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch plans", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Plans retrieved successfully", plans, page)
+}
+
+// Subscribe godoc
+// @Summary Buy a plan
+// @Description Buy a membership or session package with a gateway payment method or the wallet
+// @Tags Memberships
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.SubscribeRequest true "Subscription details"
+// @Success 201 {object} utils.Response{data=models.Subscription}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/subscriptions [post]
+func (h *MembershipHandler) Subscribe(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req services.SubscribeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	subscription, err := h.membershipService.WithContext(c.UserContext()).Subscribe(userID, req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to buy plan", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Plan purchased successfully", subscription)
+}
+
+// GetSubscriptions godoc
+// @Summary List subscriptions
+// @Description List the current user's memberships and packages with their remaining sessions
+// @Tags Memberships
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param status query string false "Comma separated statuses"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Param upcoming query bool false "Only what has not ended yet"
+// @Success 200 {object} utils.Response{data=[]models.Subscription}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/subscriptions [get]
+func (h *MembershipHandler) GetSubscriptions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	subscriptions, page, err := h.membershipService.WithContext(c.UserContext()).GetUserSubscriptions(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch subscriptions", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Subscriptions retrieved successfully", subscriptions, page)
+}
+
+// CancelRenewal godoc
+// @Summary Stop renewal
+// @Description Stop a membership from renewing; it stays valid until it ends
+// @Tags Memberships
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Subscription ID"
+// @Success 200 {object} utils.Response{data=models.Subscription}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/subscriptions/{id}/cancel [post]
+func (h *MembershipHandler) CancelRenewal(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid subscription ID", err)
+	}
+
+	subscription, err := h.membershipService.WithContext(c.UserContext()).CancelRenewal(userID, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to cancel renewal", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Renewal cancelled successfully", subscription)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type NotificationHandler struct {
+	notificationService *services.NotificationService
+}
+
+func NewNotificationHandler(notificationService *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notificationService: notificationService}
+}
+
+// GetNotifications godoc
+// @Summary Get notifications
+// @Description Get the current user's notifications, newest first
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "Only unread notifications"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Success 200 {object} utils.Response{data=[]models.Notification}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/notifications [get]
+func (h *NotificationHandler) GetNotifications(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	notifications, page, err := h.notificationService.WithContext(c.UserContext()).GetUserNotifications(userID, c.QueryBool("unread"), q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch notifications", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Notifications retrieved successfully", notifications, page)
+}
+
+// MarkAsRead godoc
+// @Summary Mark notification as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} utils.Response{data=models.Notification}
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /users/me/notifications/{id}/read [put]
+func (h *NotificationHandler) MarkAsRead(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid notification ID", err)
+	}
+
+	notification, err := h.notificationService.WithContext(c.UserContext()).MarkAsRead(userID, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Notification not found", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Notification marked as read", notification)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type OrderHandler struct {
+	orderService *services.OrderService
+}
+
+func NewOrderHandler(orderService *services.OrderService) *OrderHandler {
+	return &OrderHandler{orderService: orderService}
+}
+
+func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req services.CreateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	order, err := h.orderService.WithContext(c.UserContext()).CreateOrder(userID, req)
+	if errors.Is(err, services.ErrSlotTaken) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "One of the time slots is taken", err)
+	}
+	var quotaErr *services.QuotaError
+	if errors.As(err, &quotaErr) {
+		return utils.ErrorDetailsResponse(c, fiber.StatusForbidden, "Booking quota exceeded", err, quotaErr)
+	}
+	var ruleErr *services.BookingRuleError
+	if errors.As(err, &ruleErr) {
+		return utils.ValidationErrorResponse(c, "One of the time slots breaks the field's booking rules", err, ruleErr.Violations)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create order", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Order created successfully", order)
+}
+
+func (h *OrderHandler) GetUserOrders(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	orders, page, err := h.orderService.WithContext(c.UserContext()).GetUserOrders(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch orders", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Orders retrieved successfully", orders, page)
+}
+
+func (h *OrderHandler) GetOrderByID(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid order ID", err)
+	}
+
+	order, err := h.orderService.WithContext(c.UserContext()).GetOrderByID(userID, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Order not found", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Order retrieved successfully", order)
+}
+
+func (h *OrderHandler) CancelOrder(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid order ID", err)
+	}
+
+	order, err := h.orderService.WithContext(c.UserContext()).CancelOrder(userID, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to cancel order", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Order cancelled successfully", order)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"strconv"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/qolby/sports-booking-api/internal/services"
 	"github.com/qolby/sports-booking-api/internal/utils"
@@ -15,15 +18,60 @@ func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler
 }
 
 func (h *PaymentHandler) ProcessPayment(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
 	var req services.CreatePaymentRequest
 	if err := c.BodyParser(&req); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
 	}
 
-	payment, err := h.paymentService.ProcessPayment(req)
+	payment, err := h.paymentService.WithContext(c.UserContext()).ProcessPayment(userID, req)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Payment processing failed", err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Payment processed successfully", payment)
 }
+
+func (h *PaymentHandler) RefundPayment(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payment ID", err)
+	}
+
+	var req services.RefundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	refund, err := h.paymentService.WithContext(c.UserContext()).RefundPayment(adminID, uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Refund failed", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Refund processed successfully", refund)
+}
+
+func (h *PaymentHandler) GetPaymentRefunds(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payment ID", err)
+	}
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	refunds, page, err := h.paymentService.WithContext(c.UserContext()).GetPaymentRefunds(uint(id), q)
+	if errors.Is(err, services.ErrInvalidListQuery) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Payment not found", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Refunds retrieved successfully", refunds, page)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type PayoutHandler struct {
+	payoutService *services.PayoutService
+}
+
+func NewPayoutHandler(payoutService *services.PayoutService) *PayoutHandler {
+	return &PayoutHandler{payoutService: payoutService}
+}
+
+// GetEarnings godoc
+// @Summary Get owner earnings
+// @Description List your earnings from paid bookings, less the platform commission, with totals per currency (Owner only)
+// @Tags Owners
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=services.OwnerEarnings}
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /owner/earnings [get]
+func (h *PayoutHandler) GetEarnings(c *fiber.Ctx) error {
+	ownerID := c.Locals("userID").(uint)
+
+	earnings, err := h.payoutService.WithContext(c.UserContext()).GetEarnings(ownerID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch earnings", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Earnings retrieved successfully", earnings)
+}
+
+// GetPayouts godoc
+// @Summary Get owner payouts
+// @Description List the payouts made to you (Owner only)
+// @Tags Owners
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param status query string false "Comma separated statuses"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Success 200 {object} utils.Response{data=[]models.Payout}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /owner/payouts [get]
+func (h *PayoutHandler) GetPayouts(c *fiber.Ctx) error {
+	ownerID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	payouts, page, err := h.payoutService.WithContext(c.UserContext()).GetPayouts(ownerID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch payouts", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Payouts retrieved successfully", payouts, page)
+}
+
+// SetPayoutAccount godoc
+// @Summary Set payout account
+// @Description Set the bank account your payouts are sent to (Owner only)
+// @Tags Owners
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.PayoutAccountRequest true "Bank account"
+// @Success 200 {object} utils.Response{data=models.PayoutAccount}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /owner/payout-account [put]
+func (h *PayoutHandler) SetPayoutAccount(c *fiber.Ctx) error {
+	ownerID := c.Locals("userID").(uint)
+
+	var req services.PayoutAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	account, err := h.payoutService.WithContext(c.UserContext()).SetPayoutAccount(ownerID, req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to set payout account", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Payout account saved successfully", account)
+}
+
+// GeneratePayoutBatch godoc
+// @Summary Generate payout batch
+// @Description Pay out owner earnings made before this week, if this week's batch has not been made yet (Admin only)
+// @Tags Payouts
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} utils.Response{data=models.PayoutBatch}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /payout-batches [post]
+func (h *PayoutHandler) GeneratePayoutBatch(c *fiber.Ctx) error {
+	batch, err := h.payoutService.WithContext(c.UserContext()).GeneratePayoutBatch(time.Now())
+	if errors.Is(err, services.ErrPayoutBatchExists) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Failed to generate payout batch", err)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to generate payout batch", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Payout batch generated successfully", batch)
+}
+
+// GetPayoutBatches godoc
+// @Summary List payout batches
+// @Description List payout batches with their payouts, newest first (Admin only)
+// @Tags Payouts
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param status query string false "Comma separated statuses"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Success 200 {object} utils.Response{data=[]models.PayoutBatch}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /payout-batches [get]
+func (h *PayoutHandler) GetPayoutBatches(c *fiber.Ctx) error {
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	batches, page, err := h.payoutService.WithContext(c.UserContext()).GetBatches(q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch payout batches", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Payout batches retrieved successfully", batches, page)
+}
+
+// ExportPayoutBatch godoc
+// @Summary Export payout batch
+// @Description Download a payout batch as CSV, or its euro payouts as a SEPA credit transfer (pain.001) with format=sepa (Admin only)
+// @Tags Payouts
+// @Produce text/csv
+// @Produce xml
+// @Security BearerAuth
+// @Param id path int true "Payout batch ID"
+// @Param format query string false "csv (default) or sepa"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /payout-batches/{id}/export [get]
+func (h *PayoutHandler) ExportPayoutBatch(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payout batch ID", err)
+	}
+
+	batch, err := h.payoutService.WithContext(c.UserContext()).GetBatch(uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Payout batch not found", err)
+	}
+
+	switch c.Query("format", "csv") {
+	case "csv":
+		body, err := h.payoutService.WithContext(c.UserContext()).ExportCSV(batch)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to export payout batch", err)
+		}
+		c.Set(fiber.HeaderContentType, "text/csv")
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.csv"`, batch.Reference))
+		return c.Send(body)
+	case "sepa":
+		body, err := h.payoutService.WithContext(c.UserContext()).ExportSEPA(batch)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to export payout batch", err)
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.xml"`, batch.Reference))
+		return c.Send(body)
+	default:
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid export format", nil)
+	}
+}
+
+// MarkPayoutBatchPaid godoc
+// @Summary Mark payout batch paid
+// @Description Record that the bank has executed a payout batch (Admin only)
+// @Tags Payouts
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Payout batch ID"
+// @Success 200 {object} utils.Response{data=models.PayoutBatch}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /payout-batches/{id}/paid [post]
+func (h *PayoutHandler) MarkPayoutBatchPaid(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid payout batch ID", err)
+	}
+
+	batch, err := h.payoutService.WithContext(c.UserContext()).MarkBatchPaid(uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to mark payout batch paid", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Payout batch marked paid", batch)
+}
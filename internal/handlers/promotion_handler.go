@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type PromotionHandler struct {
+	promotionService *services.PromotionService
+}
+
+func NewPromotionHandler(promotionService *services.PromotionService) *PromotionHandler {
+	return &PromotionHandler{promotionService: promotionService}
+}
+
+// CreatePromotion godoc
+// @Summary Create promo code
+// @Description Create a percentage or fixed discount code with optional restrictions (Admin only)
+// @Tags Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreatePromotionRequest true "Promotion details"
+// @Success 201 {object} utils.Response{data=models.Promotion}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /promotions [post]
+func (h *PromotionHandler) CreatePromotion(c *fiber.Ctx) error {
+	var req services.CreatePromotionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	promotion, err := h.promotionService.WithContext(c.UserContext()).CreatePromotion(req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create promotion", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Promotion created successfully", promotion)
+}
+
+// GetPromotions godoc
+// @Summary List promo codes
+// @Description List every promotion (Admin only)
+// @Tags Promotions
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Success 200 {object} utils.Response{data=[]models.Promotion}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /promotions [get]
+func (h *PromotionHandler) GetPromotions(c *fiber.Ctx) error {
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	promotions, page, err := h.promotionService.WithContext(c.UserContext()).GetPromotions(q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch promotions", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Promotions retrieved successfully", promotions, page)
+}
+
+// UpdatePromotion godoc
+// @Summary Update promo code
+// @Description Extend, cap or deactivate a promotion (Admin only)
+// @Tags Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Promotion ID"
+// @Param request body services.UpdatePromotionRequest true "Changes"
+// @Success 200 {object} utils.Response{data=models.Promotion}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /promotions/{id} [put]
+func (h *PromotionHandler) UpdatePromotion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid promotion ID", err)
+	}
+
+	var req services.UpdatePromotionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	promotion, err := h.promotionService.WithContext(c.UserContext()).UpdatePromotion(uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to update promotion", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Promotion updated successfully", promotion)
+}
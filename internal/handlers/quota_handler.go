@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type QuotaHandler struct {
+	quotaService *services.QuotaService
+}
+
+func NewQuotaHandler(quotaService *services.QuotaService) *QuotaHandler {
+	return &QuotaHandler{quotaService: quotaService}
+}
+
+// GetMyQuotas godoc
+// @Summary Get booking quotas
+// @Description Get how much of each booking quota the current user has used and has left this week
+// @Tags Quotas
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=services.UserQuotas}
+// @Failure 401 {object} utils.Response
+// @Router /users/me/quotas [get]
+func (h *QuotaHandler) GetMyQuotas(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	quotas, err := h.quotaService.WithContext(c.UserContext()).GetUserQuotas(userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch quotas", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Quotas retrieved successfully", quotas)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type ReconciliationHandler struct {
+	reconciliationService *services.ReconciliationService
+}
+
+func NewReconciliationHandler(reconciliationService *services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+// GetIssues godoc
+// @Summary List reconciliation issues
+// @Description List discrepancies between payments and gateway settlement reports, newest first (Admin only)
+// @Tags Reconciliation
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Comma separated statuses (open, resolved), or all" default(open)
+// @Param type query string false "not_captured, not_recorded or amount_mismatch"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Success 200 {object} utils.Response{data=[]models.ReconciliationIssue}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /reconciliation/issues [get]
+func (h *ReconciliationHandler) GetIssues(c *fiber.Ctx) error {
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	issues, page, err := h.reconciliationService.WithContext(c.UserContext()).GetIssues(models.ReconciliationIssueType(c.Query("type")), q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch reconciliation issues", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Reconciliation issues retrieved successfully", issues, page)
+}
+
+// GetSummary godoc
+// @Summary Reconciliation summary
+// @Description Count the open reconciliation issues, in all and per type (Admin only)
+// @Tags Reconciliation
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=services.ReconciliationSummary}
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /reconciliation/summary [get]
+func (h *ReconciliationHandler) GetSummary(c *fiber.Ctx) error {
+	summary, err := h.reconciliationService.WithContext(c.UserContext()).GetSummary()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch reconciliation summary", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Reconciliation summary retrieved successfully", summary)
+}
+
+// ResolveIssue godoc
+// @Summary Resolve reconciliation issue
+// @Description Close a reconciliation issue with a note on how it was resolved (Admin only)
+// @Tags Reconciliation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Issue ID"
+// @Param request body services.ResolveIssueRequest true "Resolution"
+// @Success 200 {object} utils.Response{data=models.ReconciliationIssue}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /reconciliation/issues/{id}/resolve [post]
+func (h *ReconciliationHandler) ResolveIssue(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid issue ID", err)
+	}
+
+	var req services.ResolveIssueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	issue, err := h.reconciliationService.WithContext(c.UserContext()).ResolveIssue(adminID, uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to resolve issue", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Issue resolved successfully", issue)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type ReviewHandler struct {
+	reviewService *services.ReviewService
+}
+
+func NewReviewHandler(reviewService *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{reviewService: reviewService}
+}
+
+// CreateReview godoc
+// @Summary Review a field
+// @Description Rate a field after a paid booking on it has ended (one review per booking)
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreateReviewRequest true "Review details"
+// @Success 201 {object} utils.Response{data=models.Review}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /reviews [post]
+func (h *ReviewHandler) CreateReview(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req services.CreateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	review, err := h.reviewService.WithContext(c.UserContext()).CreateReview(userID, req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create review", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Review created successfully", review)
+}
+
+// GetFieldReviews godoc
+// @Summary Get field reviews
+// @Description Get the published reviews of a field
+// @Tags Reviews
+// @Produce json
+// @Param id path int true "Field ID"
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Success 200 {object} utils.Response{data=[]models.Review}
+// @Failure 400 {object} utils.Response
+// @Router /fields/{id}/reviews [get]
+func (h *ReviewHandler) GetFieldReviews(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid field ID", err)
+	}
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	reviews, page, err := h.reviewService.WithContext(c.UserContext()).GetFieldReviews(uint(id), q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch reviews", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Reviews retrieved successfully", reviews, page)
+}
+
+// GetReviews godoc
+// @Summary List reviews by status
+// @Description List reviews awaiting moderation (Admin only)
+// @Tags Reviews
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Comma separated review statuses (visible, flagged, hidden)" default(flagged)
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Success 200 {object} utils.Response{data=[]models.Review}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /reviews [get]
+func (h *ReviewHandler) GetReviews(c *fiber.Ctx) error {
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	reviews, page, err := h.reviewService.WithContext(c.UserContext()).GetReviews(q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch reviews", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Reviews retrieved successfully", reviews, page)
+}
+
+// ModerateReview godoc
+// @Summary Moderate review
+// @Description Hide, flag or restore a review (Admin only)
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Review ID"
+// @Param request body services.ModerateReviewRequest true "New status"
+// @Success 200 {object} utils.Response{data=models.Review}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /reviews/{id}/moderate [put]
+func (h *ReviewHandler) ModerateReview(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid review ID", err)
+	}
+
+	var req services.ModerateReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	review, err := h.reviewService.WithContext(c.UserContext()).ModerateReview(uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to moderate review", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Review updated successfully", review)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type SavedSearchHandler struct {
+	savedSearchService *services.SavedSearchService
+}
+
+func NewSavedSearchHandler(savedSearchService *services.SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{savedSearchService: savedSearchService}
+}
+
+// CreateSavedSearch godoc
+// @Summary Save an availability search
+// @Description Save a field or filter set with a weekday and time window to get alerted when a matching slot frees up
+// @Tags Saved Searches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreateSavedSearchRequest true "Search details"
+// @Success 201 {object} utils.Response{data=models.SavedSearch}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/saved-searches [post]
+func (h *SavedSearchHandler) CreateSavedSearch(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req services.CreateSavedSearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	search, err := h.savedSearchService.WithContext(c.UserContext()).CreateSavedSearch(userID, req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to save search", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Search saved successfully", search)
+}
+
+// GetSavedSearches godoc
+// @Summary Get saved searches
+// @Description Get the availability searches saved by the current user
+// @Tags Saved Searches
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Success 200 {object} utils.Response{data=[]models.SavedSearch}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/saved-searches [get]
+func (h *SavedSearchHandler) GetSavedSearches(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	searches, page, err := h.savedSearchService.WithContext(c.UserContext()).GetSavedSearches(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch saved searches", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Saved searches retrieved successfully", searches, page)
+}
+
+// DeleteSavedSearch godoc
+// @Summary Delete saved search
+// @Description Delete one of the current user's saved searches
+// @Tags Saved Searches
+// @Security BearerAuth
+// @Param id path int true "Saved search ID"
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /users/me/saved-searches/{id} [delete]
+func (h *SavedSearchHandler) DeleteSavedSearch(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid saved search ID", err)
+	}
+
+	if err := h.savedSearchService.WithContext(c.UserContext()).DeleteSavedSearch(userID, uint(id)); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Failed to delete saved search", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Saved search deleted successfully", nil)
+}
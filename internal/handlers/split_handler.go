@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type SplitHandler struct {
+	splitService *services.SplitService
+}
+
+func NewSplitHandler(splitService *services.SplitService) *SplitHandler {
+	return &SplitHandler{splitService: splitService}
+}
+
+func (h *SplitHandler) CreateSplit(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid booking ID", err)
+	}
+
+	var req services.CreateSplitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	split, err := h.splitService.WithContext(c.UserContext()).CreateSplit(userID, uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to split payment", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Payment split created successfully", split)
+}
+
+func (h *SplitHandler) GetSplit(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid booking ID", err)
+	}
+
+	split, err := h.splitService.WithContext(c.UserContext()).GetSplit(userID, uint(id))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Payment split not found", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Payment split retrieved successfully", split)
+}
+
+func (h *SplitHandler) GetUserShares(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	shares, page, err := h.splitService.WithContext(c.UserContext()).GetUserShares(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch payment shares", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Payment shares retrieved successfully", shares, page)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type TaxHandler struct {
+	taxService *services.TaxService
+}
+
+func NewTaxHandler(taxService *services.TaxService) *TaxHandler {
+	return &TaxHandler{taxService: taxService}
+}
+
+// CreateTaxRate godoc
+// @Summary Create tax rate
+// @Description Create a tax rate in basis points for a sport category, or the default rate when no category is given (Admin only)
+// @Tags Taxes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreateTaxRateRequest true "Tax rate details"
+// @Success 201 {object} utils.Response{data=models.TaxRate}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /tax-rates [post]
+func (h *TaxHandler) CreateTaxRate(c *fiber.Ctx) error {
+	var req services.CreateTaxRateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	taxRate, err := h.taxService.WithContext(c.UserContext()).CreateTaxRate(req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create tax rate", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Tax rate created successfully", taxRate)
+}
+
+// GetTaxRates godoc
+// @Summary List tax rates
+// @Description List every tax rate (Admin only)
+// @Tags Taxes
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Success 200 {object} utils.Response{data=[]models.TaxRate}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /tax-rates [get]
+func (h *TaxHandler) GetTaxRates(c *fiber.Ctx) error {
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	taxRates, page, err := h.taxService.WithContext(c.UserContext()).GetTaxRates(q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch tax rates", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Tax rates retrieved successfully", taxRates, page)
+}
+
+// UpdateTaxRate godoc
+// @Summary Update tax rate
+// @Description Change, switch pricing mode of or deactivate a tax rate for future bookings (Admin only)
+// @Tags Taxes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tax rate ID"
+// @Param request body services.UpdateTaxRateRequest true "Fields to change"
+// @Success 200 {object} utils.Response{data=models.TaxRate}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /tax-rates/{id} [put]
+func (h *TaxHandler) UpdateTaxRate(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid tax rate ID", err)
+	}
+
+	var req services.UpdateTaxRateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	taxRate, err := h.taxService.WithContext(c.UserContext()).UpdateTaxRate(uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to update tax rate", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tax rate updated successfully", taxRate)
+}
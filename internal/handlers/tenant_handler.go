@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type TenantHandler struct {
+	tenantService *services.TenantService
+}
+
+func NewTenantHandler(tenantService *services.TenantService) *TenantHandler {
+	return &TenantHandler{tenantService: tenantService}
+}
+
+// CreateTenant godoc
+// @Summary Create tenant
+// @Description Add a sports club to the deployment, served on its slug's subdomain or with the X-Tenant header (Platform admin only)
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.CreateTenantRequest true "Tenant details"
+// @Success 201 {object} utils.Response{data=models.Tenant}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /tenants [post]
+func (h *TenantHandler) CreateTenant(c *fiber.Ctx) error {
+	var req services.CreateTenantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	tenant, err := h.tenantService.CreateTenant(req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to create tenant", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Tenant created successfully", tenant)
+}
+
+// GetTenants godoc
+// @Summary List tenants
+// @Description List the sports clubs sharing the deployment (Platform admin only)
+// @Tags Tenants
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Success 200 {object} utils.Response{data=[]models.Tenant}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /tenants [get]
+func (h *TenantHandler) GetTenants(c *fiber.Ctx) error {
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	tenants, page, err := h.tenantService.GetTenants(q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch tenants", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Tenants retrieved successfully", tenants, page)
+}
+
+// UpdateTenant godoc
+// @Summary Update tenant
+// @Description Rename a sports club or change the seller details printed on its invoices (Platform admin only)
+// @Tags Tenants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Tenant ID"
+// @Param request body services.UpdateTenantRequest true "Changes"
+// @Success 200 {object} utils.Response{data=models.Tenant}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /tenants/{id} [put]
+func (h *TenantHandler) UpdateTenant(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid tenant ID", err)
+	}
+
+	var req services.UpdateTenantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	tenant, err := h.tenantService.UpdateTenant(uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to update tenant", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Tenant updated successfully", tenant)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type WaitlistHandler struct {
+	waitlistService *services.WaitlistService
+}
+
+func NewWaitlistHandler(waitlistService *services.WaitlistService) *WaitlistHandler {
+	return &WaitlistHandler{waitlistService: waitlistService}
+}
+
+func (h *WaitlistHandler) JoinWaitlist(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req services.JoinWaitlistRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	entry, err := h.waitlistService.WithContext(c.UserContext()).JoinWaitlist(userID, req)
+	var ruleErr *services.BookingRuleError
+	if errors.As(err, &ruleErr) {
+		return utils.ValidationErrorResponse(c, "Time slot is not a valid local time", err, ruleErr.Violations)
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to join waitlist", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Joined waitlist successfully", entry)
+}
+
+func (h *WaitlistHandler) GetUserWaitlist(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	entries, page, err := h.waitlistService.WithContext(c.UserContext()).GetUserWaitlist(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch waitlist", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Waitlist retrieved successfully", entries, page)
+}
+
+func (h *WaitlistHandler) LeaveWaitlist(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid waitlist entry ID", err)
+	}
+
+	if err := h.waitlistService.WithContext(c.UserContext()).LeaveWaitlist(userID, uint(id)); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Failed to leave waitlist", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Left waitlist successfully", nil)
+}
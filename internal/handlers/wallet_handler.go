@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+type WalletHandler struct {
+	walletService *services.WalletService
+}
+
+func NewWalletHandler(walletService *services.WalletService) *WalletHandler {
+	return &WalletHandler{walletService: walletService}
+}
+
+// GetWallet godoc
+// @Summary Get wallet
+// @Description Get the current user's wallet balance
+// @Tags Wallet
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} utils.Response{data=models.Wallet}
+// @Failure 401 {object} utils.Response
+// @Router /users/me/wallet [get]
+func (h *WalletHandler) GetWallet(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	wallet, err := h.walletService.WithContext(c.UserContext()).GetWallet(userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch wallet", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Wallet retrieved successfully", wallet)
+}
+
+// GetTransactions godoc
+// @Summary List wallet transactions
+// @Description List the current user's wallet transactions, newest first
+// @Tags Wallet
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Items per page, at most 100" default(20)
+// @Param cursor query string false "Cursor from the previous page's next_cursor, instead of page"
+// @Param sort query string false "Sort key, prefixed with - for descending order"
+// @Param from query string false "Earliest time, as an RFC 3339 time or a date"
+// @Param to query string false "Latest time, as an RFC 3339 time or a date taken in whole"
+// @Param tz query string false "Timezone of from and to dates" default(UTC)
+// @Success 200 {object} utils.Response{data=[]models.WalletTransaction}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/wallet/transactions [get]
+func (h *WalletHandler) GetTransactions(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	q, err := listQuery(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid query parameters", err)
+	}
+
+	transactions, page, err := h.walletService.WithContext(c.UserContext()).GetTransactions(userID, q)
+	if err != nil {
+		return utils.ErrorResponse(c, listErrorStatus(err, fiber.StatusInternalServerError), "Failed to fetch wallet transactions", err)
+	}
+
+	return utils.PaginatedResponse(c, fiber.StatusOK, "Wallet transactions retrieved successfully", transactions, page)
+}
+
+// TopUp godoc
+// @Summary Top up wallet
+// @Description Load credit into the current user's wallet
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body services.TopUpRequest true "Top-up details"
+// @Success 201 {object} utils.Response{data=models.WalletTransaction}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /users/me/wallet/top-ups [post]
+func (h *WalletHandler) TopUp(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(uint)
+
+	var req services.TopUpRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	entry, err := h.walletService.WithContext(c.UserContext()).TopUp(userID, req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Top-up failed", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Wallet topped up successfully", entry)
+}
+
+// AdjustBalance godoc
+// @Summary Adjust wallet balance
+// @Description Credit (positive amount) or debit (negative amount) a user's wallet (Admin only)
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param request body services.WalletAdjustmentRequest true "Adjustment details"
+// @Success 201 {object} utils.Response{data=models.WalletTransaction}
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /users/{id}/wallet/adjustments [post]
+func (h *WalletHandler) AdjustBalance(c *fiber.Ctx) error {
+	adminID := c.Locals("userID").(uint)
+
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid user ID", err)
+	}
+
+	var req services.WalletAdjustmentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body", err)
+	}
+
+	entry, err := h.walletService.WithContext(c.UserContext()).AdjustBalance(adminID, uint(id), req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Wallet adjustment failed", err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Wallet adjusted successfully", entry)
+}
@@ -0,0 +1,155 @@
+package jobs
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/services"
+)
+
+// ExpireHolds releases pending bookings that were not paid within the hold TTL.
+func ExpireHolds(bookingService *services.BookingService, holdTTL time.Duration) func() error {
+	return func() error {
+		expired, err := bookingService.ExpirePendingBookings(time.Now().Add(-holdTTL))
+		if err != nil {
+			return err
+		}
+		if expired > 0 {
+			log.Printf("Expired %d unpaid booking holds", expired)
+		}
+		return nil
+	}
+}
+
+// MarkNoShows marks paid bookings nobody checked in to as no-shows.
+func MarkNoShows(checkInService *services.CheckInService) func() error {
+	return func() error {
+		marked, err := checkInService.MarkNoShows(time.Now())
+		if err != nil {
+			return err
+		}
+		if marked > 0 {
+			log.Printf("Marked %d bookings as no-shows", marked)
+		}
+		return nil
+	}
+}
+
+// CompleteBookings marks checked-in bookings as completed once they have ended.
+func CompleteBookings(bookingService *services.BookingService) func() error {
+	return func() error {
+		completed, err := bookingService.CompleteBookings(time.Now())
+		if err != nil {
+			return err
+		}
+		if completed > 0 {
+			log.Printf("Completed %d bookings", completed)
+		}
+		return nil
+	}
+}
+
+// alertLookback is how far back each availability alert run looks for freed
+// slots. Runs overlap, and the alerts' dedupe keys keep a slot from being
+// announced twice, so a restart or a failed run does not lose any.
+const alertLookback = 24 * time.Hour
+
+// MatchAvailabilityAlerts notifies users about recently freed slots.
+func MatchAvailabilityAlerts(savedSearchService *services.SavedSearchService) func() error {
+	return func() error {
+		sent, err := savedSearchService.MatchFreedSlots(time.Now().Add(-alertLookback))
+		if err != nil {
+			return err
+		}
+		if sent > 0 {
+			log.Printf("Sent %d availability alerts", sent)
+		}
+		return nil
+	}
+}
+
+// ProcessWaitlist hands freed slots to the next waitlisted user in line.
+func ProcessWaitlist(waitlistService *services.WaitlistService, claimWindow time.Duration) func() error {
+	return func() error {
+		offered, err := waitlistService.ProcessWaitlist(claimWindow)
+		if err != nil {
+			return err
+		}
+		if offered > 0 {
+			log.Printf("Made %d waitlist offers", offered)
+		}
+		return nil
+	}
+}
+
+// SettleSplitDeadlines charges the owner or releases split bookings whose
+// payment deadline has passed.
+func SettleSplitDeadlines(splitService *services.SplitService) func() error {
+	return func() error {
+		settled, err := splitService.ProcessDeadlines()
+		if err != nil {
+			return err
+		}
+		if settled > 0 {
+			log.Printf("Settled %d overdue payment splits", settled)
+		}
+		return nil
+	}
+}
+
+// PurgeIdempotencyKeys deletes stored Idempotency-Key responses past their
+// retention window.
+func PurgeIdempotencyKeys(idempotencyService *services.IdempotencyService) func() error {
+	return func() error {
+		purged, err := idempotencyService.PurgeExpired()
+		if err != nil {
+			return err
+		}
+		if purged > 0 {
+			log.Printf("Purged %d expired idempotency keys", purged)
+		}
+		return nil
+	}
+}
+
+// RenewSubscriptions renews or expires memberships and packages that have
+// reached their end date.
+func RenewSubscriptions(membershipService *services.MembershipService) func() error {
+	return func() error {
+		renewed, expired, err := membershipService.RenewSubscriptions()
+		if err != nil {
+			return err
+		}
+		if renewed > 0 || expired > 0 {
+			log.Printf("Renewed %d and expired %d subscriptions", renewed, expired)
+		}
+		return nil
+	}
+}
+
+// ReconcileSettlements reconciles gateway settlement reports dropped into dir.
+func ReconcileSettlements(reconciliationService *services.ReconciliationService, dir string) func() error {
+	return func() error {
+		issues, err := reconciliationService.ReconcileDirectory(dir)
+		if issues > 0 {
+			log.Printf("Flagged %d reconciliation issues", issues)
+		}
+		return err
+	}
+}
+
+// GeneratePayouts makes the weekly payout batch once the week has turned.
+func GeneratePayouts(payoutService *services.PayoutService) func() error {
+	return func() error {
+		batch, err := payoutService.GeneratePayoutBatch(time.Now())
+		if errors.Is(err, services.ErrPayoutBatchExists) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		log.Printf("Generated payout batch %s with %d payouts", batch.Reference, len(batch.Payouts))
+		return nil
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
 	"github.com/qolby/sports-booking-api/internal/utils"
 )
 
@@ -25,6 +26,17 @@ func AuthRequired(cfg *config.Config) fiber.Handler {
 			return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Invalid or expired token", err)
 		}
 
+		// Tokens are only good on their own tenant. Tokens issued before
+		// tenants existed belong to the default one.
+		tenantID := claims.TenantID
+		if tenantID == 0 {
+			tenantID = models.DefaultTenantID
+		}
+		if explicit, _ := c.Locals("tenantExplicit").(bool); explicit && c.Locals("tenantID") != tenantID {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Token belongs to another tenant", nil)
+		}
+		setTenant(c, tenantID)
+
 		// Store user info in context
 		c.Locals("userID", claims.UserID)
 		c.Locals("userEmail", claims.Email)
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// Idempotency makes mutating requests safe to retry. When a request carries
+// an Idempotency-Key header, its response is stored and replayed for any
+// repeat with the same key and body. Reusing a key with a different body is
+// rejected. Server errors are not stored, so the request can be retried, and
+// a retry can take over a request still unfinished after lease. Must run
+// after AuthRequired, since keys are scoped per user.
+func Idempotency(idempotencyService *services.IdempotencyService, ttl, lease time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := strings.TrimSpace(c.Get("Idempotency-Key"))
+		if key == "" || c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead || c.Method() == fiber.MethodOptions {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Idempotency-Key is too long", nil)
+		}
+
+		userID := c.Locals("userID").(uint)
+		hash := services.Fingerprint(c.Method(), c.Path(), c.Body())
+
+		stored, err := idempotencyService.Begin(userID, key, c.Method(), c.Path(), hash, ttl, lease)
+		switch {
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			return utils.ErrorResponse(c, fiber.StatusUnprocessableEntity, "Idempotency-Key reused", err)
+		case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Request already in progress", err)
+		case err != nil:
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to check Idempotency-Key", err)
+		}
+
+		if stored != nil {
+			c.Set("Idempotent-Replayed", "true")
+			c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return c.Status(stored.StatusCode).Send(stored.ResponseBody)
+		}
+
+		if err := c.Next(); err != nil {
+			idempotencyService.Release(userID, key)
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			idempotencyService.Release(userID, key)
+			return nil
+		}
+		if err := idempotencyService.Complete(userID, key, status, c.Response().Body()); err != nil {
+			idempotencyService.Release(userID, key)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+// OwnerOnly admits venue owners. The role is read from the token, so a user
+// made an owner must log in again.
+func OwnerOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role := c.Locals("userRole").(string)
+
+		if role != "owner" {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Owner access required", nil)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+// StaffOnly admits front desk staff and admins.
+func StaffOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role := c.Locals("userRole").(string)
+
+		if role != "staff" && role != "admin" {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Staff access required", nil)
+		}
+
+		return c.Next()
+	}
+}
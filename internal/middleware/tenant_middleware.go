@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/services"
+	"github.com/qolby/sports-booking-api/internal/tenant"
+	"github.com/qolby/sports-booking-api/internal/utils"
+)
+
+// TenantHeader names the tenant of a request by slug, for clients that do
+// not call the API on the tenant's subdomain.
+const TenantHeader = "X-Tenant"
+
+// ResolveTenant scopes the request to the tenant named by its subdomain of
+// cfg.Tenant.BaseDomain or, failing that, by the X-Tenant header. Requests
+// naming neither are scoped to the default tenant until AuthRequired reads the
+// tenant from the token.
+func ResolveTenant(tenantService *services.TenantService, cfg *config.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		slug := subdomain(c.Hostname(), cfg.Tenant.BaseDomain)
+		if slug == "" {
+			slug = strings.TrimSpace(c.Get(TenantHeader))
+		}
+
+		tenantID := models.DefaultTenantID
+		if slug != "" {
+			t, err := tenantService.GetTenantBySlug(slug)
+			if err != nil {
+				return utils.ErrorResponse(c, fiber.StatusNotFound, "Tenant not found", err)
+			}
+			tenantID = t.ID
+			c.Locals("tenantExplicit", true)
+		}
+
+		setTenant(c, tenantID)
+		return c.Next()
+	}
+}
+
+// PlatformOnly admits requests of the default tenant, whose admins operate
+// the platform across all tenants, and lifts the request's tenant scope.
+func PlatformOnly() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if tenantID, _ := c.Locals("tenantID").(uint); tenantID != models.DefaultTenantID {
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "Platform access required", nil)
+		}
+
+		c.SetUserContext(tenant.AcrossTenants(c.UserContext()))
+		return c.Next()
+	}
+}
+
+func setTenant(c *fiber.Ctx, tenantID uint) {
+	c.Locals("tenantID", tenantID)
+	c.SetUserContext(tenant.NewContext(c.UserContext(), tenantID))
+}
+
+// subdomain returns the label of host directly below baseDomain, if any.
+func subdomain(host, baseDomain string) string {
+	if baseDomain == "" {
+		return ""
+	}
+	label, found := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(baseDomain))
+	if !found || label == "" || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -10,22 +10,145 @@ type BookingStatus string
 
 const (
 	StatusPending   BookingStatus = "pending"
+	StatusConfirmed BookingStatus = "confirmed"
 	StatusPaid      BookingStatus = "paid"
+	StatusCheckedIn BookingStatus = "checked_in"
+	StatusCompleted BookingStatus = "completed"
+	StatusNoShow    BookingStatus = "no_show"
 	StatusCancelled BookingStatus = "cancelled"
+	StatusExpired   BookingStatus = "expired"
+
+	StatusPartiallyRefunded BookingStatus = "partially_refunded"
+	StatusRefunded          BookingStatus = "refunded"
 )
 
+// bookingTransitions lists the statuses each status may move to. A pending
+// booking is an unpaid hold that expires; a confirmed one is held until its
+// split payment deadline instead. Paid bookings are checked in and completed,
+// or become no-shows, and any booking that took money can be refunded.
+var bookingTransitions = map[BookingStatus][]BookingStatus{
+	StatusPending:           {StatusConfirmed, StatusPaid, StatusCancelled, StatusExpired},
+	StatusConfirmed:         {StatusPaid, StatusCancelled},
+	StatusPaid:              {StatusCheckedIn, StatusCompleted, StatusNoShow, StatusCancelled, StatusPartiallyRefunded, StatusRefunded},
+	StatusPartiallyRefunded: {StatusCheckedIn, StatusCompleted, StatusNoShow, StatusCancelled, StatusRefunded},
+	StatusCheckedIn:         {StatusCompleted, StatusRefunded},
+	StatusCompleted:         {StatusRefunded},
+	StatusNoShow:            {StatusRefunded},
+	StatusCancelled:         {StatusRefunded},
+	StatusExpired:           {StatusRefunded},
+}
+
+// CanTransitionTo reports whether a booking in status s may move to next.
+func (s BookingStatus) CanTransitionTo(next BookingStatus) bool {
+	for _, allowed := range bookingTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// InactiveBookingStatuses lists the statuses that no longer occupy a slot.
+var InactiveBookingStatuses = []BookingStatus{StatusCancelled, StatusExpired, StatusRefunded}
+
+// IsPaid reports whether the booking has been paid for and still stands,
+// possibly with part of the money given back.
+func (b *Booking) IsPaid() bool {
+	switch b.Status {
+	case StatusPaid, StatusPartiallyRefunded, StatusCheckedIn, StatusCompleted:
+		return true
+	}
+	return false
+}
+
+// Booking is a held or confirmed slot on a field. The Subtotal less the
+// promo code DiscountAmount and the PlanDiscount given by a membership, or
+// covered by package sessions, is split into NetAmount and TaxAmount; their
+// sum is the TotalPrice the user pays. StartTime and EndTime are stored in
+// UTC; LocalStartTime and LocalEndTime show them in the Timezone of the
+// field. A booking made by an admin records them in CreatedByID; one for a
+// walk-in or phone guest without an account is held on the admin's own
+// account, with the guest's GuestName and GuestPhone, but is not the admin's
+// own booking.
 type Booking struct {
-	ID         uint           `gorm:"primarykey" json:"id"`
-	UserID     uint           `gorm:"not null" json:"user_id"`
-	User       User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	FieldID    uint           `gorm:"not null" json:"field_id"`
-	Field      Field          `gorm:"foreignKey:FieldID" json:"field,omitempty"`
-	StartTime  time.Time      `gorm:"not null" json:"start_time"`
-	EndTime    time.Time      `gorm:"not null" json:"end_time"`
-	Status     BookingStatus  `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	TotalPrice int            `json:"total_price"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"-"`
-	Payment    *Payment       `gorm:"foreignKey:BookingID" json:"payment,omitempty"`
+	ID              uint           `gorm:"primarykey" json:"id"`
+	TenantID        uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID          uint           `gorm:"not null" json:"user_id"`
+	User            User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	FieldID         uint           `gorm:"not null" json:"field_id"`
+	Field           Field          `gorm:"foreignKey:FieldID" json:"field,omitempty"`
+	OrderID         *uint          `gorm:"index" json:"order_id,omitempty"`
+	CreatedByID     *uint          `gorm:"index" json:"created_by_id,omitempty"`
+	GuestName       string         `gorm:"type:varchar(100)" json:"guest_name,omitempty"`
+	GuestPhone      string         `gorm:"type:varchar(30)" json:"guest_phone,omitempty"`
+	StartTime       time.Time      `gorm:"not null" json:"start_time"`
+	EndTime         time.Time      `gorm:"not null" json:"end_time"`
+	Timezone        string         `gorm:"type:varchar(64)" json:"timezone"`
+	LocalStartTime  time.Time      `gorm:"-" json:"local_start_time"`
+	LocalEndTime    time.Time      `gorm:"-" json:"local_end_time"`
+	Status          BookingStatus  `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	Subtotal        Money          `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"`
+	DiscountAmount  Money          `gorm:"embedded;embeddedPrefix:discount_amount_" json:"discount_amount"`
+	PromotionID     *uint          `gorm:"index" json:"promotion_id,omitempty"`
+	PromoCode       string         `gorm:"type:varchar(50)" json:"promo_code,omitempty"`
+	SubscriptionID  *uint          `gorm:"index" json:"subscription_id,omitempty"`
+	PlanDiscount    Money          `gorm:"embedded;embeddedPrefix:plan_discount_" json:"plan_discount"`
+	PackageSessions int            `gorm:"default:0" json:"package_sessions,omitempty"`
+	NetAmount       Money          `gorm:"embedded;embeddedPrefix:net_amount_" json:"net_amount"`
+	TaxAmount       Money          `gorm:"embedded;embeddedPrefix:tax_amount_" json:"tax_amount"`
+	TaxRateID       *uint          `gorm:"index" json:"tax_rate_id,omitempty"`
+	TaxRate         int            `gorm:"default:0" json:"tax_rate"`
+	TaxInclusive    bool           `gorm:"default:false" json:"tax_inclusive"`
+	TotalPrice      Money          `gorm:"embedded;embeddedPrefix:total_price_" json:"total_price"`
+	RescheduleCount int            `gorm:"default:0" json:"reschedule_count"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	Payments        []Payment      `gorm:"foreignKey:BookingID" json:"payments,omitempty"`
+}
+
+// AfterFind shows the booking in its field's local time.
+func (b *Booking) AfterFind(tx *gorm.DB) error {
+	b.localize()
+	return nil
+}
+
+// AfterCreate shows a new booking in its field's local time.
+func (b *Booking) AfterCreate(tx *gorm.DB) error {
+	b.localize()
+	return nil
+}
+
+func (b *Booking) localize() {
+	loc := LoadLocation(b.Timezone)
+	b.StartTime, b.EndTime = b.StartTime.UTC(), b.EndTime.UTC()
+	b.LocalStartTime, b.LocalEndTime = b.StartTime.In(loc), b.EndTime.In(loc)
+}
+
+// BookingEvent records one status change of a booking. FromStatus is empty
+// for the status a booking was created in, and ActorID is nil for changes
+// made by the system, such as an expired hold.
+type BookingEvent struct {
+	ID         uint          `gorm:"primarykey" json:"id"`
+	TenantID   uint          `gorm:"not null;default:1;index" json:"tenant_id"`
+	BookingID  uint          `gorm:"not null;index" json:"booking_id"`
+	FromStatus BookingStatus `gorm:"type:varchar(20)" json:"from_status,omitempty"`
+	ToStatus   BookingStatus `gorm:"type:varchar(20);not null" json:"to_status"`
+	ActorID    *uint         `gorm:"index" json:"actor_id,omitempty"`
+	Reason     string        `json:"reason,omitempty"`
+	CreatedAt  time.Time     `gorm:"index" json:"created_at"`
+}
+
+// BookingReschedule records the slot a booking was moved away from, so that
+// saved searches and the waitlist can offer it like a cancelled one.
+type BookingReschedule struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"not null;default:1;index" json:"tenant_id"`
+	BookingID uint      `gorm:"not null;index" json:"booking_id"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	FieldID   uint      `gorm:"not null" json:"field_id"`
+	Field     Field     `gorm:"foreignKey:FieldID" json:"field,omitempty"`
+	StartTime time.Time `gorm:"not null" json:"start_time"`
+	EndTime   time.Time `gorm:"not null" json:"end_time"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
 }
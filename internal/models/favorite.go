@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type FavoriteField struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_favorite_user_field" json:"user_id"`
+	FieldID   uint      `gorm:"not null;uniqueIndex:idx_favorite_user_field" json:"field_id"`
+	Field     Field     `gorm:"foreignKey:FieldID" json:"field,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
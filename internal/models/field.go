@@ -6,13 +6,51 @@ import (
 	"gorm.io/gorm"
 )
 
+// Field is a bookable venue. Fields with an OwnerID belong to an independent
+// venue owner, who earns their revenue less CommissionRate (in basis
+// points) and is paid out on their PayoutSchedule; other fields belong to
+// the platform. BookingRules limit the slots players can book. Opening
+// hours and every other wall clock time of the field are in its IANA
+// Timezone.
 type Field struct {
-	ID           uint           `gorm:"primarykey" json:"id"`
-	Name         string         `gorm:"not null" json:"name"`
-	PricePerHour int            `gorm:"not null" json:"price_per_hour"`
-	Location     string         `gorm:"not null" json:"location"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
-	Bookings     []Booking      `gorm:"foreignKey:FieldID" json:"bookings,omitempty"`
+	ID             uint           `gorm:"primarykey" json:"id"`
+	TenantID       uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	Name           string         `gorm:"not null" json:"name"`
+	PricePerHour   Money          `gorm:"embedded;embeddedPrefix:price_per_hour_" json:"price_per_hour"`
+	Location       string         `gorm:"not null" json:"location"`
+	Sport          string         `gorm:"type:varchar(50);index" json:"sport,omitempty"`
+	OpenTime       string         `gorm:"type:varchar(5)" json:"open_time,omitempty"`
+	CloseTime      string         `gorm:"type:varchar(5)" json:"close_time,omitempty"`
+	Timezone       string         `gorm:"type:varchar(64)" json:"timezone"`
+	TaxRateID      *uint          `gorm:"index" json:"tax_rate_id,omitempty"`
+	OwnerID        *uint          `gorm:"index" json:"owner_id,omitempty"`
+	CommissionRate int            `gorm:"default:0" json:"commission_rate"`
+	PayoutSchedule PayoutSchedule `gorm:"type:varchar(20);default:'weekly'" json:"payout_schedule"`
+	BookingRules   BookingRules   `gorm:"embedded" json:"booking_rules"`
+	RatingAvg      float64        `gorm:"default:0" json:"rating_avg"`
+	RatingCount    int            `gorm:"default:0" json:"rating_count"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	Bookings       []Booking      `gorm:"foreignKey:FieldID" json:"bookings,omitempty"`
+}
+
+// Zone returns the field's timezone.
+func (f *Field) Zone() *time.Location {
+	return LoadLocation(f.Timezone)
+}
+
+// BookingRules constrain the bookings of a field; a zero rule does not apply.
+// Bookings last between MinDurationMinutes and MaxDurationMinutes, in whole
+// slots of SlotMinutes that start on the slot grid counted from the field's
+// opening time (or midnight). BufferMinutes are kept free between bookings
+// for cleanup. Slots can be booked from MaxAdvanceDays ahead until
+// MinNoticeMinutes before they start.
+type BookingRules struct {
+	MinDurationMinutes int `gorm:"default:0" json:"min_duration_minutes"`
+	MaxDurationMinutes int `gorm:"default:0" json:"max_duration_minutes"`
+	SlotMinutes        int `gorm:"default:0" json:"slot_minutes"`
+	BufferMinutes      int `gorm:"default:0" json:"buffer_minutes"`
+	MinNoticeMinutes   int `gorm:"default:0" json:"min_notice_minutes"`
+	MaxAdvanceDays     int `gorm:"default:0" json:"max_advance_days"`
 }
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the outcome of a mutating request sent with an
+// Idempotency-Key header, so a retried request can be answered with the
+// original response instead of being executed twice. A key is scoped to the
+// user that sent it. StatusCode stays zero while the first request is still
+// being processed, which holds the key until LockedUntil.
+type IdempotencyKey struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	UserID       uint       `gorm:"not null;uniqueIndex:idx_idempotency_user_key" json:"user_id"`
+	Key          string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key" json:"key"`
+	Method       string     `gorm:"type:varchar(10);not null" json:"method"`
+	Path         string     `gorm:"not null" json:"path"`
+	RequestHash  string     `gorm:"type:varchar(64);not null" json:"-"`
+	StatusCode   int        `json:"status_code"`
+	ResponseBody []byte     `json:"-"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
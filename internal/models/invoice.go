@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// InvoicePrefix starts every invoice number, e.g. INV-2026-000042, and
+// CreditNotePrefix every credit note number, e.g. CN-2026-000043.
+const (
+	InvoicePrefix    = "INV"
+	CreditNotePrefix = "CN"
+)
+
+// Invoice is issued for every completed payment and every follow-up charge
+// on it. A refund is documented by a credit note: an invoice with negative
+// amounts for the refund that credits the payment's invoice. Invoices and
+// credit notes share one sequence within a tenant's calendar year, without
+// gaps.
+type Invoice struct {
+	ID                uint           `gorm:"primarykey" json:"id"`
+	TenantID          uint           `gorm:"not null;default:1;index;uniqueIndex:idx_invoices_tenant_number;uniqueIndex:idx_invoices_tenant_sequence" json:"tenant_id"`
+	Number            string         `gorm:"type:varchar(30);not null;uniqueIndex:idx_invoices_tenant_number" json:"number"`
+	Year              int            `gorm:"not null;uniqueIndex:idx_invoices_tenant_sequence" json:"year"`
+	Sequence          int            `gorm:"not null;uniqueIndex:idx_invoices_tenant_sequence" json:"sequence"`
+	PaymentID         uint           `gorm:"not null;index:idx_invoices_payment" json:"payment_id"`
+	Payment           *Payment       `gorm:"foreignKey:PaymentID" json:"payment,omitempty"`
+	AdjustmentID      *uint          `gorm:"uniqueIndex" json:"adjustment_id,omitempty"`
+	RefundID          *uint          `gorm:"uniqueIndex" json:"refund_id,omitempty"`
+	CreditedInvoiceID *uint          `gorm:"index" json:"credited_invoice_id,omitempty"`
+	CreditedInvoice   *Invoice       `gorm:"foreignKey:CreditedInvoiceID" json:"credited_invoice,omitempty"`
+	UserID            uint           `gorm:"not null;index" json:"user_id"`
+	BillToName        string         `json:"bill_to_name"`
+	BillToEmail       string         `json:"bill_to_email"`
+	Subtotal          Money          `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"`
+	DiscountAmount    Money          `gorm:"embedded;embeddedPrefix:discount_amount_" json:"discount_amount"`
+	NetAmount         Money          `gorm:"embedded;embeddedPrefix:net_amount_" json:"net_amount"`
+	TaxAmount         Money          `gorm:"embedded;embeddedPrefix:tax_amount_" json:"tax_amount"`
+	Total             Money          `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+	PaymentMethod     string         `json:"payment_method"`
+	IssuedAt          time.Time      `gorm:"not null" json:"issued_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+	Lines             []InvoiceLine  `gorm:"foreignKey:InvoiceID" json:"lines,omitempty"`
+}
+
+// InvoiceLine is one booked slot, or a participant's share of one. The slot
+// is printed in the Timezone of its booking.
+type InvoiceLine struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	InvoiceID    uint      `gorm:"not null;index" json:"invoice_id"`
+	BookingID    *uint     `gorm:"index" json:"booking_id,omitempty"`
+	Description  string    `gorm:"not null" json:"description"`
+	Venue        string    `json:"venue"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	Timezone     string    `gorm:"type:varchar(64)" json:"timezone"`
+	UnitPrice    Money     `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	Amount       Money     `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Discount     Money     `gorm:"embedded;embeddedPrefix:discount_" json:"discount"`
+	Net          Money     `gorm:"embedded;embeddedPrefix:net_" json:"net"`
+	Tax          Money     `gorm:"embedded;embeddedPrefix:tax_" json:"tax"`
+	TaxRate      int       `gorm:"default:0" json:"tax_rate"`
+	TaxInclusive bool      `gorm:"default:false" json:"tax_inclusive"`
+	Total        Money     `gorm:"embedded;embeddedPrefix:total_" json:"total"`
+}
+
+// InvoiceSequence holds the last invoice number a tenant issued in a year.
+// Its row is locked while an invoice is numbered, inside the payment's
+// transaction.
+type InvoiceSequence struct {
+	TenantID   uint `gorm:"primarykey;autoIncrement:false" json:"tenant_id"`
+	Year       int  `gorm:"primarykey;autoIncrement:false" json:"year"`
+	LastNumber int  `gorm:"not null;default:0" json:"last_number"`
+}
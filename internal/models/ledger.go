@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// LedgerAccount is an account of the internal double-entry ledger. Asset
+// and expense accounts (receivables, gateway clearing, cash, refunds,
+// discounts) normally carry a debit balance; liability and revenue accounts
+// a credit balance.
+type LedgerAccount string
+
+const (
+	// AccountReceivables holds what customers owe for sales until paid
+	AccountReceivables LedgerAccount = "customer_receivables"
+	// AccountGatewayClearing holds money collected through the payment
+	// gateway, net of refunds paid out through it
+	AccountGatewayClearing LedgerAccount = "gateway_clearing"
+	// AccountCash holds cash taken at the front desk, net of cash refunds
+	AccountCash LedgerAccount = "cash"
+	// AccountCustomerWallets is the credit owed to customers in wallets
+	AccountCustomerWallets LedgerAccount = "customer_wallets"
+	AccountVenueRevenue    LedgerAccount = "venue_revenue"
+	AccountPlanRevenue     LedgerAccount = "plan_revenue"
+	AccountTaxPayable      LedgerAccount = "tax_payable"
+	AccountRefunds         LedgerAccount = "refunds"
+	// AccountDiscounts collects promo, membership and goodwill discounts
+	AccountDiscounts LedgerAccount = "discounts"
+	// AccountOwnerPayables is venue revenue owed to independent owners
+	// until it is paid out
+	AccountOwnerPayables LedgerAccount = "owner_payables"
+)
+
+// LedgerAccounts lists every account in chart order.
+var LedgerAccounts = []LedgerAccount{
+	AccountReceivables, AccountGatewayClearing, AccountCash, AccountCustomerWallets,
+	AccountVenueRevenue, AccountPlanRevenue, AccountTaxPayable, AccountRefunds, AccountDiscounts, AccountOwnerPayables,
+}
+
+// JournalEntry records one money movement as balanced debit and credit
+// lines: in every currency, the debits add up to the credits. Entries are
+// never changed; mistakes are corrected with new entries.
+type JournalEntry struct {
+	ID                  uint          `gorm:"primarykey" json:"id"`
+	Reference           string        `gorm:"type:varchar(100);not null;index" json:"reference"`
+	Description         string        `json:"description"`
+	PaymentID           *uint         `gorm:"index" json:"payment_id,omitempty"`
+	RefundID            *uint         `gorm:"index" json:"refund_id,omitempty"`
+	WalletTransactionID *uint         `gorm:"index" json:"wallet_transaction_id,omitempty"`
+	SubscriptionID      *uint         `gorm:"index" json:"subscription_id,omitempty"`
+	Lines               []JournalLine `gorm:"foreignKey:EntryID" json:"lines"`
+	CreatedAt           time.Time     `json:"created_at"`
+}
+
+// JournalLine debits or credits one account, in minor units of Currency.
+// Exactly one of Debit and Credit is set.
+type JournalLine struct {
+	ID       uint          `gorm:"primarykey" json:"id"`
+	EntryID  uint          `gorm:"not null;index" json:"entry_id"`
+	Account  LedgerAccount `gorm:"type:varchar(50);not null;index" json:"account"`
+	Currency string        `gorm:"type:varchar(3);not null" json:"currency"`
+	Debit    int64         `gorm:"not null;default:0" json:"debit"`
+	Credit   int64         `gorm:"not null;default:0" json:"credit"`
+}
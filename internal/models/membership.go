@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PlanType string
+
+const (
+	PlanMembership PlanType = "membership"
+	PlanPackage    PlanType = "package"
+)
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionActive    SubscriptionStatus = "active"
+	SubscriptionExpired   SubscriptionStatus = "expired"
+	SubscriptionExhausted SubscriptionStatus = "exhausted"
+)
+
+// MembershipPlan is something a club sells. A membership gives a
+// percentage off every booking and may open bookings further ahead than
+// the default window, or raise the booking quotas (0 keeps the platform
+// default). A package is a punch card of Sessions, each covering
+// one hour of play, valid for DurationDays.
+type MembershipPlan struct {
+	ID                uint           `gorm:"primarykey" json:"id"`
+	TenantID          uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	Name              string         `gorm:"not null" json:"name"`
+	Type              PlanType       `gorm:"type:varchar(20);not null" json:"type"`
+	Price             Money          `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	DurationDays      int            `gorm:"not null" json:"duration_days"`
+	DiscountPercent   int            `gorm:"default:0" json:"discount_percent,omitempty"`
+	BookingWindowDays int            `gorm:"default:0" json:"booking_window_days,omitempty"`
+	Quotas            PlanQuotas     `gorm:"embedded;embeddedPrefix:quota_" json:"quotas"`
+	Sessions          int            `gorm:"default:0" json:"sessions,omitempty"`
+	Active            bool           `json:"active"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PlanQuotas are the booking quotas a membership allows, where higher than
+// the platform's.
+type PlanQuotas struct {
+	MaxActiveBookings   int `gorm:"default:0" json:"max_active_bookings,omitempty"`
+	MaxWeeklyFieldHours int `gorm:"default:0" json:"max_weekly_field_hours,omitempty"`
+	MaxWeeklySportHours int `gorm:"default:0" json:"max_weekly_sport_hours,omitempty"`
+	MaxWeeklyPrimeTime  int `gorm:"default:0" json:"max_weekly_prime_time,omitempty"`
+}
+
+// Subscription is a plan bought by a user. Memberships with AutoRenew are
+// charged again with the same payment method when they end.
+type Subscription struct {
+	ID                uint               `gorm:"primarykey" json:"id"`
+	TenantID          uint               `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID            uint               `gorm:"not null;index" json:"user_id"`
+	PlanID            uint               `gorm:"not null;index" json:"plan_id"`
+	Plan              MembershipPlan     `gorm:"foreignKey:PlanID" json:"plan"`
+	Status            SubscriptionStatus `gorm:"type:varchar(20);not null;index" json:"status"`
+	StartsAt          time.Time          `gorm:"not null" json:"starts_at"`
+	EndsAt            time.Time          `gorm:"not null;index" json:"ends_at"`
+	AutoRenew         bool               `json:"auto_renew"`
+	RenewalCount      int                `gorm:"default:0" json:"renewal_count"`
+	SessionsRemaining int                `gorm:"default:0" json:"sessions_remaining"`
+	PaymentMethod     string             `json:"payment_method"`
+	TransactionID     string             `json:"transaction_id"`
+	CreatedAt         time.Time          `json:"created_at"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
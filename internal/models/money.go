@@ -0,0 +1,145 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrCurrencyMismatch is returned when amounts in different currencies meet
+// in one booking, order or payment.
+var ErrCurrencyMismatch = errors.New("amounts are in different currencies")
+
+// currencyExponents lists the supported ISO 4217 currencies with the number
+// of minor-unit digits they are kept in. Rupiah, yen, won and dong are priced
+// in whole units in practice and payment gateways treat them that way.
+var currencyExponents = map[string]int{
+	"IDR": 0, "JPY": 0, "KRW": 0, "VND": 0,
+	"USD": 2, "EUR": 2, "GBP": 2, "AUD": 2, "NZD": 2, "CAD": 2, "CHF": 2,
+	"SGD": 2, "MYR": 2, "THB": 2, "PHP": 2, "HKD": 2, "CNY": 2, "INR": 2,
+	"KWD": 3, "BHD": 3, "OMR": 3,
+}
+
+// ValidCurrency reports whether code is a supported ISO 4217 currency.
+func ValidCurrency(code string) bool {
+	_, ok := currencyExponents[code]
+	return ok
+}
+
+// NormalizeCurrency upper-cases a currency code and checks it is supported.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !ValidCurrency(code) {
+		return "", fmt.Errorf("unsupported currency %q", code)
+	}
+	return code, nil
+}
+
+// Money is an amount in the minor unit of its currency: 1999 USD is $19.99
+// and 150000 IDR is Rp150,000. Models embed it with a column prefix, so a
+// TotalPrice is stored as total_price_amount and total_price_currency.
+type Money struct {
+	Amount   int64  `gorm:"not null;default:0" json:"amount"`
+	Currency string `gorm:"type:varchar(3)" json:"currency"`
+}
+
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// SameCurrency reports whether two amounts can be added up. A zero amount
+// without a currency goes with anything.
+func (m Money) SameCurrency(o Money) bool {
+	return m.Currency == o.Currency || m.Currency == "" || o.Currency == ""
+}
+
+func (m Money) currencyWith(o Money) string {
+	if !m.SameCurrency(o) {
+		panic(ErrCurrencyMismatch)
+	}
+	if m.Currency == "" {
+		return o.Currency
+	}
+	return m.Currency
+}
+
+// Add sums two amounts. Callers check currencies first; adding different
+// currencies is a bug and panics.
+func (m Money) Add(o Money) Money {
+	return Money{Amount: m.Amount + o.Amount, Currency: m.currencyWith(o)}
+}
+
+// Sub subtracts o from m, with the same currency rule as Add.
+func (m Money) Sub(o Money) Money {
+	return Money{Amount: m.Amount - o.Amount, Currency: m.currencyWith(o)}
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: -m.Amount, Currency: m.Currency}
+}
+
+// Percent returns percent % of the amount, rounded down to the minor unit.
+func (m Money) Percent(percent int) Money {
+	return Money{Amount: m.Amount * int64(percent) / 100, Currency: m.Currency}
+}
+
+// Min returns the smaller of two amounts in the same currency.
+func (m Money) Min(o Money) Money {
+	if o.Amount < m.Amount {
+		return Money{Amount: o.Amount, Currency: m.currencyWith(o)}
+	}
+	return Money{Amount: m.Amount, Currency: m.currencyWith(o)}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// String formats the amount with its currency and thousands separators,
+// e.g. IDR 150,000 or USD 19.99.
+func (m Money) String() string {
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	exponent := currencyExponents[m.Currency]
+	digits := strconv.FormatInt(amount, 10)
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-exponent], digits[len(digits)-exponent:]
+
+	var grouped []byte
+	for i := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped = append(grouped, ',')
+		}
+		grouped = append(grouped, whole[i])
+	}
+	if fraction != "" {
+		grouped = append(append(grouped, '.'), fraction...)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s%s %s", sign, m.Currency, grouped))
+}
+
+// Decimal formats the amount in major units without grouping, e.g. 19.99
+// for USD 19.99, as bank files expect.
+func (m Money) Decimal() string {
+	exponent := currencyExponents[m.Currency]
+	if exponent == 0 {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := fmt.Sprintf("%0*d", exponent+1, amount)
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+type NotificationType string
+
+const (
+	NotificationSlotAvailable NotificationType = "slot_available"
+	NotificationWaitlistOffer NotificationType = "waitlist_offer"
+	NotificationPaymentShare  NotificationType = "payment_share"
+	NotificationNoShow        NotificationType = "no_show"
+)
+
+type Notification struct {
+	ID        uint             `gorm:"primarykey" json:"id"`
+	TenantID  uint             `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID    uint             `gorm:"not null;index" json:"user_id"`
+	Type      NotificationType `gorm:"type:varchar(40);not null" json:"type"`
+	Message   string           `gorm:"not null" json:"message"`
+	FieldID   *uint            `json:"field_id,omitempty"`
+	BookingID *uint            `json:"booking_id,omitempty"`
+	StartTime *time.Time       `json:"start_time,omitempty"`
+	EndTime   *time.Time       `json:"end_time,omitempty"`
+	DedupeKey *string          `gorm:"uniqueIndex" json:"-"`
+	ReadAt    *time.Time       `json:"read_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
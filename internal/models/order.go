@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderCancelled OrderStatus = "cancelled"
+	OrderExpired   OrderStatus = "expired"
+)
+
+// Order groups several bookings that are held together and paid for with a
+// single payment.
+type Order struct {
+	ID          uint           `gorm:"primarykey" json:"id"`
+	TenantID    uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID      uint           `gorm:"not null;index" json:"user_id"`
+	Status      OrderStatus    `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	TotalAmount Money          `gorm:"embedded;embeddedPrefix:total_amount_" json:"total_amount"`
+	Bookings    []Booking      `gorm:"foreignKey:OrderID" json:"bookings,omitempty"`
+	Payment     *Payment       `gorm:"foreignKey:OrderID" json:"payment,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -12,17 +12,42 @@ const (
 	PaymentPending   PaymentStatus = "pending"
 	PaymentCompleted PaymentStatus = "paid"
 	PaymentFailed    PaymentStatus = "failed"
+
+	PaymentPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentRefunded          PaymentStatus = "refunded"
 )
 
+// PaymentMethodWallet pays from the user's prepaid wallet instead of the
+// gateway.
+const PaymentMethodWallet = "wallet"
+
+// PaymentMethodCash is cash taken at the front desk for a booking made by
+// an admin.
+const PaymentMethodCash = "cash"
+
+// Payment settles either a single booking or a whole order. Exactly one of
+// BookingID and OrderID is set. A booking whose price is split among several
+// participants has one payment per paid share. CashierID is the admin who
+// took a cash payment.
 type Payment struct {
-	ID            uint           `gorm:"primarykey" json:"id"`
-	BookingID     uint           `gorm:"uniqueIndex;not null" json:"booking_id"`
-	Booking       Booking        `gorm:"foreignKey:BookingID" json:"booking,omitempty"`
-	Amount        int            `gorm:"not null" json:"amount"`
-	Status        PaymentStatus  `gorm:"type:varchar(20);default:'pending'" json:"status"`
-	PaymentMethod string         `json:"payment_method"`
-	TransactionID string         `gorm:"uniqueIndex" json:"transaction_id"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint                `gorm:"primarykey" json:"id"`
+	TenantID      uint                `gorm:"not null;default:1;index" json:"tenant_id"`
+	BookingID     *uint               `gorm:"index:idx_payments_booking" json:"booking_id,omitempty"`
+	Booking       *Booking            `gorm:"foreignKey:BookingID" json:"booking,omitempty"`
+	OrderID       *uint               `gorm:"uniqueIndex" json:"order_id,omitempty"`
+	Order         *Order              `gorm:"foreignKey:OrderID" json:"order,omitempty"`
+	UserID        *uint               `gorm:"index" json:"user_id,omitempty"`
+	ShareID       *uint               `gorm:"uniqueIndex" json:"share_id,omitempty"`
+	CashierID     *uint               `gorm:"index" json:"cashier_id,omitempty"`
+	Amount        Money               `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	NetAmount     Money               `gorm:"embedded;embeddedPrefix:net_amount_" json:"net_amount"`
+	TaxAmount     Money               `gorm:"embedded;embeddedPrefix:tax_amount_" json:"tax_amount"`
+	Status        PaymentStatus       `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	PaymentMethod string              `json:"payment_method"`
+	TransactionID string              `gorm:"uniqueIndex" json:"transaction_id"`
+	CreatedAt     time.Time           `json:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt      `gorm:"index" json:"-"`
+	Adjustments   []PaymentAdjustment `gorm:"foreignKey:PaymentID" json:"adjustments,omitempty"`
+	Refunds       []Refund            `gorm:"foreignKey:PaymentID" json:"refunds,omitempty"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdjustmentType string
+
+const (
+	AdjustmentCharge AdjustmentType = "charge"
+)
+
+// PaymentAdjustment records a follow-up charge against an existing payment,
+// for example when a paid booking is rescheduled to a pricier slot. Money
+// going back to the customer is tracked as a Refund instead.
+type PaymentAdjustment struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	TenantID      uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	PaymentID     uint           `gorm:"not null;index" json:"payment_id"`
+	Type          AdjustmentType `gorm:"type:varchar(20);not null" json:"type"`
+	Amount        Money          `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Status        PaymentStatus  `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	Reason        string         `json:"reason"`
+	TransactionID string         `gorm:"uniqueIndex" json:"transaction_id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SplitStatus string
+
+const (
+	SplitOpen         SplitStatus = "open"
+	SplitCompleted    SplitStatus = "completed"
+	SplitOwnerCharged SplitStatus = "owner_charged"
+	SplitReleased     SplitStatus = "released"
+)
+
+// SplitFallback decides what happens to a booking when its split deadline
+// passes with shares still unpaid.
+type SplitFallback string
+
+const (
+	SplitChargeOwner SplitFallback = "charge_owner"
+	SplitRelease     SplitFallback = "release"
+)
+
+type ShareStatus string
+
+const (
+	SharePending   ShareStatus = "pending"
+	SharePaid      ShareStatus = "paid"
+	ShareCancelled ShareStatus = "cancelled"
+)
+
+// PaymentSplit divides the price of a booking among several participants.
+// The booking stays held until every share is paid or the deadline passes.
+// PaymentMethod is how the owner pays the unpaid remainder when charged at
+// the deadline.
+type PaymentSplit struct {
+	ID            uint           `gorm:"primarykey" json:"id"`
+	TenantID      uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	BookingID     uint           `gorm:"uniqueIndex;not null" json:"booking_id"`
+	OwnerID       uint           `gorm:"not null;index" json:"owner_id"`
+	Deadline      time.Time      `gorm:"not null" json:"deadline"`
+	OnDeadline    SplitFallback  `gorm:"type:varchar(20);not null" json:"on_deadline"`
+	PaymentMethod string         `gorm:"type:varchar(20);default:'wallet'" json:"payment_method"`
+	Status        SplitStatus    `gorm:"type:varchar(20);default:'open'" json:"status"`
+	Shares        []PaymentShare `gorm:"foreignKey:SplitID" json:"shares,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// PaymentShare is one participant's part of a split. Participants invited by
+// email who have no account yet can pay once they register with that email.
+type PaymentShare struct {
+	ID        uint        `gorm:"primarykey" json:"id"`
+	TenantID  uint        `gorm:"not null;default:1;index" json:"tenant_id"`
+	SplitID   uint        `gorm:"not null;index" json:"split_id"`
+	BookingID uint        `gorm:"not null;index" json:"booking_id"`
+	UserID    *uint       `gorm:"index" json:"user_id,omitempty"`
+	Email     string      `gorm:"index" json:"email"`
+	Amount    Money       `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Status    ShareStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	PaymentID *uint       `json:"payment_id,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
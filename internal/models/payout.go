@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+type PayoutSchedule string
+
+const (
+	PayoutWeekly  PayoutSchedule = "weekly"
+	PayoutMonthly PayoutSchedule = "monthly"
+)
+
+type EarningType string
+
+const (
+	EarningSale   EarningType = "sale"
+	EarningCharge EarningType = "charge"
+	EarningRefund EarningType = "refund"
+)
+
+type PayoutStatus string
+
+const (
+	PayoutPending PayoutStatus = "pending"
+	PayoutPaid    PayoutStatus = "paid"
+)
+
+// OwnerEarning is what a venue owner earns from one payment, follow-up
+// charge or refund on one of their fields: Gross is the revenue before tax,
+// Commission the platform's cut and Net the owner's share. Refunds are
+// negative. An earning is paid out once it is part of a payout.
+type OwnerEarning struct {
+	ID             uint        `gorm:"primarykey" json:"id"`
+	TenantID       uint        `gorm:"not null;default:1;index" json:"tenant_id"`
+	OwnerID        uint        `gorm:"not null;index" json:"owner_id"`
+	FieldID        uint        `gorm:"not null;index" json:"field_id"`
+	BookingID      *uint       `gorm:"index" json:"booking_id,omitempty"`
+	PaymentID      uint        `gorm:"not null;index" json:"payment_id"`
+	AdjustmentID   *uint       `json:"adjustment_id,omitempty"`
+	RefundID       *uint       `json:"refund_id,omitempty"`
+	Type           EarningType `gorm:"type:varchar(20);not null" json:"type"`
+	Gross          Money       `gorm:"embedded;embeddedPrefix:gross_" json:"gross"`
+	CommissionRate int         `json:"commission_rate"`
+	Commission     Money       `gorm:"embedded;embeddedPrefix:commission_" json:"commission"`
+	Net            Money       `gorm:"embedded;embeddedPrefix:net_" json:"net"`
+	PayoutID       *uint       `gorm:"index" json:"payout_id,omitempty"`
+	CreatedAt      time.Time   `gorm:"index" json:"created_at"`
+}
+
+// PayoutAccount is the bank account a venue owner is paid out to.
+type PayoutAccount struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	TenantID   uint      `gorm:"not null;default:1;index" json:"tenant_id"`
+	OwnerID    uint      `gorm:"not null;uniqueIndex" json:"owner_id"`
+	HolderName string    `gorm:"not null" json:"holder_name"`
+	IBAN       string    `gorm:"type:varchar(34);not null" json:"iban"`
+	BIC        string    `gorm:"type:varchar(11)" json:"bic,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// PayoutBatch collects the payouts of one weekly run, covering earnings made
+// before PeriodEnd.
+type PayoutBatch struct {
+	ID        uint         `gorm:"primarykey" json:"id"`
+	Reference string       `gorm:"type:varchar(30);uniqueIndex;not null" json:"reference"`
+	PeriodEnd time.Time    `gorm:"uniqueIndex;not null" json:"period_end"`
+	Status    PayoutStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	PaidAt    *time.Time   `json:"paid_at,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	Payouts   []Payout     `gorm:"foreignKey:BatchID" json:"payouts,omitempty"`
+}
+
+// Payout is the money transferred to one owner in one currency. The bank
+// details are copied from the owner's payout account when the batch is made.
+type Payout struct {
+	ID         uint         `gorm:"primarykey" json:"id"`
+	TenantID   uint         `gorm:"not null;default:1;index" json:"tenant_id"`
+	BatchID    uint         `gorm:"not null;index" json:"batch_id"`
+	OwnerID    uint         `gorm:"not null;index" json:"owner_id"`
+	Amount     Money        `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Earnings   int          `json:"earnings"`
+	HolderName string       `json:"holder_name"`
+	IBAN       string       `json:"iban"`
+	BIC        string       `json:"bic,omitempty"`
+	Status     PayoutStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	PaidAt     *time.Time   `json:"paid_at,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
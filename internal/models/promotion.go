@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type DiscountType string
+
+const (
+	DiscountPercentage DiscountType = "percentage"
+	DiscountFixed      DiscountType = "fixed"
+)
+
+// Promotion is a promo code giving a percentage or fixed amount off a
+// booking. Every restriction is optional: a zero value means no limit.
+// SlotStartFrom and SlotStartBefore (HH:MM) restrict the time of day the
+// booked slot may start at, e.g. mornings only. A fixed DiscountValue and
+// the MinSpend are in minor units of Currency, and such a promotion only
+// applies to fields priced in that currency.
+type Promotion struct {
+	ID                    uint           `gorm:"primarykey" json:"id"`
+	TenantID              uint           `gorm:"not null;default:1;uniqueIndex:idx_promotions_tenant_code" json:"tenant_id"`
+	Code                  string         `gorm:"type:varchar(50);not null;uniqueIndex:idx_promotions_tenant_code" json:"code"`
+	Description           string         `json:"description"`
+	DiscountType          DiscountType   `gorm:"type:varchar(20);not null" json:"discount_type"`
+	DiscountValue         int64          `gorm:"not null" json:"discount_value"`
+	Currency              string         `gorm:"type:varchar(3)" json:"currency,omitempty"`
+	MinSpend              int64          `gorm:"default:0" json:"min_spend"`
+	ValidFrom             *time.Time     `json:"valid_from,omitempty"`
+	ValidUntil            *time.Time     `json:"valid_until,omitempty"`
+	SlotStartFrom         string         `gorm:"type:varchar(5)" json:"slot_start_from,omitempty"`
+	SlotStartBefore       string         `gorm:"type:varchar(5)" json:"slot_start_before,omitempty"`
+	Sport                 string         `gorm:"type:varchar(50)" json:"sport,omitempty"`
+	FirstBookingOnly      bool           `json:"first_booking_only"`
+	MaxRedemptions        int            `gorm:"default:0" json:"max_redemptions"`
+	MaxRedemptionsPerUser int            `gorm:"default:0" json:"max_redemptions_per_user"`
+	Active                bool           `json:"active"`
+	Fields                []Field        `gorm:"many2many:promotion_fields" json:"fields,omitempty"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Discount returns the amount taken off the given subtotal, which never
+// exceeds the subtotal itself.
+func (p *Promotion) Discount(subtotal Money) Money {
+	discount := NewMoney(p.DiscountValue, subtotal.Currency)
+	if p.DiscountType == DiscountPercentage {
+		discount = subtotal.Percent(int(p.DiscountValue))
+	}
+	return discount.Min(subtotal)
+}
+
+// PromotionRedemption records a promo code used on a booking. Redemptions
+// on bookings that were released or refunded no longer count towards the
+// promotion's caps.
+type PromotionRedemption struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	TenantID    uint      `gorm:"not null;default:1;index" json:"tenant_id"`
+	PromotionID uint      `gorm:"not null;index" json:"promotion_id"`
+	UserID      uint      `gorm:"not null;index" json:"user_id"`
+	BookingID   uint      `gorm:"not null;uniqueIndex" json:"booking_id"`
+	Amount      Money     `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
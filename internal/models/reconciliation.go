@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type ReconciliationIssueType string
+
+const (
+	// IssueNotCaptured is a gateway payment marked paid locally that the
+	// gateway never settled, or reported as failed
+	IssueNotCaptured ReconciliationIssueType = "not_captured"
+	// IssueNotRecorded is a capture the gateway settled without a matching
+	// paid payment or charge in the database
+	IssueNotRecorded ReconciliationIssueType = "not_recorded"
+	// IssueAmountMismatch is a capture settled for a different amount or
+	// currency than recorded locally
+	IssueAmountMismatch ReconciliationIssueType = "amount_mismatch"
+)
+
+type ReconciliationIssueStatus string
+
+const (
+	IssueOpen     ReconciliationIssueStatus = "open"
+	IssueResolved ReconciliationIssueStatus = "resolved"
+)
+
+// ReconciliationIssue is a discrepancy between the database and a gateway
+// settlement report. A transaction has at most one open issue per issue
+// type, so ingesting a report twice does not duplicate issues. Once resolved,
+// the discrepancy is flagged again by any later report that still shows it.
+type ReconciliationIssue struct {
+	ID            uint                      `gorm:"primarykey" json:"id"`
+	Type          ReconciliationIssueType   `gorm:"type:varchar(20);not null;uniqueIndex:idx_reconciliation_open_issue,where:status = 'open'" json:"type"`
+	TransactionID string                    `gorm:"not null;uniqueIndex:idx_reconciliation_open_issue" json:"transaction_id"`
+	Status        ReconciliationIssueStatus `gorm:"type:varchar(20);not null;default:open;index" json:"status"`
+	PaymentID     *uint                     `gorm:"index" json:"payment_id,omitempty"`
+	// Expected is the amount recorded locally, Settled the amount the
+	// gateway reported; either is zero when that side has no record
+	Expected   Money      `gorm:"embedded;embeddedPrefix:expected_" json:"expected"`
+	Settled    Money      `gorm:"embedded;embeddedPrefix:settled_" json:"settled"`
+	Source     string     `json:"source"`
+	Detail     string     `json:"detail"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy *uint      `json:"resolved_by,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
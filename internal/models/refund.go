@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundCompleted RefundStatus = "completed"
+	RefundFailed    RefundStatus = "failed"
+)
+
+type Refund struct {
+	ID               uint           `gorm:"primarykey" json:"id"`
+	TenantID         uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	PaymentID        uint           `gorm:"not null;index" json:"payment_id"`
+	Amount           Money          `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Reason           string         `gorm:"not null" json:"reason"`
+	Status           RefundStatus   `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	GatewayReference string         `gorm:"uniqueIndex" json:"gateway_reference"`
+	ToWallet         bool           `gorm:"default:false" json:"to_wallet"`
+	ProcessedBy      *uint          `json:"processed_by,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
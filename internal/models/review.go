@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewVisible ReviewStatus = "visible"
+	ReviewFlagged ReviewStatus = "flagged"
+	ReviewHidden  ReviewStatus = "hidden"
+)
+
+type Review struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	TenantID  uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	BookingID uint           `gorm:"uniqueIndex;not null" json:"booking_id"`
+	UserID    uint           `gorm:"not null;index" json:"user_id"`
+	User      User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	FieldID   uint           `gorm:"not null;index" json:"field_id"`
+	Rating    int            `gorm:"not null" json:"rating"`
+	Comment   string         `gorm:"type:text" json:"comment"`
+	Status    ReviewStatus   `gorm:"type:varchar(20);default:'visible'" json:"status"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedSearch describes a recurring slot a user wants to be alerted about.
+// It targets either a single field or a filter set over all fields, and
+// optionally narrows down to a weekday and a daily time window (HH:MM).
+// Searches only ever match slots of their own tenant.
+type SavedSearch struct {
+	ID              uint           `gorm:"primarykey" json:"id"`
+	TenantID        uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID          uint           `gorm:"not null;index" json:"user_id"`
+	Name            string         `json:"name"`
+	FieldID         *uint          `gorm:"index" json:"field_id,omitempty"`
+	Location        string         `json:"location,omitempty"`
+	MaxPricePerHour Money          `gorm:"embedded;embeddedPrefix:max_price_per_hour_" json:"max_price_per_hour"`
+	FavoritesOnly   bool           `json:"favorites_only"`
+	Weekday         *time.Weekday  `json:"weekday,omitempty"`
+	TimeFrom        string         `gorm:"type:varchar(5)" json:"time_from"`
+	TimeTo          string         `gorm:"type:varchar(5)" json:"time_to"`
+	Active          bool           `gorm:"default:true" json:"active"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TaxRate is a sales tax such as VAT. It applies to the fields linked to it,
+// otherwise to every field whose sport matches Category. An active rate with
+// no category is the default for all remaining fields.
+type TaxRate struct {
+	ID       uint   `gorm:"primarykey" json:"id"`
+	TenantID uint   `gorm:"not null;default:1;index" json:"tenant_id"`
+	Name     string `gorm:"not null" json:"name"`
+	Category string `gorm:"type:varchar(50);index" json:"category,omitempty"`
+	// Rate is in basis points: 1100 is 11%
+	Rate int `gorm:"not null" json:"rate"`
+	// Inclusive means field prices already contain the tax; otherwise it is
+	// added on top
+	Inclusive bool           `gorm:"default:false" json:"inclusive"`
+	Active    bool           `gorm:"default:true" json:"active"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// Split divides a price after discounts into its net amount and tax,
+// rounding the tax half up to the minor unit. Without a rate there is no tax.
+func (r *TaxRate) Split(amount Money) (net, tax Money) {
+	if r == nil || r.Rate == 0 || amount.Amount <= 0 {
+		return amount, NewMoney(0, amount.Currency)
+	}
+	rate := int64(r.Rate)
+	if r.Inclusive {
+		net = NewMoney((amount.Amount*10000+(10000+rate)/2)/(10000+rate), amount.Currency)
+		return net, amount.Sub(net)
+	}
+	return amount, NewMoney((amount.Amount*rate+5000)/10000, amount.Currency)
+}
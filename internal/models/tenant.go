@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// DefaultTenantID is the tenant that existed before the deployment was shared
+// and that requests without a tenant fall back to. Its admins operate the
+// platform: the ledger, reconciliation and owner payouts span all tenants.
+const DefaultTenantID uint = 1
+
+// Tenant is a sports club sharing the deployment. Users, fields, bookings,
+// payments and the club's own settings belong to exactly one tenant. The
+// club's invoices name it as the seller with its seller details, or with its
+// name when it has none.
+type Tenant struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	Slug          string    `gorm:"type:varchar(63);uniqueIndex;not null" json:"slug"`
+	Name          string    `gorm:"not null" json:"name"`
+	SellerName    string    `json:"seller_name"`
+	SellerAddress string    `json:"seller_address"`
+	SellerTaxID   string    `json:"seller_tax_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	// Embed the zone database so venue timezones resolve on hosts without one
+	_ "time/tzdata"
+)
+
+// LoadLocation returns the IANA timezone name, or UTC if the name is empty
+// or unknown.
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
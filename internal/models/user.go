@@ -12,18 +12,27 @@ type UserRole string
 const (
 	RoleUser  UserRole = "user"
 	RoleAdmin UserRole = "admin"
+	// RoleOwner is an independent venue owner who is paid out the revenue
+	// of their fields, less the platform commission
+	RoleOwner UserRole = "owner"
+	// RoleStaff is front desk staff who check players in
+	RoleStaff UserRole = "staff"
 )
 
+// User is a player, admin, venue owner or staff member of one tenant.
+// BookingBannedUntil is set while repeated no-shows keep them from booking.
 type User struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string         `gorm:"not null" json:"-"`
-	Name      string         `gorm:"not null" json:"name"`
-	Role      UserRole       `gorm:"type:varchar(20);default:'user'" json:"role"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-	Bookings  []Booking      `gorm:"foreignKey:UserID" json:"bookings,omitempty"`
+	ID                 uint           `gorm:"primarykey" json:"id"`
+	TenantID           uint           `gorm:"not null;default:1;uniqueIndex:idx_users_tenant_email" json:"tenant_id"`
+	Email              string         `gorm:"not null;uniqueIndex:idx_users_tenant_email" json:"email"`
+	Password           string         `gorm:"not null" json:"-"`
+	Name               string         `gorm:"not null" json:"name"`
+	Role               UserRole       `gorm:"type:varchar(20);default:'user'" json:"role"`
+	BookingBannedUntil *time.Time     `json:"booking_banned_until,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
+	Bookings           []Booking      `gorm:"foreignKey:UserID" json:"bookings,omitempty"`
 }
 
 func (u *User) HashPassword(password string) error {
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "waiting"
+	WaitlistOffered   WaitlistStatus = "offered"
+	WaitlistClaimed   WaitlistStatus = "claimed"
+	WaitlistLapsed    WaitlistStatus = "lapsed"
+	WaitlistCancelled WaitlistStatus = "cancelled"
+)
+
+// WaitlistEntry queues a user for an exact field and interval that is
+// currently booked. When the slot frees up, entries are offered in order and
+// the offered user holds an exclusive claim until ClaimExpiresAt.
+type WaitlistEntry struct {
+	ID             uint           `gorm:"primarykey" json:"id"`
+	TenantID       uint           `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID         uint           `gorm:"not null;index" json:"user_id"`
+	FieldID        uint           `gorm:"not null;index:idx_waitlist_slot" json:"field_id"`
+	Field          Field          `gorm:"foreignKey:FieldID" json:"field,omitempty"`
+	StartTime      time.Time      `gorm:"not null;index:idx_waitlist_slot" json:"start_time"`
+	EndTime        time.Time      `gorm:"not null;index:idx_waitlist_slot" json:"end_time"`
+	Status         WaitlistStatus `gorm:"type:varchar(20);default:'waiting'" json:"status"`
+	Position       int            `gorm:"-" json:"position,omitempty"`
+	OfferedAt      *time.Time     `json:"offered_at,omitempty"`
+	ClaimExpiresAt *time.Time     `json:"claim_expires_at,omitempty"`
+	BookingID      *uint          `json:"booking_id,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
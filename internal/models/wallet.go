@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+type WalletTransactionType string
+
+const (
+	WalletTopUp           WalletTransactionType = "top_up"
+	WalletBookingCharge   WalletTransactionType = "booking_charge"
+	WalletRefundCredit    WalletTransactionType = "refund_credit"
+	WalletAdminAdjustment WalletTransactionType = "admin_adjustment"
+	WalletPlanPurchase    WalletTransactionType = "plan_purchase"
+)
+
+// Wallet holds a user's prepaid credit. Balance is a running total of the
+// wallet's transactions, kept on the row so it can be locked and checked
+// before a charge.
+type Wallet struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	TenantID  uint      `gorm:"not null;default:1;index" json:"tenant_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+	Balance   Money     `gorm:"embedded;embeddedPrefix:balance_" json:"balance"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WalletTransaction is an append-only ledger entry. Amount is positive for
+// credits and negative for debits.
+type WalletTransaction struct {
+	ID           uint                  `gorm:"primarykey" json:"id"`
+	TenantID     uint                  `gorm:"not null;default:1;index" json:"tenant_id"`
+	WalletID     uint                  `gorm:"not null;index" json:"wallet_id"`
+	Type         WalletTransactionType `gorm:"type:varchar(30);not null" json:"type"`
+	Amount       Money                 `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	BalanceAfter Money                 `gorm:"embedded;embeddedPrefix:balance_after_" json:"balance_after"`
+	PaymentID    *uint                 `gorm:"index" json:"payment_id,omitempty"`
+	RefundID     *uint                 `json:"refund_id,omitempty"`
+	Reference    string                `gorm:"uniqueIndex" json:"reference"`
+	Description  string                `json:"description"`
+	CreatedBy    *uint                 `json:"created_by,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
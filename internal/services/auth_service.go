@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	"github.com/qolby/sports-booking-api/internal/config"
 	"github.com/qolby/sports-booking-api/internal/models"
@@ -18,6 +20,12 @@ func NewAuthService(db *gorm.DB, cfg *config.Config) *AuthService {
 	return &AuthService{db: db, cfg: cfg}
 }
 
+func (s *AuthService) WithContext(ctx context.Context) *AuthService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
@@ -62,7 +70,7 @@ func (s *AuthService) Register(req RegisterRequest) (*AuthResponse, error) {
 		return nil, err
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.Role), s.cfg)
+	token, err := utils.GenerateToken(user.ID, user.TenantID, user.Email, string(user.Role), s.cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +91,7 @@ func (s *AuthService) Login(req LoginRequest) (*AuthResponse, error) {
 		return nil, errors.New("invalid credentials")
 	}
 
-	token, err := utils.GenerateToken(user.ID, user.Email, string(user.Role), s.cfg)
+	token, err := utils.GenerateToken(user.ID, user.TenantID, user.Email, string(user.Role), s.cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -93,3 +101,31 @@ func (s *AuthService) Login(req LoginRequest) (*AuthResponse, error) {
 		Token: token,
 	}, nil
 }
+
+type SetRoleRequest struct {
+	Role models.UserRole `json:"role" validate:"required"`
+}
+
+// SetUserRole makes a player front desk staff or back. Admins and venue
+// owners keep their role.
+func (s *AuthService) SetUserRole(id uint, req SetRoleRequest) (*models.User, error) {
+	if req.Role != models.RoleUser && req.Role != models.RoleStaff {
+		return nil, errors.New("role must be user or staff")
+	}
+
+	var user models.User
+	if err := s.db.First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if user.Role != models.RoleUser && user.Role != models.RoleStaff {
+		return nil, fmt.Errorf("the role of %s users cannot be changed", user.Role)
+	}
+
+	if err := s.db.Model(&user).Update("role", req.Role).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
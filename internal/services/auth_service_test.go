@@ -139,3 +139,24 @@ func TestAuthService_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_SetUserRole(t *testing.T) {
+	db := setupTestDB()
+	authService := NewAuthService(db, &config.Config{})
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	admin := models.User{Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin}
+	db.Create(&admin)
+
+	updated, err := authService.SetUserRole(user.ID, SetRoleRequest{Role: models.RoleStaff})
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleStaff, updated.Role)
+
+	_, err = authService.SetUserRole(user.ID, SetRoleRequest{Role: models.RoleAdmin})
+	assert.Error(t, err)
+	_, err = authService.SetUserRole(admin.ID, SetRoleRequest{Role: models.RoleUser})
+	assert.Error(t, err)
+	_, err = authService.SetUserRole(user.ID+99, SetRoleRequest{Role: models.RoleUser})
+	assert.Error(t, err)
+}
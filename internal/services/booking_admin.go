@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+// PaymentUnpaid filters for bookings without a captured payment.
+const PaymentUnpaid = "unpaid"
+
+// ownBooking matches the bookings that are their user's own, leaving out
+// those of guests held on the account of the admin who made them.
+const ownBooking = "COALESCE(bookings.guest_name, '') = ''"
+
+// likeEscaper escapes the wildcards of a search term for a LIKE pattern, with
+// a backslash as the escape character.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// AdminBookingFilter narrows the bookings an admin lists, on top of the
+// list query. PaymentStatus is the status of any payment for the booking, or
+// "unpaid". Search matches the player's name or email or the guest's name or
+// phone.
+type AdminBookingFilter struct {
+	FieldID       uint   `query:"field_id"`
+	UserID        uint   `query:"user_id"`
+	PaymentStatus string `query:"payment_status"`
+	Search        string `query:"q"`
+}
+
+// ListBookings lists a page of every booking of the tenant matching the
+// filter.
+func (s *BookingService) ListBookings(filter AdminBookingFilter, q ListQuery) ([]models.Booking, *utils.Pagination, error) {
+	query := s.db.Preload("Field").Preload("User").Preload("Payments")
+	if filter.FieldID != 0 {
+		query = query.Where("field_id = ?", filter.FieldID)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	// Bookings in an order are paid by the order's payment
+	const payments = "SELECT 1 FROM payments WHERE (payments.booking_id = bookings.id OR payments.order_id = bookings.order_id) AND payments.deleted_at IS NULL"
+	switch status := models.PaymentStatus(filter.PaymentStatus); status {
+	case "":
+	case PaymentUnpaid:
+		captured := []models.PaymentStatus{models.PaymentCompleted, models.PaymentPartiallyRefunded, models.PaymentRefunded}
+		query = query.Where("NOT EXISTS ("+payments+" AND payments.status IN ?)", captured)
+	case models.PaymentPending, models.PaymentCompleted, models.PaymentFailed, models.PaymentPartiallyRefunded, models.PaymentRefunded:
+		query = query.Where("EXISTS ("+payments+" AND payments.status = ?)", status)
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown payment status %q", ErrInvalidListQuery, filter.PaymentStatus)
+	}
+
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(search)) + "%"
+		query = query.Where(`(user_id IN (SELECT id FROM users WHERE LOWER(name) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\') OR LOWER(guest_name) LIKE ? ESCAPE '\' OR guest_phone LIKE ? ESCAPE '\')`,
+			pattern, pattern, pattern, pattern)
+	}
+
+	var bookings []models.Booking
+	page, err := q.list(query, bookingListSpec, &bookings)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bookings, page, nil
+}
+
+// CustomerBookingRequest books a slot for a registered player, given by
+// UserID, or for a walk-in or phone guest without an account, given by
+// GuestName and GuestPhone. PaidInCash marks it paid with cash taken at the
+// desk.
+type CustomerBookingRequest struct {
+	CreateBookingRequest
+	UserID     uint   `json:"user_id"`
+	GuestName  string `json:"guest_name"`
+	GuestPhone string `json:"guest_phone"`
+	PaidInCash bool   `json:"paid_in_cash"`
+}
+
+// CreateBookingForCustomer books a slot on behalf of a player or guest. The
+// slot is checked like one players book themselves, against the player's
+// quotas and membership. A guest's booking is held on the admin's account,
+// but none of the admin's quotas, discounts or bans apply to it, and it takes
+// no promo code, having no account of its own to redeem one on. With
+// PaidInCash the admin is recorded as the cashier.
+func (s *BookingService) CreateBookingForCustomer(adminID uint, req CustomerBookingRequest) (*models.Booking, error) {
+	req.GuestName = strings.TrimSpace(req.GuestName)
+	req.GuestPhone = strings.TrimSpace(req.GuestPhone)
+	guest := req.GuestName != "" || req.GuestPhone != ""
+	if (req.UserID != 0) == guest {
+		return nil, errors.New("either user_id or guest_name and guest_phone is required")
+	}
+	if guest && (req.GuestName == "" || req.GuestPhone == "") {
+		return nil, errors.New("guests need both a name and a phone number")
+	}
+	if guest && req.UsePackage {
+		return nil, errors.New("guests cannot pay with package sessions")
+	}
+	if guest && req.PromoCode != "" {
+		return nil, errors.New("guests cannot use promo codes")
+	}
+	if req.PaidInCash && req.UsePackage {
+		return nil, errors.New("a booking paid with package sessions cannot also be paid in cash")
+	}
+
+	var booking *models.Booking
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if !guest {
+			if err := tx.Select("id").First(&models.User{}, req.UserID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("user not found")
+				}
+				return err
+			}
+		}
+
+		var err error
+		booking, err = holdSlot(tx, s.cfg, req.UserID, req.CreateBookingRequest, &adminID)
+		if err != nil {
+			return err
+		}
+		if guest {
+			booking.GuestName, booking.GuestPhone = req.GuestName, req.GuestPhone
+			if err := tx.Model(booking).Updates(map[string]interface{}{"guest_name": req.GuestName, "guest_phone": req.GuestPhone}).Error; err != nil {
+				return err
+			}
+		}
+		if req.PaidInCash {
+			_, err = recordCashPayment(tx, booking, adminID)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Load relations
+	s.db.Preload("Field").Preload("User").Preload("Payments").First(booking, booking.ID)
+
+	return booking, nil
+}
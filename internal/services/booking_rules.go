@@ -0,0 +1,170 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// BookingRuleViolation is one rule a requested slot breaks. Field names the
+// request field at fault.
+type BookingRuleViolation struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// BookingRuleError lists every rule a requested slot breaks, so clients can
+// show them all at once.
+type BookingRuleError struct {
+	Violations []BookingRuleViolation
+}
+
+func (e *BookingRuleError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (e *BookingRuleError) add(rule, field, format string, args ...interface{}) {
+	e.Violations = append(e.Violations, BookingRuleViolation{Rule: rule, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the error if any rule was broken, and nil otherwise.
+func (e *BookingRuleError) err() error {
+	if len(e.Violations) == 0 {
+		return nil
+	}
+	return e
+}
+
+func newBookingRuleError(rule, field, format string, args ...interface{}) error {
+	e := &BookingRuleError{}
+	e.add(rule, field, format, args...)
+	return e
+}
+
+// checkBookingRules checks a requested slot against the field's opening hours
+// and booking rules, and reports every rule it breaks.
+func checkBookingRules(field models.Field, start, end, now time.Time) error {
+	violations := &BookingRuleError{}
+	if !end.After(start) {
+		violations.add("time_range", "end_time", "end time must be after start time")
+		return violations
+	}
+
+	rules := field.BookingRules
+	if !start.After(now) {
+		violations.add("past", "start_time", "start time is in the past")
+	} else if notice := minutes(rules.MinNoticeMinutes); notice > 0 && start.Before(now.Add(notice)) {
+		violations.add("min_notice", "start_time", "field must be booked at least %s before the start", notice)
+	}
+	if rules.MaxAdvanceDays > 0 && start.After(now.AddDate(0, 0, rules.MaxAdvanceDays)) {
+		violations.add("max_advance", "start_time", "field can be booked at most %d days ahead", rules.MaxAdvanceDays)
+	}
+
+	duration := end.Sub(start)
+	if shortest := minutes(rules.MinDurationMinutes); shortest > 0 && duration < shortest {
+		violations.add("min_duration", "end_time", "bookings must last at least %s", shortest)
+	}
+	if longest := minutes(rules.MaxDurationMinutes); longest > 0 && duration > longest {
+		violations.add("max_duration", "end_time", "bookings can last at most %s", longest)
+	}
+
+	if slot := minutes(rules.SlotMinutes); slot > 0 {
+		// The grid is laid on the wall clock, so it stays put across DST
+		// changes
+		var anchor time.Duration
+		if field.OpenTime != "" {
+			if opens, err := parseClock(field.OpenTime); err == nil {
+				anchor = opens
+			}
+		}
+		local := start.In(field.Zone())
+		clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+			time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+		if (clock-anchor)%slot != 0 {
+			violations.add("slot_alignment", "start_time", "bookings must start on a %s slot from %s", slot, atClock(start, field.Zone(), anchor).Format("15:04"))
+		}
+		if duration%slot != 0 {
+			violations.add("slot_granularity", "end_time", "bookings must last a whole number of %s slots", slot)
+		}
+	}
+
+	if err := checkOperatingHours(field, start, end); err != nil {
+		violations.add("operating_hours", "start_time", "%s", err)
+	}
+
+	return violations.err()
+}
+
+// resolveSlotTimes reads a slot given as wall clock times in the field's
+// timezone, and stores every slot in UTC.
+func resolveSlotTimes(field models.Field, start, end *time.Time, localStart, localEnd string) error {
+	if localStart != "" || localEnd != "" {
+		var err error
+		if *start, err = parseLocalTime(localStart, field.Zone()); err != nil {
+			return newBookingRuleError("local_time", "local_start_time", "%s", err)
+		}
+		if *end, err = parseLocalTime(localEnd, field.Zone()); err != nil {
+			return newBookingRuleError("local_time", "local_end_time", "%s", err)
+		}
+	}
+	*start, *end = start.UTC(), end.UTC()
+	return nil
+}
+
+// checkBuffer keeps the field's cleanup buffer free around a slot. Bookings
+// in exclude, such as the one being rescheduled, are ignored.
+func checkBuffer(tx *gorm.DB, field models.Field, start, end time.Time, exclude ...uint) error {
+	buffer := minutes(field.BookingRules.BufferMinutes)
+	if buffer == 0 {
+		return nil
+	}
+
+	query := overlappingBookings(tx, field.ID, start.Add(-buffer), end.Add(buffer))
+	if len(exclude) > 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return newBookingRuleError("buffer", "start_time", "field needs %s free between bookings", buffer)
+	}
+	return nil
+}
+
+// validateBookingRules rejects rules that cannot be satisfied.
+func validateBookingRules(rules models.BookingRules) error {
+	for _, value := range []int{rules.MinDurationMinutes, rules.MaxDurationMinutes, rules.SlotMinutes, rules.BufferMinutes, rules.MinNoticeMinutes, rules.MaxAdvanceDays} {
+		if value < 0 {
+			return errors.New("booking rules cannot be negative")
+		}
+	}
+	if rules.MaxDurationMinutes > 0 && rules.MaxDurationMinutes < rules.MinDurationMinutes {
+		return errors.New("max duration cannot be shorter than min duration")
+	}
+	if rules.SlotMinutes > 0 {
+		if rules.SlotMinutes > 24*60 {
+			return errors.New("slots cannot be longer than a day")
+		}
+		for _, duration := range []int{rules.MinDurationMinutes, rules.MaxDurationMinutes} {
+			if duration%rules.SlotMinutes != 0 {
+				return errors.New("min and max duration must be whole slots")
+			}
+		}
+	}
+	return nil
+}
+
+func minutes(n int) time.Duration {
+	return time.Duration(n) * time.Minute
+}
@@ -1,34 +1,89 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/qolby/sports-booking-api/internal/config"
 	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrSlotTaken = errors.New("field is already booked for this time slot")
+
 type BookingService struct {
-	db *gorm.DB
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewBookingService(db *gorm.DB, cfg *config.Config) *BookingService {
+	return &BookingService{db: db, cfg: cfg}
 }
 
-func NewBookingService(db *gorm.DB) *BookingService {
-	return &BookingService{db: db}
+func (s *BookingService) WithContext(ctx context.Context) *BookingService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
 }
 
+// CreateBookingRequest gives the slot as instants in StartTime and EndTime,
+// or as wall clock times in the field's timezone in LocalStartTime and
+// LocalEndTime (2006-01-02T15:04).
 type CreateBookingRequest struct {
-	FieldID   uint      `json:"field_id" validate:"required"`
-	StartTime time.Time `json:"start_time" validate:"required"`
-	EndTime   time.Time `json:"end_time" validate:"required"`
+	FieldID        uint      `json:"field_id" validate:"required"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	LocalStartTime string    `json:"local_start_time"`
+	LocalEndTime   string    `json:"local_end_time"`
+	PromoCode      string    `json:"promo_code"`
+	// UsePackage pays for the slot with package sessions instead of money
+	UsePackage bool `json:"use_package"`
+}
+
+// BookingQuote is the price a slot would be booked at, without holding it.
+type BookingQuote struct {
+	FieldID        uint         `json:"field_id"`
+	StartTime      time.Time    `json:"start_time"`
+	EndTime        time.Time    `json:"end_time"`
+	Timezone       string       `json:"timezone"`
+	LocalStartTime time.Time    `json:"local_start_time"`
+	LocalEndTime   time.Time    `json:"local_end_time"`
+	Available      bool         `json:"available"`
+	Subtotal       models.Money `json:"subtotal"`
+	DiscountAmount models.Money `json:"discount_amount"`
+	PromoCode      string       `json:"promo_code,omitempty"`
+	PlanDiscount   models.Money `json:"plan_discount"`
+	Sessions       int          `json:"package_sessions,omitempty"`
+	NetAmount      models.Money `json:"net_amount"`
+	TaxAmount      models.Money `json:"tax_amount"`
+	TaxRate        int          `json:"tax_rate"`
+	TaxInclusive   bool         `json:"tax_inclusive"`
+	TotalPrice     models.Money `json:"total_price"`
 }
 
 func (s *BookingService) CreateBooking(userID uint, req CreateBookingRequest) (*models.Booking, error) {
-	// Validate time range
-	if req.EndTime.Before(req.StartTime) || req.EndTime.Equal(req.StartTime) {
-		return nil, errors.New("end time must be after start time")
+	var booking *models.Booking
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, err = holdSlot(tx, s.cfg, userID, req, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Check if field exists
+	// Load relations
+	s.db.Preload("Field").Preload("User").First(booking, booking.ID)
+
+	return booking, nil
+}
+
+// QuoteBooking prices a slot, including any promo code, without holding it.
+func (s *BookingService) QuoteBooking(userID uint, req CreateBookingRequest) (*BookingQuote, error) {
 	var field models.Field
 	if err := s.db.First(&field, req.FieldID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -36,60 +91,677 @@ func (s *BookingService) CreateBooking(userID uint, req CreateBookingRequest) (*
 		}
 		return nil, err
 	}
+	if err := resolveSlotTimes(field, &req.StartTime, &req.EndTime, req.LocalStartTime, req.LocalEndTime); err != nil {
+		return nil, err
+	}
+	if err := checkBookingRules(field, req.StartTime, req.EndTime, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := checkBookingWindow(s.db, s.cfg, userID, req.StartTime); err != nil {
+		return nil, err
+	}
 
-	// Check for overlapping bookings
-	var count int64
-	s.db.Model(&models.Booking{}).Where(
-		"field_id = ? AND status != ? AND ((start_time < ? AND end_time > ?) OR (start_time < ? AND end_time > ?) OR (start_time >= ? AND end_time <= ?))",
-		req.FieldID,
-		models.StatusCancelled,
-		req.EndTime, req.StartTime,
-		req.EndTime, req.EndTime,
-		req.StartTime, req.EndTime,
-	).Count(&count)
+	price, err := priceSlot(s.db, userID, field, req)
+	if err != nil {
+		return nil, err
+	}
 
-	if count > 0 {
-		return nil, errors.New("field is already booked for this time slot")
+	quote := &BookingQuote{
+		FieldID:        field.ID,
+		StartTime:      req.StartTime,
+		EndTime:        req.EndTime,
+		Timezone:       field.Timezone,
+		LocalStartTime: req.StartTime.In(field.Zone()),
+		LocalEndTime:   req.EndTime.In(field.Zone()),
+		Available:      !isSlotTaken(s.db, field.ID, req.StartTime, req.EndTime) && !isSlotOffered(s.db, field.ID, req.StartTime, req.EndTime, userID) && checkBuffer(s.db, field, req.StartTime, req.EndTime) == nil,
+		Subtotal:       price.Subtotal,
+		DiscountAmount: price.PromoDiscount,
+		PlanDiscount:   price.PlanDiscount,
+		Sessions:       price.PackageSessions,
+		NetAmount:      price.NetAmount,
+		TaxAmount:      price.TaxAmount,
+		TotalPrice:     price.Total(),
 	}
+	if price.Promotion != nil {
+		quote.PromoCode = price.Promotion.Code
+	}
+	if price.TaxRate != nil {
+		quote.TaxRate = price.TaxRate.Rate
+		quote.TaxInclusive = price.TaxRate.Inclusive
+	}
+	return quote, nil
+}
 
-	// Calculate total price
-	duration := req.EndTime.Sub(req.StartTime).Hours()
-	totalPrice := int(duration * float64(field.PricePerHour))
+// bookingListSpec lists bookings newest slot first. Their time is the start
+// time, and upcoming bookings have not ended.
+var bookingListSpec = listSpec{
+	sorts: map[string]string{
+		"id":          "id",
+		"start_time":  "start_time",
+		"created_at":  "created_at",
+		"status":      "status",
+		"total_price": "total_price_amount",
+	},
+	defaultSort: "-start_time",
+	status:      "status",
+	time:        "start_time",
+	upcoming:    "end_time",
+}
 
-	booking := models.Booking{
-		UserID:     userID,
-		FieldID:    req.FieldID,
-		StartTime:  req.StartTime,
-		EndTime:    req.EndTime,
-		Status:     models.StatusPending,
-		TotalPrice: totalPrice,
+// GetUserBookings lists a page of the user's bookings. Fields are only
+// loaded for the bookings on the page.
+func (s *BookingService) GetUserBookings(userID uint, q ListQuery) ([]models.Booking, *utils.Pagination, error) {
+	var bookings []models.Booking
+	page, err := q.list(s.db.Preload("Field").Where("user_id = ?", userID).Where(ownBooking), bookingListSpec, &bookings)
+	if err != nil {
+		return nil, nil, err
 	}
+	return bookings, page, nil
+}
 
-	if err := s.db.Create(&booking).Error; err != nil {
+func (s *BookingService) GetBookingByID(id uint) (*models.Booking, error) {
+	var booking models.Booking
+	if err := s.db.Preload("Field").Preload("User").Preload("Payments").First(&booking, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("booking not found")
+		}
 		return nil, err
 	}
+	return &booking, nil
+}
 
-	// Load relations
-	s.db.Preload("Field").Preload("User").First(&booking, booking.ID)
+func (s *BookingService) CancelBooking(userID, id uint) (*models.Booking, error) {
+	var booking models.Booking
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the booking, so that a share being paid or the split deadline
+		// job waits here and then finds it cancelled
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("booking not found")
+			}
+			return err
+		}
+
+		if booking.UserID != userID {
+			return errors.New("booking does not belong to user")
+		}
+		if !booking.Status.CanTransitionTo(models.StatusCancelled) {
+			return errors.New("booking cannot be cancelled")
+		}
+		if booking.OrderID != nil && booking.Status == models.StatusPending {
+			return errors.New("booking is part of an unpaid order, cancel the order instead")
+		}
+		if !booking.StartTime.After(time.Now()) {
+			return errors.New("booking has already started")
+		}
+
+		if err := transitionBooking(tx, &booking, models.StatusCancelled, &userID, "cancelled by player"); err != nil {
+			return err
+		}
+
+		// Package sessions come back when the slot is released early enough
+		if booking.PackageSessions > 0 && time.Until(booking.StartTime) >= s.cfg.Booking.SessionRestoreNotice {
+			if err := restoreSessions(tx, *booking.SubscriptionID, booking.PackageSessions); err != nil {
+				return err
+			}
+		}
+
+		// Participants who already paid their share get it back
+		var split models.PaymentSplit
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("booking_id = ? AND status = ?", booking.ID, models.SplitOpen).First(&split).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return releaseSplit(tx, &split)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &booking, nil
 }
 
-func (s *BookingService) GetUserBookings(userID uint) ([]models.Booking, error) {
-	var bookings []models.Booking
-	if err := s.db.Preload("Field").Where("user_id = ?", userID).Find(&bookings).Error; err != nil {
+// ExpirePendingBookings releases unpaid holds created before the cutoff and
+// returns how many bookings were expired.
+func (s *BookingService) ExpirePendingBookings(cutoff time.Time) (int64, error) {
+	var expired int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Confirmed bookings wait on split payments and stay held until the
+		// split deadline
+		var err error
+		expired, err = transitionBookings(tx, models.StatusExpired, nil, "hold expired",
+			"status = ? AND created_at < ?", models.StatusPending, cutoff)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Order{}).
+			Where("status = ? AND created_at < ?", models.OrderPending, cutoff).
+			Update("status", models.OrderExpired).Error
+	})
+	return expired, err
+}
+
+// CompleteBookings marks checked-in bookings that ended before now as
+// completed and returns how many were. Without no-show tracking, paid
+// bookings complete without a check-in.
+func (s *BookingService) CompleteBookings(now time.Time) (int64, error) {
+	statuses := []models.BookingStatus{models.StatusCheckedIn}
+	if s.cfg.CheckIn.NoShowGrace == 0 {
+		statuses = append(statuses, models.StatusPaid, models.StatusPartiallyRefunded)
+	}
+
+	var completed int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		completed, err = transitionBookings(tx, models.StatusCompleted, nil, "booking ended",
+			"status IN ? AND end_time <= ?", statuses, now)
+		return err
+	})
+	return completed, err
+}
+
+// holdSlot validates a requested slot and creates a pending booking for it.
+// A slot paid with package sessions is confirmed straight away. bookedBy is
+// the admin booking on the player's behalf, or nil when players book for
+// themselves. Callers run it inside a transaction so several slots can be
+// held together.
+func holdSlot(tx *gorm.DB, cfg *config.Config, userID uint, req CreateBookingRequest, bookedBy *uint) (*models.Booking, error) {
+	// A guest, given by a zero userID, has no account of its own: no ban,
+	// quota or membership applies, and the slot is held on the account of
+	// the admin booking it
+	guest := userID == 0
+	holderID := userID
+	if guest {
+		holderID = *bookedBy
+	} else if err := checkBookingBan(tx, userID); err != nil {
+		return nil, err
+	}
+
+	// Check if field exists
+	var field models.Field
+	if err := tx.First(&field, req.FieldID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("field not found")
+		}
+		return nil, err
+	}
+
+	if err := resolveSlotTimes(field, &req.StartTime, &req.EndTime, req.LocalStartTime, req.LocalEndTime); err != nil {
+		return nil, err
+	}
+	if err := checkBookingRules(field, req.StartTime, req.EndTime, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := checkBookingWindow(tx, cfg, userID, req.StartTime); err != nil {
+		return nil, err
+	}
+	if !guest {
+		if err := checkBookingQuotas(tx, cfg, userID, field, req.StartTime, req.EndTime); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check for overlapping bookings
+	if isSlotTaken(tx, req.FieldID, req.StartTime, req.EndTime) {
+		return nil, ErrSlotTaken
+	}
+	if err := checkBuffer(tx, field, req.StartTime, req.EndTime); err != nil {
+		return nil, err
+	}
+
+	// A freed slot may be reserved for someone on the waitlist
+	if isSlotOffered(tx, req.FieldID, req.StartTime, req.EndTime, userID) {
+		return nil, errors.New("time slot is reserved for a waitlisted user")
+	}
+
+	price, err := priceSlot(tx, userID, field, req)
+	if err != nil {
+		return nil, err
+	}
+
+	booking := models.Booking{
+		UserID:          holderID,
+		FieldID:         req.FieldID,
+		CreatedByID:     bookedBy,
+		StartTime:       req.StartTime,
+		EndTime:         req.EndTime,
+		Timezone:        field.Timezone,
+		Status:          models.StatusPending,
+		Subtotal:        price.Subtotal,
+		DiscountAmount:  price.PromoDiscount,
+		PlanDiscount:    price.PlanDiscount,
+		PackageSessions: price.PackageSessions,
+		NetAmount:       price.NetAmount,
+		TaxAmount:       price.TaxAmount,
+		TotalPrice:      price.Total(),
+	}
+	if price.Promotion != nil {
+		booking.PromotionID = &price.Promotion.ID
+		booking.PromoCode = price.Promotion.Code
+	}
+	if price.TaxRate != nil {
+		booking.TaxRateID = &price.TaxRate.ID
+		booking.TaxRate = price.TaxRate.Rate
+		booking.TaxInclusive = price.TaxRate.Inclusive
+	}
+	if price.Subscription != nil {
+		booking.SubscriptionID = &price.Subscription.ID
+	}
+	if price.PackageSessions > 0 {
+		booking.Status = models.StatusPaid
+	}
+
+	if err := tx.Create(&booking).Error; err != nil {
 		return nil, err
 	}
-	return bookings, nil
+	reason, actorID := "slot held", &userID
+	if booking.Status == models.StatusPaid {
+		reason = "paid with package sessions"
+	}
+	if bookedBy != nil {
+		reason, actorID = reason+" by admin", bookedBy
+	}
+	if err := recordBookingCreated(tx, &booking, actorID, reason); err != nil {
+		return nil, err
+	}
+	if price.Promotion != nil {
+		if err := tx.Create(&models.PromotionRedemption{
+			TenantID:    booking.TenantID,
+			PromotionID: price.Promotion.ID,
+			UserID:      holderID,
+			BookingID:   booking.ID,
+			Amount:      price.PromoDiscount,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if price.PackageSessions > 0 {
+		if err := consumeSessions(tx, price.Subscription.ID, price.PackageSessions); err != nil {
+			return nil, err
+		}
+	}
+	if !guest {
+		if err := claimWaitlistOffer(tx, &booking); err != nil {
+			return nil, err
+		}
+	}
+
+	return &booking, nil
 }
 
-func (s *BookingService) GetBookingByID(id uint) (*models.Booking, error) {
+// overlappingBookings scopes a query to the active bookings on the field that
+// overlap the half-open interval [start, end).
+func overlappingBookings(db *gorm.DB, fieldID uint, start, end time.Time) *gorm.DB {
+	return db.Model(&models.Booking{}).Where(
+		"field_id = ? AND status NOT IN ? AND start_time < ? AND end_time > ?",
+		fieldID, models.InactiveBookingStatuses, end, start,
+	)
+}
+
+// isSlotTaken reports whether any active booking on the field overlaps the
+// half-open interval [start, end).
+func isSlotTaken(db *gorm.DB, fieldID uint, start, end time.Time) bool {
+	var count int64
+	overlappingBookings(db, fieldID, start, end).Count(&count)
+	return count > 0
+}
+
+// checkOperatingHours rejects slots outside the field's opening window. The
+// window applies to the local day the booking starts on.
+func checkOperatingHours(field models.Field, start, end time.Time) error {
+	if field.OpenTime == "" || field.CloseTime == "" {
+		return nil
+	}
+	opens, err := parseClock(field.OpenTime)
+	if err != nil {
+		return err
+	}
+	closes, err := parseClock(field.CloseTime)
+	if err != nil {
+		return err
+	}
+
+	loc := field.Zone()
+	if start.Before(atClock(start, loc, opens)) || end.After(atClock(start, loc, closes)) {
+		return fmt.Errorf("field is only open from %s to %s", field.OpenTime, field.CloseTime)
+	}
+	return nil
+}
+
+// slotPrice breaks down what a slot costs a user.
+type slotPrice struct {
+	Subtotal        models.Money
+	Promotion       *models.Promotion
+	PromoDiscount   models.Money
+	Subscription    *models.Subscription
+	PlanDiscount    models.Money
+	PackageSessions int
+	TaxRate         *models.TaxRate
+	NetAmount       models.Money
+	TaxAmount       models.Money
+}
+
+func (p slotPrice) Total() models.Money {
+	return p.NetAmount.Add(p.TaxAmount)
+}
+
+// applyTax splits the discounted price into net amount and tax.
+func (p *slotPrice) applyTax(tx *gorm.DB, field models.Field) error {
+	taxRate, err := resolveTaxRate(tx, field)
+	if err != nil {
+		return err
+	}
+	p.TaxRate = taxRate
+	p.NetAmount, p.TaxAmount = taxRate.Split(p.Subtotal.Sub(p.PromoDiscount).Sub(p.PlanDiscount))
+	return nil
+}
+
+// priceSlot prices a slot for a user. A slot paid with package sessions is
+// fully covered; otherwise the request's promo code and the user's
+// membership discount both apply to the list price.
+func priceSlot(tx *gorm.DB, userID uint, field models.Field, req CreateBookingRequest) (slotPrice, error) {
+	subtotal := calculatePrice(field, req.StartTime, req.EndTime)
+	price := slotPrice{Subtotal: subtotal, PromoDiscount: models.NewMoney(0, subtotal.Currency), PlanDiscount: models.NewMoney(0, subtotal.Currency)}
+
+	if req.UsePackage {
+		if req.PromoCode != "" {
+			return price, errors.New("promo codes cannot be combined with package sessions")
+		}
+		sessions := sessionsFor(req.StartTime, req.EndTime)
+		pkg := usablePackage(tx, userID, sessions)
+		if pkg == nil {
+			return price, fmt.Errorf("no package with %d sessions left", sessions)
+		}
+		price.Subscription = pkg
+		price.PackageSessions = sessions
+		price.PlanDiscount = price.Subtotal
+		return price, price.applyTax(tx, field)
+	}
+
+	if req.PromoCode != "" {
+		promotion, discount, err := applyPromoCode(tx, userID, req.PromoCode, field, req.StartTime, price.Subtotal)
+		if err != nil {
+			return price, err
+		}
+		price.Promotion = promotion
+		price.PromoDiscount = discount
+	}
+
+	if membership := activeMembership(tx, userID); membership != nil && membership.Plan.DiscountPercent > 0 {
+		price.Subscription = membership
+		price.PlanDiscount = price.Subtotal.Percent(membership.Plan.DiscountPercent).Min(price.Subtotal.Sub(price.PromoDiscount))
+	}
+
+	return price, price.applyTax(tx, field)
+}
+
+// calculatePrice charges the hourly rate pro rata to the second, rounding
+// half up to the minor unit of the field's currency.
+func calculatePrice(field models.Field, start, end time.Time) models.Money {
+	seconds := int64(end.Sub(start) / time.Second)
+	amount := (field.PricePerHour.Amount*seconds + 1800) / 3600
+	return models.NewMoney(amount, field.PricePerHour.Currency)
+}
+
+// RescheduleBookingRequest gives the new slot like CreateBookingRequest.
+type RescheduleBookingRequest struct {
+	FieldID        uint      `json:"field_id"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	LocalStartTime string    `json:"local_start_time"`
+	LocalEndTime   string    `json:"local_end_time"`
+}
+
+// RescheduleBooking moves a booking to a new interval, optionally on another
+// field, keeping its payment. The price difference is settled against the
+// existing payment with a top-up charge or a partial refund.
+func (s *BookingService) RescheduleBooking(userID, id uint, req RescheduleBookingRequest) (*models.Booking, error) {
 	var booking models.Booking
-	if err := s.db.Preload("Field").Preload("User").Preload("Payment").First(&booking, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("booking not found")
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("booking not found")
+			}
+			return err
+		}
+
+		if booking.UserID != userID {
+			return errors.New("booking does not belong to user")
+		}
+		if booking.Status != models.StatusPending && !booking.IsPaid() {
+			return errors.New("booking cannot be rescheduled")
+		}
+		if hasPaymentSplit(tx, booking.ID) {
+			return errors.New("bookings with split payments cannot be rescheduled")
+		}
+		if time.Until(booking.StartTime) < s.cfg.Booking.RescheduleMinNotice {
+			return fmt.Errorf("bookings can only be rescheduled up to %s before they start", s.cfg.Booking.RescheduleMinNotice)
+		}
+		if booking.RescheduleCount >= s.cfg.Booking.MaxReschedules {
+			return fmt.Errorf("booking has already been rescheduled %d times", booking.RescheduleCount)
+		}
+
+		fieldID := booking.FieldID
+		if req.FieldID != 0 {
+			fieldID = req.FieldID
 		}
+
+		var field models.Field
+		if err := tx.First(&field, fieldID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("field not found")
+			}
+			return err
+		}
+		if !field.PricePerHour.SameCurrency(booking.TotalPrice) {
+			return errors.New("bookings cannot move to a field priced in another currency")
+		}
+
+		if err := resolveSlotTimes(field, &req.StartTime, &req.EndTime, req.LocalStartTime, req.LocalEndTime); err != nil {
+			return err
+		}
+		if err := checkBookingRules(field, req.StartTime, req.EndTime, time.Now()); err != nil {
+			return err
+		}
+		if err := checkBookingWindow(tx, s.cfg, userID, req.StartTime); err != nil {
+			return err
+		}
+		if err := checkBookingQuotas(tx, s.cfg, userID, field, req.StartTime, req.EndTime, booking.ID); err != nil {
+			return err
+		}
+
+		var count int64
+		overlappingBookings(tx, fieldID, req.StartTime, req.EndTime).Where("id != ?", booking.ID).Count(&count)
+		if count > 0 {
+			return ErrSlotTaken
+		}
+		if err := checkBuffer(tx, field, req.StartTime, req.EndTime, booking.ID); err != nil {
+			return err
+		}
+		if isSlotOffered(tx, fieldID, req.StartTime, req.EndTime, userID) {
+			return errors.New("time slot is reserved for a waitlisted user")
+		}
+
+		// The booking's promo code keeps applying to the new slot as long as
+		// it could still be used there
+		subtotal := calculatePrice(field, req.StartTime, req.EndTime)
+		discount := models.NewMoney(0, subtotal.Currency)
+		if booking.PromotionID != nil {
+			promotion, promoDiscount, err := applyPromoCode(tx, userID, booking.PromoCode, field, req.StartTime, subtotal, booking.ID)
+			if err != nil {
+				return err
+			}
+			if promotion.ID != *booking.PromotionID {
+				return ErrInvalidPromoCode
+			}
+			discount = promoDiscount
+			if err := tx.Model(&models.PromotionRedemption{}).Where("booking_id = ?", booking.ID).Updates(map[string]interface{}{"amount_amount": discount.Amount, "amount_currency": discount.Currency}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Plan discounts follow the booking too; package bookings stay
+		// covered as long as they use the same number of sessions
+		planDiscount := models.NewMoney(0, subtotal.Currency)
+		if booking.PackageSessions > 0 {
+			if sessionsFor(req.StartTime, req.EndTime) != booking.PackageSessions {
+				return errors.New("package bookings can only move to a slot using the same number of sessions")
+			}
+			planDiscount = subtotal
+		} else if booking.SubscriptionID != nil {
+			var subscription models.Subscription
+			if err := tx.Preload("Plan").First(&subscription, *booking.SubscriptionID).Error; err != nil {
+				return err
+			}
+			planDiscount = subtotal.Percent(subscription.Plan.DiscountPercent).Min(subtotal.Sub(discount))
+		}
+
+		price := slotPrice{Subtotal: subtotal, PromoDiscount: discount, PlanDiscount: planDiscount}
+		if err := price.applyTax(tx, field); err != nil {
+			return err
+		}
+		var taxRateID *uint
+		taxRate, taxInclusive := 0, false
+		if price.TaxRate != nil {
+			taxRateID = &price.TaxRate.ID
+			taxRate, taxInclusive = price.TaxRate.Rate, price.TaxRate.Inclusive
+		}
+
+		newPrice := price.Total()
+		diff := newPrice.Sub(booking.TotalPrice)
+		if booking.OrderID != nil {
+			if err := tx.Model(&models.Order{}).Where("id = ?", *booking.OrderID).
+				Update("total_amount_amount", gorm.Expr("total_amount_amount + ?", diff.Amount)).Error; err != nil {
+				return err
+			}
+		}
+
+		// The old slot is released like a cancelled one
+		if err := tx.Create(&models.BookingReschedule{
+			TenantID:  booking.TenantID,
+			BookingID: booking.ID,
+			UserID:    booking.UserID,
+			FieldID:   booking.FieldID,
+			StartTime: booking.StartTime,
+			EndTime:   booking.EndTime,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&booking).Updates(map[string]interface{}{
+			"field_id":               fieldID,
+			"timezone":               field.Timezone,
+			"start_time":             req.StartTime,
+			"end_time":               req.EndTime,
+			"subtotal_amount":        subtotal.Amount,
+			"discount_amount_amount": discount.Amount,
+			"plan_discount_amount":   planDiscount.Amount,
+			"net_amount_amount":      price.NetAmount.Amount,
+			"tax_amount_amount":      price.TaxAmount.Amount,
+			"tax_rate_id":            taxRateID,
+			"tax_rate":               taxRate,
+			"tax_inclusive":          taxInclusive,
+			"total_price_amount":     newPrice.Amount,
+			"reschedule_count":       gorm.Expr("reschedule_count + 1"),
+		}).Error; err != nil {
+			return err
+		}
+
+		// The difference is settled once the booking is on its new slot, so
+		// a top-up is invoiced for the slot it pays for
+		if !booking.IsPaid() || booking.PackageSessions > 0 {
+			return nil
+		}
+		if err := tx.First(&booking, booking.ID).Error; err != nil {
+			return err
+		}
+		payment, err := bookingPayment(tx, &booking)
+		if err != nil {
+			return err
+		}
+		return settlePriceDifference(tx, payment, &booking, &field, diff)
+	})
+	if err != nil {
 		return nil, err
 	}
+
+	s.db.Preload("Field").Preload("User").Preload("Payments.Adjustments").Preload("Payments.Refunds").First(&booking, booking.ID)
+
 	return &booking, nil
 }
+
+// bookingPayment returns the payment that covers a booking, which is either
+// its own payment or the payment of the order it belongs to.
+func bookingPayment(tx *gorm.DB, booking *models.Booking) (*models.Payment, error) {
+	var payment models.Payment
+	query := tx.Where("booking_id = ?", booking.ID)
+	if booking.OrderID != nil {
+		query = tx.Where("order_id = ?", *booking.OrderID)
+	}
+	if err := query.First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment not found")
+		}
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// settlePriceDifference records a top-up charge (positive diff) or a partial
+// refund (negative diff) against the payment. Charges are earned on the
+// field the booking moves to.
+func settlePriceDifference(tx *gorm.DB, payment *models.Payment, booking *models.Booking, field *models.Field, diff models.Money) error {
+	switch {
+	case diff.Amount > 0:
+		return recordCharge(tx, payment, booking, field, diff, "reschedule")
+	case diff.Amount < 0:
+		_, err := issueRefund(tx, payment, diff.Neg(), "reschedule", nil, false)
+		return err
+	}
+	return nil
+}
+
+// recordCharge adds a follow-up charge to a payment. Like the initial
+// payment, the charge is taken from the wallet or processed by the mock
+// gateway, and settles immediately.
+func recordCharge(tx *gorm.DB, payment *models.Payment, booking *models.Booking, field *models.Field, amount models.Money, reason string) error {
+	if payment.PaymentMethod == models.PaymentMethodWallet {
+		if err := chargeWallet(tx, payment, amount, reason); err != nil {
+			return err
+		}
+	}
+
+	var seq int64
+	tx.Model(&models.PaymentAdjustment{}).Where("payment_id = ?", payment.ID).Count(&seq)
+
+	adjustment := models.PaymentAdjustment{
+		TenantID:      payment.TenantID,
+		PaymentID:     payment.ID,
+		Type:          models.AdjustmentCharge,
+		Amount:        amount,
+		Status:        models.PaymentCompleted,
+		Reason:        reason,
+		TransactionID: fmt.Sprintf("ADJ-%d-%d-%d", payment.ID, seq+1, time.Now().Unix()),
+	}
+	if err := tx.Create(&adjustment).Error; err != nil {
+		return err
+	}
+	if _, err := issueChargeInvoice(tx, payment, &adjustment, booking, field); err != nil {
+		return err
+	}
+
+	// Follow-up charges are earned in full, like refunds are given back in full
+	j := newJournal(fmt.Sprintf("adjustment:%d", adjustment.ID), fmt.Sprintf("%s charge on payment %s", reason, payment.TransactionID))
+	j.entry.PaymentID = &payment.ID
+	j.post(models.AccountReceivables, models.AccountVenueRevenue, amount).
+		post(settlementAccount(payment.PaymentMethod), models.AccountReceivables, amount)
+	if err := accrueChargeEarning(tx, payment, &adjustment, booking, field, j); err != nil {
+		return err
+	}
+	return postJournal(tx, j)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/qolby/sports-booking-api/internal/config"
 	"github.com/qolby/sports-booking-api/internal/models"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
@@ -16,14 +17,28 @@ func setupBookingTestDB() *gorm.DB {
 		panic("failed to connect to test database")
 	}
 
-	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{})
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.WaitlistEntry{}, &models.Payment{}, &models.PaymentAdjustment{}, &models.Refund{}, &models.PaymentSplit{}, &models.PaymentShare{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
 
 	return db
 }
 
+func bookingTestConfig() *config.Config {
+	return &config.Config{
+		Booking: config.BookingConfig{
+			HoldTTL:              15 * time.Minute,
+			WaitlistClaimWindow:  30 * time.Minute,
+			RescheduleMinNotice:  24 * time.Hour,
+			MaxReschedules:       2,
+			AdvanceWindow:        30 * 24 * time.Hour,
+			SessionRestoreNotice: 24 * time.Hour,
+		},
+		Currency: config.CurrencyConfig{Default: "IDR"},
+	}
+}
+
 func TestBookingService_CreateBooking(t *testing.T) {
 	db := setupBookingTestDB()
-	bookingService := NewBookingService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
 
 	// Create test data
 	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
@@ -32,7 +47,7 @@ func TestBookingService_CreateBooking(t *testing.T) {
 
 	field := models.Field{
 		Name:         "Test Field",
-		PricePerHour: 100000,
+		PricePerHour: models.NewMoney(100000, "IDR"),
 		Location:     "Test Location",
 	}
 	db.Create(&field)
@@ -92,3 +107,681 @@ func TestBookingService_CreateBooking(t *testing.T) {
 		})
 	}
 }
+
+func TestBookingService_CancelBooking(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(24 * time.Hour)
+	endTime := startTime.Add(2 * time.Hour)
+
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: endTime})
+	assert.NoError(t, err)
+
+	_, err = bookingService.CancelBooking(user.ID+1, booking.ID)
+	assert.Error(t, err)
+
+	cancelled, err := bookingService.CancelBooking(user.ID, booking.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCancelled, cancelled.Status)
+
+	_, err = bookingService.CancelBooking(user.ID, booking.ID)
+	assert.Error(t, err)
+
+	// The slot is free again
+	rebooked, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: endTime})
+	assert.NoError(t, err)
+	assert.NotNil(t, rebooked)
+}
+
+func TestBookingService_ExpirePendingBookings(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(24 * time.Hour)
+	stale := models.Booking{UserID: 1, FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour), Status: models.StatusPending, CreatedAt: time.Now().Add(-time.Hour)}
+	db.Create(&stale)
+	fresh := models.Booking{UserID: 1, FieldID: field.ID, StartTime: startTime.Add(2 * time.Hour), EndTime: startTime.Add(3 * time.Hour), Status: models.StatusPending}
+	db.Create(&fresh)
+
+	expired, err := bookingService.ExpirePendingBookings(time.Now().Add(-15 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), expired)
+
+	db.First(&stale, stale.ID)
+	db.First(&fresh, fresh.ID)
+	assert.Equal(t, models.StatusExpired, stale.Status)
+	assert.Equal(t, models.StatusPending, fresh.Status)
+}
+
+func TestBookingService_StatusHistory(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	admin := models.User{Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin}
+	db.Create(&admin)
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	_, err = paymentService.RefundPayment(admin.ID, payment.ID, RefundRequest{Amount: 10000, Reason: "lights out"})
+	assert.NoError(t, err)
+
+	// Nothing completes before the booking ends
+	completed, err := bookingService.CompleteBookings(startTime)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), completed)
+	completed, err = bookingService.CompleteBookings(startTime.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), completed)
+
+	// A completed booking can no longer be cancelled or paid
+	db.First(booking, booking.ID)
+	assert.Equal(t, models.StatusCompleted, booking.Status)
+	assert.ErrorIs(t, transitionBooking(db, booking, models.StatusCancelled, &user.ID, "too late"), ErrInvalidBookingTransition)
+	_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.Error(t, err)
+
+	// A stale copy loses to the change made in the meantime
+	stale := *booking
+	stale.Status = models.StatusPaid
+	assert.ErrorIs(t, transitionBooking(db, &stale, models.StatusNoShow, nil, "stale"), ErrInvalidBookingTransition)
+
+	_, err = paymentService.RefundPayment(admin.ID, payment.ID, RefundRequest{Amount: payment.Amount.Amount - 10000, Reason: "goodwill"})
+	assert.NoError(t, err)
+
+	_, err = bookingService.GetBookingHistory(user.ID+99, false, booking.ID)
+	assert.Error(t, err)
+	events, err := bookingService.GetBookingHistory(admin.ID, true, booking.ID)
+	assert.NoError(t, err)
+	assert.Len(t, events, 5)
+
+	expected := []struct {
+		from, to models.BookingStatus
+		actor    *uint
+		reason   string
+	}{
+		{"", models.StatusPending, &user.ID, "slot held"},
+		{models.StatusPending, models.StatusPaid, &user.ID, "paid"},
+		{models.StatusPaid, models.StatusPartiallyRefunded, &admin.ID, "lights out"},
+		{models.StatusPartiallyRefunded, models.StatusCompleted, nil, "booking ended"},
+		{models.StatusCompleted, models.StatusRefunded, &admin.ID, "goodwill"},
+	}
+	for i, want := range expected {
+		assert.Equal(t, want.from, events[i].FromStatus)
+		assert.Equal(t, want.to, events[i].ToStatus)
+		assert.Equal(t, want.actor, events[i].ActorID)
+		assert.Equal(t, want.reason, events[i].Reason)
+	}
+}
+
+func TestBookingService_RescheduleBooking(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+	premium := models.Field{Name: "Premium Field", PricePerHour: models.NewMoney(200000, "IDR"), Location: "Test Location", OpenTime: "08:00", CloseTime: "22:00"}
+	db.Create(&premium)
+
+	day := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour)
+	startTime := day.Add(10 * time.Hour)
+
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+
+	payment := models.Payment{BookingID: &booking.ID, Amount: booking.TotalPrice, Status: models.PaymentCompleted, PaymentMethod: "credit_card", TransactionID: "TRX-1"}
+	db.Create(&payment)
+	db.Model(booking).Update("status", models.StatusPaid)
+
+	blocker := models.Booking{UserID: user.ID + 1, FieldID: premium.ID, StartTime: day.Add(14 * time.Hour), EndTime: day.Add(15 * time.Hour), Status: models.StatusPaid}
+	db.Create(&blocker)
+
+	tests := []struct {
+		name      string
+		request   RescheduleBookingRequest
+		wantErr   bool
+		wantPrice int64
+		wantAdj   string
+	}{
+		{
+			name:    "Outside operating hours",
+			request: RescheduleBookingRequest{FieldID: premium.ID, StartTime: day.Add(21 * time.Hour), EndTime: day.Add(23 * time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "Conflicting slot",
+			request: RescheduleBookingRequest{FieldID: premium.ID, StartTime: day.Add(14 * time.Hour), EndTime: day.Add(16 * time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:      "Move to pricier field charges the difference",
+			request:   RescheduleBookingRequest{FieldID: premium.ID, StartTime: day.Add(16 * time.Hour), EndTime: day.Add(18 * time.Hour)},
+			wantPrice: 400000,
+			wantAdj:   "charge",
+		},
+		{
+			name:      "Shorter slot refunds the difference",
+			request:   RescheduleBookingRequest{StartTime: day.Add(16 * time.Hour), EndTime: day.Add(17 * time.Hour)},
+			wantPrice: 200000,
+			wantAdj:   "refund",
+		},
+		{
+			name:    "Reschedule limit reached",
+			request: RescheduleBookingRequest{StartTime: day.Add(18 * time.Hour), EndTime: day.Add(19 * time.Hour)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := bookingService.RescheduleBooking(user.ID, booking.ID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.wantPrice, result.TotalPrice.Amount)
+				assert.Equal(t, models.StatusPaid, result.Status)
+				payment := result.Payments[0]
+				if tt.wantAdj == "charge" {
+					assert.Equal(t, models.AdjustmentCharge, payment.Adjustments[len(payment.Adjustments)-1].Type)
+					assert.Equal(t, int64(200000), payment.Adjustments[len(payment.Adjustments)-1].Amount.Amount)
+				} else {
+					assert.Len(t, payment.Refunds, 1)
+					assert.Equal(t, int64(200000), payment.Refunds[0].Amount.Amount)
+					assert.Equal(t, models.PaymentPartiallyRefunded, payment.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestBookingService_RescheduleTooLate(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(2 * time.Hour)
+	booking, err := bookingService.CreateBooking(1, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = bookingService.RescheduleBooking(1, booking.ID, RescheduleBookingRequest{StartTime: startTime.Add(48 * time.Hour), EndTime: startTime.Add(49 * time.Hour)})
+	assert.Error(t, err)
+}
+
+func TestBookingService_BookingRules(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	field := models.Field{
+		Name:         "Test Field",
+		PricePerHour: models.NewMoney(100000, "IDR"),
+		Location:     "Test Location",
+		OpenTime:     "08:30",
+		CloseTime:    "22:30",
+		BookingRules: models.BookingRules{
+			MinDurationMinutes: 60,
+			MaxDurationMinutes: 120,
+			SlotMinutes:        30,
+			BufferMinutes:      15,
+			MinNoticeMinutes:   120,
+			MaxAdvanceDays:     14,
+		},
+	}
+	db.Create(&field)
+
+	day := time.Now().AddDate(0, 0, 2)
+	at := func(hour, minute int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		rules      []string
+	}{
+		{"Aligned slot", at(10, 30), at(11, 30), nil},
+		{"End before start", at(11, 0), at(10, 0), []string{"time_range"}},
+		{"In the past", at(10, 30).AddDate(0, 0, -3), at(11, 30).AddDate(0, 0, -3), []string{"past"}},
+		{"Too short and misaligned", at(19, 13), at(19, 20), []string{"min_duration", "slot_alignment", "slot_granularity"}},
+		{"Too long", at(14, 30), at(17, 30), []string{"max_duration"}},
+		{"Too far ahead", at(10, 30).AddDate(0, 0, 20), at(11, 30).AddDate(0, 0, 20), []string{"max_advance"}},
+		{"Before opening", at(7, 30), at(8, 30), []string{"operating_hours"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bookingService.CreateBooking(1, CreateBookingRequest{FieldID: field.ID, StartTime: tt.start, EndTime: tt.end})
+			if tt.rules == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var ruleErr *BookingRuleError
+			if assert.ErrorAs(t, err, &ruleErr) {
+				var rules []string
+				for _, v := range ruleErr.Violations {
+					rules = append(rules, v.Rule)
+				}
+				assert.Equal(t, tt.rules, rules)
+			}
+		})
+	}
+
+	// Not enough notice
+	soon := time.Now().Add(time.Hour)
+	_, err := bookingService.QuoteBooking(1, CreateBookingRequest{FieldID: field.ID, StartTime: soon, EndTime: soon.Add(time.Hour)})
+	var ruleErr *BookingRuleError
+	if assert.ErrorAs(t, err, &ruleErr) {
+		assert.Equal(t, "min_notice", ruleErr.Violations[0].Rule)
+	}
+
+	// The cleanup buffer after 10:30-11:30 keeps 11:30 free, but not 12:00
+	_, err = bookingService.CreateBooking(1, CreateBookingRequest{FieldID: field.ID, StartTime: at(11, 30), EndTime: at(12, 30)})
+	if assert.ErrorAs(t, err, &ruleErr) {
+		assert.Equal(t, "buffer", ruleErr.Violations[0].Rule)
+	}
+	quote, err := bookingService.QuoteBooking(1, CreateBookingRequest{FieldID: field.ID, StartTime: at(11, 30), EndTime: at(12, 30)})
+	assert.NoError(t, err)
+	assert.False(t, quote.Available)
+	booking, err := bookingService.CreateBooking(1, CreateBookingRequest{FieldID: field.ID, StartTime: at(12, 0), EndTime: at(13, 0)})
+	assert.NoError(t, err)
+
+	// A booking's own buffer does not stop it moving by one slot
+	_, err = bookingService.RescheduleBooking(1, booking.ID, RescheduleBookingRequest{StartTime: at(12, 30), EndTime: at(13, 30)})
+	assert.NoError(t, err)
+}
+
+func TestBookingService_Timezones(t *testing.T) {
+	db := setupBookingTestDB()
+	cfg := bookingTestConfig()
+	cfg.Booking.AdvanceWindow = 0
+	bookingService := NewBookingService(db, cfg)
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+	field := models.Field{
+		Name:         "Test Field",
+		PricePerHour: models.NewMoney(100000, "IDR"),
+		Location:     "Test Location",
+		OpenTime:     "08:00",
+		CloseTime:    "22:00",
+		Timezone:     "Europe/Berlin",
+		BookingRules: models.BookingRules{SlotMinutes: 90},
+	}
+	db.Create(&field)
+
+	// Clocks go forward on 31 March 2030: Berlin is UTC+1 before, UTC+2 after
+	booking, err := bookingService.CreateBooking(1, CreateBookingRequest{FieldID: field.ID, LocalStartTime: "2030-03-31T20:00", LocalEndTime: "2030-03-31T21:30"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 3, 31, 18, 0, 0, 0, time.UTC), booking.StartTime)
+	assert.Equal(t, time.UTC, booking.StartTime.Location())
+	assert.Equal(t, "Europe/Berlin", booking.Timezone)
+	assert.Equal(t, "2030-03-31T20:00:00+02:00", booking.LocalStartTime.Format(time.RFC3339))
+
+	// Closing time is 22:00 local, 20:00 UTC, not midnight plus 22 hours
+	_, err = bookingService.QuoteBooking(1, CreateBookingRequest{FieldID: field.ID, StartTime: time.Date(2030, 3, 31, 19, 30, 0, 0, time.UTC), EndTime: time.Date(2030, 3, 31, 21, 0, 0, 0, time.UTC)})
+	var ruleErr *BookingRuleError
+	if assert.ErrorAs(t, err, &ruleErr) {
+		assert.Equal(t, "operating_hours", ruleErr.Violations[0].Rule)
+	}
+	// The day before, 20:00 to 21:30 local is an hour later in UTC
+	quote, err := bookingService.QuoteBooking(1, CreateBookingRequest{FieldID: field.ID, LocalStartTime: "2030-03-30T20:00", LocalEndTime: "2030-03-30T21:30"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 3, 30, 19, 0, 0, 0, time.UTC), quote.StartTime)
+
+	// 02:30 is skipped that night
+	_, err = bookingService.QuoteBooking(1, CreateBookingRequest{FieldID: field.ID, LocalStartTime: "2030-03-31T02:30", LocalEndTime: "2030-03-31T04:00"})
+	if assert.ErrorAs(t, err, &ruleErr) {
+		assert.Equal(t, "local_time", ruleErr.Violations[0].Rule)
+	}
+
+	// The slot grid follows the wall clock after the change
+	quote, err = bookingService.QuoteBooking(1, CreateBookingRequest{FieldID: field.ID, LocalStartTime: "2030-03-31T09:30", LocalEndTime: "2030-03-31T11:00"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 3, 31, 7, 30, 0, 0, time.UTC), quote.StartTime)
+
+	var stored models.Booking
+	db.Preload("Field").First(&stored, booking.ID)
+	assert.Equal(t, booking.StartTime, stored.StartTime)
+	assert.Equal(t, 20, stored.LocalStartTime.Hour())
+	assert.Equal(t, berlin.String(), stored.LocalStartTime.Location().String())
+}
+
+func TestCalendar_DST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	// 19:00 is 18:00 UTC the day before the clocks go forward and 17:00 UTC on the day
+	assert.Equal(t, time.Date(2030, 3, 30, 18, 0, 0, 0, time.UTC), atClock(time.Date(2030, 3, 30, 12, 0, 0, 0, time.UTC), berlin, 19*time.Hour).UTC())
+	assert.Equal(t, time.Date(2030, 3, 31, 17, 0, 0, 0, time.UTC), atClock(time.Date(2030, 3, 31, 12, 0, 0, 0, time.UTC), berlin, 19*time.Hour).UTC())
+	// 23:30 UTC on 30 March is already 31 March in Berlin
+	assert.Equal(t, time.Date(2030, 3, 31, 6, 0, 0, 0, time.UTC), atClock(time.Date(2030, 3, 30, 23, 30, 0, 0, time.UTC), berlin, 8*time.Hour).UTC())
+
+	// The week with the change is an hour short
+	week := weekOf(time.Date(2030, 3, 30, 12, 0, 0, 0, time.UTC), berlin)
+	assert.Equal(t, time.Date(2030, 3, 24, 23, 0, 0, 0, time.UTC), week.UTC())
+	assert.Equal(t, 167*time.Hour, week.AddDate(0, 0, 7).Sub(week))
+	assert.Equal(t, time.Monday, weekOf(time.Date(2030, 3, 31, 23, 30, 0, 0, time.UTC), berlin).Weekday())
+
+	// 02:30 does not exist when the clocks go forward, and happens twice when
+	// they go back, where the first is taken
+	_, err = parseLocalTime("2030-03-31T02:30", berlin)
+	assert.Error(t, err)
+	first, err := parseLocalTime("2030-10-27T02:30", berlin)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 10, 27, 0, 30, 0, 0, time.UTC), first)
+	_, err = parseLocalTime("next tuesday", berlin)
+	assert.Error(t, err)
+}
+
+func TestBookingService_CreateBookingForCustomer(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	admin := models.User{Email: "admin@example.com", Name: "Front Desk", Role: models.RoleAdmin}
+	db.Create(&admin)
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	slot := func(hours int) CreateBookingRequest {
+		at := start.Add(time.Duration(hours) * time.Hour)
+		return CreateBookingRequest{FieldID: field.ID, StartTime: at, EndTime: at.Add(time.Hour)}
+	}
+
+	// On behalf of a player, left for them to pay
+	booking, err := bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(0), UserID: user.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, booking.UserID)
+	assert.Equal(t, &admin.ID, booking.CreatedByID)
+	assert.Equal(t, models.StatusPending, booking.Status)
+
+	var event models.BookingEvent
+	db.Where("booking_id = ?", booking.ID).First(&event)
+	assert.Equal(t, &admin.ID, event.ActorID)
+
+	// A walk-in guest paying cash
+	guest, err := bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(1), GuestName: "Walk In", GuestPhone: "+62 812 0000", PaidInCash: true})
+	assert.NoError(t, err)
+	assert.Equal(t, admin.ID, guest.UserID)
+	assert.Equal(t, "Walk In", guest.GuestName)
+	assert.Equal(t, models.StatusPaid, guest.Status)
+	if assert.Len(t, guest.Payments, 1) {
+		payment := guest.Payments[0]
+		assert.Equal(t, models.PaymentMethodCash, payment.PaymentMethod)
+		assert.Equal(t, &admin.ID, payment.CashierID)
+		assert.Nil(t, payment.UserID)
+
+		var invoice models.Invoice
+		db.Where("payment_id = ?", payment.ID).First(&invoice)
+		assert.Equal(t, "Walk In", invoice.BillToName)
+	}
+	assert.Equal(t, guest.TotalPrice.Amount, accountBalance(db, models.AccountCash, "IDR"))
+
+	// A guest gets none of the admin's discounts or bans, and the booking is
+	// not among the admin's own
+	plan := models.MembershipPlan{Name: "Staff", Type: models.PlanMembership, Price: models.NewMoney(0, "IDR"), DurationDays: 30, DiscountPercent: 50}
+	db.Create(&plan)
+	db.Create(&models.Subscription{UserID: admin.ID, PlanID: plan.ID, Status: models.SubscriptionActive, StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().AddDate(0, 0, 30)})
+	bannedUntil := time.Now().Add(time.Hour)
+	db.Model(&admin).Update("booking_banned_until", &bannedUntil)
+	guest, err = bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(3), GuestName: "Phone Guest", GuestPhone: "+62 812 1111"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100000), guest.TotalPrice.Amount)
+	assert.Nil(t, guest.SubscriptionID)
+	own, _, err := bookingService.GetUserBookings(admin.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, own)
+
+	_, err = bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(2)})
+	assert.Error(t, err)
+	_, err = bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(2), UserID: user.ID, GuestName: "Walk In", GuestPhone: "+62 812 0000"})
+	assert.Error(t, err)
+	_, err = bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(2), GuestName: "Walk In"})
+	assert.Error(t, err)
+	promo := slot(2)
+	promo.PromoCode = "WELCOME"
+	_, err = bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: promo, GuestName: "Walk In", GuestPhone: "+62 812 0000"})
+	assert.Error(t, err, "a guest has no account to redeem the code on")
+	_, err = bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(2), UserID: 999})
+	assert.Error(t, err)
+	_, err = bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{CreateBookingRequest: slot(1), UserID: user.ID})
+	assert.ErrorIs(t, err, ErrSlotTaken)
+}
+
+func TestBookingService_ListBookings(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+
+	admin := models.User{Email: "admin@example.com", Name: "Front Desk", Role: models.RoleAdmin}
+	db.Create(&admin)
+	user := models.User{Email: "alice@example.com", Name: "Alice", Role: models.RoleUser}
+	db.Create(&user)
+	court := models.Field{Name: "Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&court)
+	pitch := models.Field{Name: "Pitch", PricePerHour: models.NewMoney(200000, "IDR"), Location: "Test Location"}
+	db.Create(&pitch)
+
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	book := func(field models.Field, hours int) *models.Booking {
+		at := start.Add(time.Duration(hours) * time.Hour)
+		booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: at, EndTime: at.Add(time.Hour)})
+		assert.NoError(t, err)
+		return booking
+	}
+	first := book(court, 0)
+	book(court, 1)
+	paid := book(pitch, 0)
+	_, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: paid.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	guest, err := bookingService.CreateBookingForCustomer(admin.ID, CustomerBookingRequest{
+		CreateBookingRequest: CreateBookingRequest{FieldID: pitch.ID, StartTime: start.Add(24 * time.Hour), EndTime: start.Add(25 * time.Hour)},
+		GuestName:            "Bob Guest", GuestPhone: "0812345",
+	})
+	assert.NoError(t, err)
+
+	bookings, page, err := bookingService.ListBookings(AdminBookingFilter{}, ListQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), *page.Total)
+	assert.Equal(t, guest.ID, bookings[0].ID)
+
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{FieldID: court.ID}, ListQuery{Sort: "start_time", PerPage: 1, Page: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *page.Total)
+	if assert.Len(t, bookings, 1) {
+		assert.NotEqual(t, first.ID, bookings[0].ID)
+	}
+
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{PaymentStatus: string(models.PaymentCompleted)}, ListQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, bookings, 1) {
+		assert.Equal(t, paid.ID, bookings[0].ID)
+	}
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{PaymentStatus: PaymentUnpaid}, ListQuery{Status: "pending,paid"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), *page.Total)
+
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{Search: "bob"}, ListQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *page.Total)
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{Search: "ALICE@"}, ListQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), *page.Total)
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{Search: "%"}, ListQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), *page.Total, "wildcards are searched for literally")
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{Search: "b_b"}, ListQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), *page.Total)
+
+	// Dates take in the whole day
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{}, ListQuery{From: start.Add(12 * time.Hour).Format(time.RFC3339)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *page.Total)
+	day := guest.StartTime.Format("2006-01-02")
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{UserID: admin.ID}, ListQuery{From: day, To: day})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *page.Total)
+	bookings, page, err = bookingService.ListBookings(AdminBookingFilter{}, ListQuery{To: time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02")})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), *page.Total)
+
+	_, _, err = bookingService.ListBookings(AdminBookingFilter{}, ListQuery{Sort: "password"})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+	_, _, err = bookingService.ListBookings(AdminBookingFilter{PaymentStatus: "maybe"}, ListQuery{})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+	_, _, err = bookingService.ListBookings(AdminBookingFilter{}, ListQuery{From: "yesterday"})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+}
+
+func TestListQuery(t *testing.T) {
+	db := setupBookingTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+	fieldService := NewFieldService(db, bookingTestConfig())
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		at := start.Add(time.Duration(i) * time.Hour)
+		booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: at, EndTime: at.Add(time.Hour)})
+		assert.NoError(t, err)
+		ids = append(ids, booking.ID)
+	}
+	_, err := bookingService.CancelBooking(user.ID, ids[4])
+	assert.NoError(t, err)
+	past := models.Booking{UserID: user.ID, FieldID: field.ID, StartTime: start.Add(-72 * time.Hour), EndTime: start.Add(-71 * time.Hour), TotalPrice: models.NewMoney(100000, "IDR"), Status: models.StatusCompleted}
+	db.Create(&past)
+
+	// Offset pages are counted
+	bookings, page, err := bookingService.GetUserBookings(user.ID, ListQuery{PerPage: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{ids[4], ids[3], ids[2], ids[1]}, bookingIDs(bookings))
+	assert.Equal(t, 1, page.Page)
+	assert.Equal(t, int64(6), *page.Total)
+	assert.Equal(t, 2, page.TotalPages)
+	assert.True(t, page.HasMore)
+	bookings, page, err = bookingService.GetUserBookings(user.ID, ListQuery{PerPage: 4, Page: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], past.ID}, bookingIDs(bookings))
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.NextCursor)
+
+	// Cursors walk the list, ties broken by ID, without counting it
+	var walked []uint
+	q := ListQuery{PerPage: 4, Sort: "-total_price"}
+	for {
+		bookings, page, err = bookingService.GetUserBookings(user.ID, q)
+		assert.NoError(t, err)
+		walked = append(walked, bookingIDs(bookings)...)
+		if q.Cursor != "" {
+			assert.Nil(t, page.Total)
+		}
+		if !page.HasMore {
+			break
+		}
+		q.Cursor = page.NextCursor
+	}
+	assert.ElementsMatch(t, append(ids, past.ID), walked)
+	assert.Len(t, walked, 6)
+
+	// Filters
+	bookings, _, err = bookingService.GetUserBookings(user.ID, ListQuery{Status: "cancelled, completed", Sort: "start_time"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{past.ID, ids[4]}, bookingIDs(bookings))
+	bookings, _, err = bookingService.GetUserBookings(user.ID, ListQuery{Upcoming: true})
+	assert.NoError(t, err)
+	assert.Len(t, bookings, 5)
+	bookings, _, err = bookingService.GetUserBookings(user.ID, ListQuery{From: start.Add(2 * time.Hour).Format(time.RFC3339), To: start.Add(3 * time.Hour).Format(time.RFC3339), Timezone: "Asia/Jakarta"})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{ids[2]}, bookingIDs(bookings))
+
+	// Pages hold at most maxPerPage, and an empty page is still a list
+	_, page, err = bookingService.GetUserBookings(user.ID, ListQuery{PerPage: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, maxPerPage, page.PerPage)
+	bookings, _, err = bookingService.GetUserBookings(user.ID+1, ListQuery{})
+	assert.NoError(t, err)
+	assert.NotNil(t, bookings)
+
+	for _, q := range []ListQuery{
+		{Sort: "user_id"},
+		{Cursor: "not a cursor"},
+		{Cursor: q.Cursor, Sort: "created_at"},
+		{From: "2030-01-01", Timezone: "Mars/Olympus"},
+	} {
+		_, _, err = bookingService.GetUserBookings(user.ID, q)
+		assert.ErrorIs(t, err, ErrInvalidListQuery, "%+v", q)
+	}
+	_, _, err = fieldService.GetAllFields(ListQuery{Status: "open"})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+}
+
+func bookingIDs(bookings []models.Booking) []uint {
+	ids := make([]uint, len(bookings))
+	for i, booking := range bookings {
+		ids[i] = booking.ID
+	}
+	return ids
+}
+
+func TestValidateBookingRules(t *testing.T) {
+	assert.NoError(t, validateBookingRules(models.BookingRules{}))
+	assert.NoError(t, validateBookingRules(models.BookingRules{MinDurationMinutes: 60, MaxDurationMinutes: 180, SlotMinutes: 30}))
+	assert.Error(t, validateBookingRules(models.BookingRules{BufferMinutes: -5}))
+	assert.Error(t, validateBookingRules(models.BookingRules{MinDurationMinutes: 120, MaxDurationMinutes: 60}))
+	assert.Error(t, validateBookingRules(models.BookingRules{MinDurationMinutes: 45, SlotMinutes: 30}))
+}
+
+func TestCalculatePrice(t *testing.T) {
+	start := time.Date(2030, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		price    models.Money
+		duration time.Duration
+		want     models.Money
+	}{
+		{name: "Whole hours", price: models.NewMoney(100000, "IDR"), duration: 2 * time.Hour, want: models.NewMoney(200000, "IDR")},
+		{name: "Half hour", price: models.NewMoney(99999, "IDR"), duration: 90 * time.Minute, want: models.NewMoney(149999, "IDR")},
+		{name: "Rounds half up", price: models.NewMoney(1999, "USD"), duration: 30 * time.Minute, want: models.NewMoney(1000, "USD")},
+		{name: "Odd minutes", price: models.NewMoney(60000, "IDR"), duration: 50 * time.Minute, want: models.NewMoney(50000, "IDR")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field := models.Field{PricePerHour: tt.price}
+			assert.Equal(t, tt.want, calculatePrice(field, start, start.Add(tt.duration)))
+		})
+	}
+}
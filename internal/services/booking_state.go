@@ -0,0 +1,93 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidBookingTransition = errors.New("invalid booking status change")
+
+// recordBookingCreated starts the history of a new booking.
+func recordBookingCreated(tx *gorm.DB, booking *models.Booking, actorID *uint, reason string) error {
+	return tx.Create(&models.BookingEvent{
+		TenantID:  booking.TenantID,
+		BookingID: booking.ID,
+		ToStatus:  booking.Status,
+		ActorID:   actorID,
+		Reason:    reason,
+	}).Error
+}
+
+// transitionBooking is the only way a booking's status changes. It rejects
+// moves the state machine does not allow, and moves that lose a race with
+// another change, and records the move in the booking's history.
+func transitionBooking(tx *gorm.DB, booking *models.Booking, to models.BookingStatus, actorID *uint, reason string) error {
+	from := booking.Status
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: booking %d cannot go from %s to %s", ErrInvalidBookingTransition, booking.ID, from, to)
+	}
+
+	result := tx.Model(&models.Booking{}).Where("id = ? AND status = ?", booking.ID, from).Update("status", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: booking %d is no longer %s", ErrInvalidBookingTransition, booking.ID, from)
+	}
+	booking.Status = to
+
+	return tx.Create(&models.BookingEvent{
+		TenantID:   booking.TenantID,
+		BookingID:  booking.ID,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    actorID,
+		Reason:     reason,
+	}).Error
+}
+
+// transitionBookings moves every booking matching the conditions that may
+// take the new status, and returns how many did.
+func transitionBookings(tx *gorm.DB, to models.BookingStatus, actorID *uint, reason string, query interface{}, args ...interface{}) (int64, error) {
+	var bookings []models.Booking
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...).Order("id ASC").Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for i := range bookings {
+		if !bookings[i].Status.CanTransitionTo(to) {
+			continue
+		}
+		if err := transitionBooking(tx, &bookings[i], to, actorID, reason); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// GetBookingHistory lists a booking's status changes, oldest first, to the
+// player who made it or to admins.
+func (s *BookingService) GetBookingHistory(userID uint, isAdmin bool, id uint) ([]models.BookingEvent, error) {
+	var booking models.Booking
+	if err := s.db.First(&booking, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("booking not found")
+		}
+		return nil, err
+	}
+	if booking.UserID != userID && !isAdmin {
+		return nil, errors.New("booking does not belong to user")
+	}
+
+	var events []models.BookingEvent
+	if err := s.db.Where("booking_id = ?", booking.ID).Order("created_at ASC, id ASC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// atClock returns the instant a wall clock time, as parsed by parseClock,
+// falls on, on the day t falls on in loc. Days with a DST change are not 24
+// hours long, so the clock is set on the calendar rather than added to
+// midnight.
+func atClock(t time.Time, loc *time.Location, clock time.Duration) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, loc)
+}
+
+// weekOf returns midnight on the Monday of the week t falls in, in loc.
+func weekOf(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day()-(int(local.Weekday())+6)%7, 0, 0, 0, 0, loc)
+}
+
+// parseLocalTime reads a wall clock date and time such as 2026-03-29T19:00
+// in loc. Times skipped when the clocks go forward do not exist and are
+// rejected; times repeated when the clocks go back are read as the first.
+func parseLocalTime(value string, loc *time.Location) (time.Time, error) {
+	const layout = "2006-01-02T15:04"
+	t, err := time.ParseInLocation(layout, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a local time like %s", value, layout)
+	}
+	if t.Format(layout) != value {
+		return time.Time{}, fmt.Errorf("%s does not exist in %s, the clocks go forward", value, loc)
+	}
+	for _, shift := range []time.Duration{time.Hour, 30 * time.Minute} {
+		if earlier := t.Add(-shift); earlier.Format(layout) == value {
+			return earlier.UTC(), nil
+		}
+	}
+	return t.UTC(), nil
+}
+
+// parseDateBound reads one end of a date range, given as an RFC 3339 time or
+// as a date (2006-01-02) in loc. A date is its midnight, or with end set, the
+// midnight after it, so that the range takes in the whole day.
+func parseDateBound(value string, loc *time.Location, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date like 2006-01-02 or an RFC 3339 time", value)
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t.UTC(), nil
+}
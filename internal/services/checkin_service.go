@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// checkInCodePrefix versions the payload of booking QR codes
+const checkInCodePrefix = "SFB1"
+
+var ErrInvalidCheckInCode = errors.New("invalid check-in code")
+
+type CheckInService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewCheckInService(db *gorm.DB, cfg *config.Config) *CheckInService {
+	return &CheckInService{db: db, cfg: cfg}
+}
+
+func (s *CheckInService) WithContext(ctx context.Context) *CheckInService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type CheckInRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// CheckInCode is the signed payload of a booking's QR code, as
+// SFB1.<booking ID>.<signature>.
+func (s *CheckInService) CheckInCode(bookingID uint) string {
+	payload := fmt.Sprintf("%s.%d", checkInCodePrefix, bookingID)
+	return payload + "." + s.sign(payload)
+}
+
+func (s *CheckInService) sign(payload string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.CheckIn.Secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseCheckInCode returns the booking a check-in code was signed for.
+func (s *CheckInService) parseCheckInCode(code string) (uint, error) {
+	parts := strings.Split(strings.TrimSpace(code), ".")
+	if len(parts) != 3 || parts[0] != checkInCodePrefix {
+		return 0, ErrInvalidCheckInCode
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return 0, ErrInvalidCheckInCode
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return 0, ErrInvalidCheckInCode
+	}
+	return uint(id), nil
+}
+
+// BookingQR renders the check-in code of a paid booking as a PNG or SVG QR
+// code, for the player who made it or for admins. It returns the image and
+// its content type.
+func (s *CheckInService) BookingQR(userID uint, isAdmin bool, id uint, format string) ([]byte, string, error) {
+	var booking models.Booking
+	if err := s.db.First(&booking, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", errors.New("booking not found")
+		}
+		return nil, "", err
+	}
+	if booking.UserID != userID && !isAdmin {
+		return nil, "", errors.New("booking does not belong to user")
+	}
+	if !booking.Status.CanTransitionTo(models.StatusCheckedIn) {
+		return nil, "", fmt.Errorf("booking is %s and cannot be checked in", booking.Status)
+	}
+
+	qr, err := qrcode.New(s.CheckInCode(booking.ID), qrcode.Medium)
+	if err != nil {
+		return nil, "", err
+	}
+	switch format {
+	case "png":
+		image, err := qr.PNG(256)
+		return image, "image/png", err
+	case "svg":
+		return renderQRSVG(qr.Bitmap()), "image/svg+xml", nil
+	default:
+		return nil, "", errors.New("format must be png or svg")
+	}
+}
+
+// renderQRSVG draws a QR code bitmap, quiet zone included, one unit per
+// module.
+func renderQRSVG(bitmap [][]bool) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %[1]d %[1]d" shape-rendering="crispEdges">`, len(bitmap))
+	fmt.Fprintf(&buf, `<rect width="%[1]d" height="%[1]d" fill="#fff"/><path fill="#000" d="`, len(bitmap))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
+
+// CheckIn admits the player of the booking a QR code was signed for. Check-in
+// opens CHECKIN_OPENS_BEFORE the start and closes when the booking would
+// become a no-show, or at its end if no-shows are not tracked.
+func (s *CheckInService) CheckIn(staffID uint, req CheckInRequest) (*models.Booking, error) {
+	id, err := s.parseCheckInCode(req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	var booking models.Booking
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("booking not found")
+			}
+			return err
+		}
+		if booking.Status == models.StatusCheckedIn {
+			return errors.New("booking is already checked in")
+		}
+		if !booking.Status.CanTransitionTo(models.StatusCheckedIn) {
+			return fmt.Errorf("booking is %s", booking.Status)
+		}
+
+		now := time.Now()
+		opens := booking.StartTime.Add(-s.cfg.CheckIn.OpensBefore)
+		closes := booking.EndTime
+		if s.cfg.CheckIn.NoShowGrace > 0 {
+			closes = booking.StartTime.Add(s.cfg.CheckIn.NoShowGrace)
+		}
+		if now.Before(opens) {
+			return fmt.Errorf("check-in opens at %s", opens.UTC().Format(time.RFC3339))
+		}
+		if !now.Before(closes) {
+			return fmt.Errorf("check-in closed at %s", closes.UTC().Format(time.RFC3339))
+		}
+
+		return transitionBooking(tx, &booking, models.StatusCheckedIn, &staffID, "checked in")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.db.Preload("Field").Preload("User").First(&booking, booking.ID)
+	return &booking, nil
+}
+
+// MarkNoShows marks paid bookings nobody checked in to within the grace
+// period as no-shows, suspends players who keep not showing up, and returns
+// how many bookings were marked.
+func (s *CheckInService) MarkNoShows(now time.Time) (int, error) {
+	if s.cfg.CheckIn.NoShowGrace == 0 {
+		return 0, nil
+	}
+
+	var bookings []models.Booking
+	if err := s.db.Where("status IN ? AND start_time <= ?",
+		[]models.BookingStatus{models.StatusPaid, models.StatusPartiallyRefunded}, now.Add(-s.cfg.CheckIn.NoShowGrace)).
+		Order("start_time ASC").Find(&bookings).Error; err != nil {
+		return 0, err
+	}
+
+	marked := 0
+	for i := range bookings {
+		booking := &bookings[i]
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := transitionBooking(tx, booking, models.StatusNoShow, nil, "not checked in"); err != nil {
+				return err
+			}
+			// A guest has no account to suspend or notify
+			if booking.GuestName != "" {
+				return nil
+			}
+
+			message := fmt.Sprintf("You did not check in to booking %d at %s", booking.ID, booking.StartTime.In(models.LoadLocation(booking.Timezone)).Format(time.RFC3339))
+			bannedUntil, err := s.banRepeatOffender(tx, booking.UserID, now)
+			if err != nil {
+				return err
+			}
+			if bannedUntil != nil {
+				message += fmt.Sprintf(". After repeated no-shows you cannot book until %s", bannedUntil.UTC().Format(time.RFC3339))
+			}
+
+			key := fmt.Sprintf("no-show:%d", booking.ID)
+			return notify(tx, &models.Notification{
+				TenantID:  booking.TenantID,
+				UserID:    booking.UserID,
+				Type:      models.NotificationNoShow,
+				Message:   message,
+				FieldID:   &booking.FieldID,
+				BookingID: &booking.ID,
+				DedupeKey: &key,
+			})
+		})
+		if errors.Is(err, ErrInvalidBookingTransition) {
+			// Checked in or cancelled since it was listed
+			continue
+		}
+		if err != nil {
+			return marked, err
+		}
+		marked++
+	}
+
+	return marked, nil
+}
+
+// banRepeatOffender suspends booking for a player who reached the no-show
+// threshold within the ban window, and returns until when.
+func (s *CheckInService) banRepeatOffender(tx *gorm.DB, userID uint, now time.Time) (*time.Time, error) {
+	if s.cfg.CheckIn.BanThreshold == 0 {
+		return nil, nil
+	}
+
+	var noShows int64
+	if err := tx.Model(&models.Booking{}).
+		Where("user_id = ? AND status = ? AND start_time > ?", userID, models.StatusNoShow, now.Add(-s.cfg.CheckIn.BanWindow)).Where(ownBooking).
+		Count(&noShows).Error; err != nil {
+		return nil, err
+	}
+	if noShows < int64(s.cfg.CheckIn.BanThreshold) {
+		return nil, nil
+	}
+
+	until := now.Add(s.cfg.CheckIn.BanDuration)
+	if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("booking_banned_until", until).Error; err != nil {
+		return nil, err
+	}
+	return &until, nil
+}
+
+// checkBookingBan keeps players suspended for no-shows from booking.
+func checkBookingBan(tx *gorm.DB, userID uint) error {
+	var user models.User
+	if err := tx.Select("id", "booking_banned_until").Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return err
+	}
+	if user.BookingBannedUntil != nil && user.BookingBannedUntil.After(time.Now()) {
+		return fmt.Errorf("booking is suspended until %s after repeated no-shows", user.BookingBannedUntil.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
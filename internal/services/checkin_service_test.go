@@ -0,0 +1,187 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupCheckInTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Notification{}, &models.WaitlistEntry{}, &models.Payment{}, &models.PaymentAdjustment{}, &models.Refund{}, &models.PaymentSplit{}, &models.PaymentShare{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func checkInTestConfig() *config.Config {
+	cfg := bookingTestConfig()
+	cfg.CheckIn = config.CheckInConfig{
+		Secret:       "test-secret",
+		OpensBefore:  30 * time.Minute,
+		NoShowGrace:  15 * time.Minute,
+		BanThreshold: 2,
+		BanWindow:    90 * 24 * time.Hour,
+		BanDuration:  14 * 24 * time.Hour,
+	}
+	return cfg
+}
+
+func TestCheckInService_Code(t *testing.T) {
+	checkInService := NewCheckInService(nil, checkInTestConfig())
+
+	code := checkInService.CheckInCode(42)
+	assert.True(t, strings.HasPrefix(code, "SFB1.42."))
+	id, err := checkInService.parseCheckInCode(code)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(42), id)
+
+	// A code edited to point at another booking fails the signature
+	_, err = checkInService.parseCheckInCode(strings.Replace(code, ".42.", ".43.", 1))
+	assert.ErrorIs(t, err, ErrInvalidCheckInCode)
+
+	// As does one signed with another secret
+	other := checkInTestConfig()
+	other.CheckIn.Secret = "other-secret"
+	_, err = checkInService.parseCheckInCode(NewCheckInService(nil, other).CheckInCode(42))
+	assert.ErrorIs(t, err, ErrInvalidCheckInCode)
+
+	_, err = checkInService.parseCheckInCode("not a code")
+	assert.ErrorIs(t, err, ErrInvalidCheckInCode)
+}
+
+func TestCheckInService_CheckIn(t *testing.T) {
+	db := setupCheckInTestDB()
+	cfg := checkInTestConfig()
+	checkInService := NewCheckInService(db, cfg)
+	bookingService := NewBookingService(db, cfg)
+	paymentService := NewPaymentService(db)
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	staff := models.User{Email: "staff@example.com", Name: "Front Desk", Role: models.RoleStaff}
+	db.Create(&staff)
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	soon := time.Now().Add(10 * time.Minute)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: soon, EndTime: soon.Add(time.Hour)})
+	assert.NoError(t, err)
+	later := time.Now().Add(24 * time.Hour)
+	laterBooking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: later, EndTime: later.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	// No QR code until the booking is paid
+	_, _, err = checkInService.BookingQR(user.ID, false, booking.ID, "png")
+	assert.Error(t, err)
+
+	for _, b := range []*models.Booking{booking, laterBooking} {
+		_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: b.ID, PaymentMethod: "credit_card"})
+		assert.NoError(t, err)
+	}
+
+	png, contentType, err := checkInService.BookingQR(user.ID, false, booking.ID, "png")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, "\x89PNG", string(png[:4]))
+	svg, contentType, err := checkInService.BookingQR(user.ID, false, booking.ID, "svg")
+	assert.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", contentType)
+	assert.True(t, strings.HasPrefix(string(svg), "<svg"))
+	_, _, err = checkInService.BookingQR(user.ID, false, booking.ID, "gif")
+	assert.Error(t, err)
+	_, _, err = checkInService.BookingQR(staff.ID, false, booking.ID, "png")
+	assert.Error(t, err)
+
+	// Check-in for tomorrow's booking has not opened yet
+	_, err = checkInService.CheckIn(staff.ID, CheckInRequest{Code: checkInService.CheckInCode(laterBooking.ID)})
+	assert.Error(t, err)
+
+	checkedIn, err := checkInService.CheckIn(staff.ID, CheckInRequest{Code: checkInService.CheckInCode(booking.ID)})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusCheckedIn, checkedIn.Status)
+	_, err = checkInService.CheckIn(staff.ID, CheckInRequest{Code: checkInService.CheckInCode(booking.ID)})
+	assert.Error(t, err)
+
+	var event models.BookingEvent
+	db.Where("booking_id = ? AND to_status = ?", booking.ID, models.StatusCheckedIn).First(&event)
+	assert.Equal(t, &staff.ID, event.ActorID)
+
+	// Checked-in bookings complete once they end, and are never no-shows
+	marked, err := checkInService.MarkNoShows(soon.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, marked)
+	completed, err := bookingService.CompleteBookings(soon.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), completed)
+	db.First(booking, booking.ID)
+	assert.Equal(t, models.StatusCompleted, booking.Status)
+}
+
+func TestCheckInService_MarkNoShows(t *testing.T) {
+	db := setupCheckInTestDB()
+	cfg := checkInTestConfig()
+	checkInService := NewCheckInService(db, cfg)
+	bookingService := NewBookingService(db, cfg)
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	now := time.Now()
+	first := models.Booking{UserID: user.ID, FieldID: field.ID, StartTime: now.Add(-3 * time.Hour), EndTime: now.Add(-2 * time.Hour), Status: models.StatusPaid}
+	db.Create(&first)
+	second := models.Booking{UserID: user.ID, FieldID: field.ID, StartTime: now.Add(-20 * time.Minute), EndTime: now.Add(40 * time.Minute), Status: models.StatusPaid}
+	db.Create(&second)
+
+	// The second booking is still within its grace period
+	marked, err := checkInService.MarkNoShows(now.Add(-10 * time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	var bannedUser models.User
+	db.First(&bannedUser, user.ID)
+	assert.Nil(t, bannedUser.BookingBannedUntil)
+
+	start := now.Add(24 * time.Hour)
+	_, err = bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: start, EndTime: start.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	// The second no-show within the window suspends booking
+	marked, err = checkInService.MarkNoShows(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, marked)
+	marked, err = checkInService.MarkNoShows(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, marked)
+
+	db.First(&second, second.ID)
+	assert.Equal(t, models.StatusNoShow, second.Status)
+	db.First(&bannedUser, user.ID)
+	if assert.NotNil(t, bannedUser.BookingBannedUntil) {
+		assert.WithinDuration(t, now.Add(cfg.CheckIn.BanDuration), *bannedUser.BookingBannedUntil, time.Second)
+	}
+
+	var notifications []models.Notification
+	db.Where("user_id = ? AND type = ?", user.ID, models.NotificationNoShow).Order("id ASC").Find(&notifications)
+	assert.Len(t, notifications, 2)
+	assert.NotContains(t, notifications[0].Message, "cannot book")
+	assert.Contains(t, notifications[1].Message, "cannot book")
+
+	start = start.Add(2 * time.Hour)
+	_, err = bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: start, EndTime: start.Add(time.Hour)})
+	assert.Error(t, err)
+
+	// No-shows can still be refunded by an admin
+	assert.True(t, second.Status.CanTransitionTo(models.StatusRefunded))
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FavoriteService struct {
+	db *gorm.DB
+}
+
+func NewFavoriteService(db *gorm.DB) *FavoriteService {
+	return &FavoriteService{db: db}
+}
+
+func (s *FavoriteService) WithContext(ctx context.Context) *FavoriteService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+func (s *FavoriteService) AddFavorite(userID, fieldID uint) (*models.FavoriteField, error) {
+	var field models.Field
+	if err := s.db.First(&field, fieldID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("field not found")
+		}
+		return nil, err
+	}
+
+	favorite := models.FavoriteField{UserID: userID, FieldID: fieldID}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite).Error; err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Preload("Field").Where("user_id = ? AND field_id = ?", userID, fieldID).First(&favorite).Error; err != nil {
+		return nil, err
+	}
+
+	return &favorite, nil
+}
+
+func (s *FavoriteService) RemoveFavorite(userID, fieldID uint) error {
+	result := s.db.Where("user_id = ? AND field_id = ?", userID, fieldID).Delete(&models.FavoriteField{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("favorite not found")
+	}
+	return nil
+}
+
+var favoriteListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at"},
+	defaultSort: "-created_at",
+}
+
+func (s *FavoriteService) GetFavorites(userID uint, q ListQuery) ([]models.FavoriteField, *utils.Pagination, error) {
+	var favorites []models.FavoriteField
+	page, err := q.list(s.db.Preload("Field").Where("user_id = ?", userID), favoriteListSpec, &favorites)
+	if err != nil {
+		return nil, nil, err
+	}
+	return favorites, page, nil
+}
@@ -1,52 +1,157 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/qolby/sports-booking-api/internal/config"
 	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
 	"gorm.io/gorm"
 )
 
 type FieldService struct {
-	db *gorm.DB
+	db  *gorm.DB
+	cfg *config.Config
 }
 
-func NewFieldService(db *gorm.DB) *FieldService {
-	return &FieldService{db: db}
+func NewFieldService(db *gorm.DB, cfg *config.Config) *FieldService {
+	return &FieldService{db: db, cfg: cfg}
 }
 
+func (s *FieldService) WithContext(ctx context.Context) *FieldService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// CreateFieldRequest may hand the field to a venue owner with OwnerID, who
+// is paid out its revenue less CommissionRate basis points (the platform
+// default when omitted). BookingRules default to none, and Timezone to
+// DEFAULT_TIMEZONE.
 type CreateFieldRequest struct {
-	Name         string `json:"name" validate:"required"`
-	PricePerHour int    `json:"price_per_hour" validate:"required,gt=0"`
-	Location     string `json:"location" validate:"required"`
+	Name           string                `json:"name" validate:"required"`
+	PricePerHour   int64                 `json:"price_per_hour" validate:"required,gt=0"`
+	Currency       string                `json:"currency"`
+	Location       string                `json:"location" validate:"required"`
+	Sport          string                `json:"sport"`
+	OpenTime       string                `json:"open_time"`
+	CloseTime      string                `json:"close_time"`
+	Timezone       string                `json:"timezone"`
+	TaxRateID      *uint                 `json:"tax_rate_id"`
+	OwnerID        *uint                 `json:"owner_id"`
+	CommissionRate *int                  `json:"commission_rate"`
+	PayoutSchedule models.PayoutSchedule `json:"payout_schedule"`
+	BookingRules   *models.BookingRules  `json:"booking_rules"`
 }
 
+// UpdateFieldRequest changes only what is given. An owner_id of 0 gives the
+// field back to the platform, and booking_rules replace all of the field's
+// rules.
 type UpdateFieldRequest struct {
-	Name         string `json:"name"`
-	PricePerHour int    `json:"price_per_hour"`
-	Location     string `json:"location"`
+	Name           string                `json:"name"`
+	PricePerHour   int64                 `json:"price_per_hour"`
+	Currency       string                `json:"currency"`
+	Location       string                `json:"location"`
+	Sport          string                `json:"sport"`
+	OpenTime       string                `json:"open_time"`
+	CloseTime      string                `json:"close_time"`
+	Timezone       string                `json:"timezone"`
+	TaxRateID      *uint                 `json:"tax_rate_id"`
+	OwnerID        *uint                 `json:"owner_id"`
+	CommissionRate *int                  `json:"commission_rate"`
+	PayoutSchedule models.PayoutSchedule `json:"payout_schedule"`
+	BookingRules   *models.BookingRules  `json:"booking_rules"`
 }
 
 func (s *FieldService) CreateField(req CreateFieldRequest) (*models.Field, error) {
+	if err := validateOperatingHours(req.OpenTime, req.CloseTime); err != nil {
+		return nil, err
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = s.cfg.Booking.DefaultTimezone
+	}
+	if err := validateTimezone(timezone); err != nil {
+		return nil, err
+	}
+	if err := s.checkTaxRate(req.TaxRateID); err != nil {
+		return nil, err
+	}
+	currency, err := currencyOrDefault(s.cfg, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	commissionRate := s.cfg.Payout.CommissionRate
+	if req.CommissionRate != nil {
+		commissionRate = *req.CommissionRate
+	}
+	if err := validateCommission(commissionRate, req.PayoutSchedule); err != nil {
+		return nil, err
+	}
+	schedule := req.PayoutSchedule
+	if schedule == "" {
+		schedule = models.PayoutWeekly
+	}
+	var rules models.BookingRules
+	if req.BookingRules != nil {
+		if err := validateBookingRules(*req.BookingRules); err != nil {
+			return nil, err
+		}
+		rules = *req.BookingRules
+	}
+
 	field := models.Field{
-		Name:         req.Name,
-		PricePerHour: req.PricePerHour,
-		Location:     req.Location,
+		Name:           req.Name,
+		PricePerHour:   models.NewMoney(req.PricePerHour, currency),
+		Location:       req.Location,
+		Sport:          strings.ToLower(req.Sport),
+		OpenTime:       req.OpenTime,
+		CloseTime:      req.CloseTime,
+		Timezone:       timezone,
+		TaxRateID:      req.TaxRateID,
+		CommissionRate: commissionRate,
+		PayoutSchedule: schedule,
+		BookingRules:   rules,
 	}
 
-	if err := s.db.Create(&field).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if req.OwnerID != nil {
+			if err := assignOwner(tx, *req.OwnerID); err != nil {
+				return err
+			}
+			field.OwnerID = req.OwnerID
+		}
+		return tx.Create(&field).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &field, nil
 }
 
-func (s *FieldService) GetAllFields() ([]models.Field, error) {
+var fieldListSpec = listSpec{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"price":      "price_per_hour_amount",
+		"rating":     "rating_avg",
+		"created_at": "created_at",
+	},
+	defaultSort: "id",
+}
+
+func (s *FieldService) GetAllFields(q ListQuery) ([]models.Field, *utils.Pagination, error) {
 	var fields []models.Field
-	if err := s.db.Find(&fields).Error; err != nil {
-		return nil, err
+	page, err := q.list(s.db, fieldListSpec, &fields)
+	if err != nil {
+		return nil, nil, err
 	}
-	return fields, nil
+	return fields, page, nil
 }
 
 func (s *FieldService) GetFieldByID(id uint) (*models.Field, error) {
@@ -66,22 +171,101 @@ func (s *FieldService) UpdateField(id uint, req UpdateFieldRequest) (*models.Fie
 		return nil, err
 	}
 
+	openTime, closeTime := field.OpenTime, field.CloseTime
+	if req.OpenTime != "" || req.CloseTime != "" {
+		openTime, closeTime = req.OpenTime, req.CloseTime
+	}
+	if err := validateOperatingHours(openTime, closeTime); err != nil {
+		return nil, err
+	}
+
 	updates := map[string]interface{}{}
 	if req.Name != "" {
 		updates["name"] = req.Name
 	}
 	if req.PricePerHour > 0 {
-		updates["price_per_hour"] = req.PricePerHour
+		updates["price_per_hour_amount"] = req.PricePerHour
+	}
+	if req.Currency != "" {
+		currency, err := models.NormalizeCurrency(req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		updates["price_per_hour_currency"] = currency
 	}
 	if req.Location != "" {
 		updates["location"] = req.Location
 	}
+	if req.Sport != "" {
+		updates["sport"] = strings.ToLower(req.Sport)
+	}
+	if req.OpenTime != "" || req.CloseTime != "" {
+		updates["open_time"] = openTime
+		updates["close_time"] = closeTime
+	}
+	if req.Timezone != "" {
+		if err := validateTimezone(req.Timezone); err != nil {
+			return nil, err
+		}
+		updates["timezone"] = req.Timezone
+	}
+	// A tax_rate_id of 0 goes back to the rate for the field's sport
+	if req.TaxRateID != nil && *req.TaxRateID == 0 {
+		updates["tax_rate_id"] = nil
+	} else if req.TaxRateID != nil {
+		if err := s.checkTaxRate(req.TaxRateID); err != nil {
+			return nil, err
+		}
+		updates["tax_rate_id"] = *req.TaxRateID
+	}
+
+	if req.CommissionRate != nil || req.PayoutSchedule != "" {
+		commissionRate := field.CommissionRate
+		if req.CommissionRate != nil {
+			commissionRate = *req.CommissionRate
+		}
+		if err := validateCommission(commissionRate, req.PayoutSchedule); err != nil {
+			return nil, err
+		}
+		updates["commission_rate"] = commissionRate
+		if req.PayoutSchedule != "" {
+			updates["payout_schedule"] = req.PayoutSchedule
+		}
+	}
+	if req.BookingRules != nil {
+		if err := validateBookingRules(*req.BookingRules); err != nil {
+			return nil, err
+		}
+		updates["min_duration_minutes"] = req.BookingRules.MinDurationMinutes
+		updates["max_duration_minutes"] = req.BookingRules.MaxDurationMinutes
+		updates["slot_minutes"] = req.BookingRules.SlotMinutes
+		updates["buffer_minutes"] = req.BookingRules.BufferMinutes
+		updates["min_notice_minutes"] = req.BookingRules.MinNoticeMinutes
+		updates["max_advance_days"] = req.BookingRules.MaxAdvanceDays
+	}
 
-	if err := s.db.Model(field).Updates(updates).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if req.OwnerID != nil && *req.OwnerID == 0 {
+			updates["owner_id"] = nil
+		} else if req.OwnerID != nil {
+			if err := assignOwner(tx, *req.OwnerID); err != nil {
+				return err
+			}
+			updates["owner_id"] = *req.OwnerID
+		}
+		if req.Timezone != "" {
+			// Bookings show their times in the field's timezone
+			if err := tx.Model(&models.Booking{}).Where("field_id = ?", field.ID).Update("timezone", req.Timezone).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(field).Updates(updates).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return field, nil
+	return s.GetFieldByID(id)
 }
 
 func (s *FieldService) DeleteField(id uint) error {
@@ -94,3 +278,82 @@ func (s *FieldService) DeleteField(id uint) error {
 	}
 	return nil
 }
+
+func (s *FieldService) checkTaxRate(id *uint) error {
+	if id == nil {
+		return nil
+	}
+	var count int64
+	s.db.Model(&models.TaxRate{}).Where("id = ?", *id).Count(&count)
+	if count == 0 {
+		return errors.New("tax rate not found")
+	}
+	return nil
+}
+
+// assignOwner checks that a user can own fields and gives them the owner
+// role. Admins manage the platform's own fields and cannot be owners.
+func assignOwner(tx *gorm.DB, userID uint) error {
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("owner not found")
+		}
+		return err
+	}
+	switch user.Role {
+	case models.RoleOwner:
+		return nil
+	case models.RoleAdmin:
+		return errors.New("admins cannot own fields")
+	}
+	return tx.Model(&user).Update("role", models.RoleOwner).Error
+}
+
+func validateCommission(rate int, schedule models.PayoutSchedule) error {
+	if rate < 0 || rate > 10000 {
+		return errors.New("commission_rate must be between 0 and 10000 basis points")
+	}
+	switch schedule {
+	case "", models.PayoutWeekly, models.PayoutMonthly:
+		return nil
+	}
+	return errors.New("payout_schedule must be weekly or monthly")
+}
+
+// validateTimezone accepts IANA timezone names such as Asia/Jakarta.
+func validateTimezone(name string) error {
+	if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+		return fmt.Errorf("unknown timezone %q, use an IANA name such as Asia/Jakarta", name)
+	}
+	return nil
+}
+
+// validateOperatingHours checks an optional HH:MM opening window. Both ends
+// must be given together; leaving both empty means the field never closes.
+func validateOperatingHours(openTime, closeTime string) error {
+	if openTime == "" && closeTime == "" {
+		return nil
+	}
+	opens, err := parseClock(openTime)
+	if err != nil {
+		return errors.New("open_time must be in HH:MM format")
+	}
+	closes, err := parseClock(closeTime)
+	if err != nil {
+		return errors.New("close_time must be in HH:MM format")
+	}
+	if closes <= opens {
+		return errors.New("close_time must be after open_time")
+	}
+	return nil
+}
+
+// currencyOrDefault normalizes an optional currency code, falling back to the
+// configured default when none is given.
+func currencyOrDefault(cfg *config.Config, code string) (string, error) {
+	if code == "" {
+		return cfg.Currency.Default, nil
+	}
+	return models.NormalizeCurrency(code)
+}
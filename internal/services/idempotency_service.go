@@ -0,0 +1,119 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+type IdempotencyService struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyService(db *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+// Fingerprint identifies a request by its method, path and body.
+func Fingerprint(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Begin claims a key for a request. It returns the stored record when the
+// request was already completed and should be replayed, or nil when the
+// caller now owns the key and must run the request and then call Complete
+// or Release. Keys past their expiry are treated as unused. The caller holds
+// the key for lease; a request that has not completed by then, say because
+// its server died, can be taken over by a retry.
+func (s *IdempotencyService) Begin(userID uint, key, method, path, hash string, ttl, lease time.Duration) (*models.IdempotencyKey, error) {
+	now := time.Now()
+	lockedUntil := now.Add(lease)
+	record := models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Method:      method,
+		Path:        path,
+		RequestHash: hash,
+		LockedUntil: &lockedUntil,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	// The unique index decides which of two concurrent requests wins
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := s.db.Where("user_id = ? AND key = ?", userID, key).First(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	if existing.ExpiresAt.Before(time.Now()) {
+		if err := s.db.Where("id = ? AND expires_at < ?", existing.ID, time.Now()).Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return nil, err
+		}
+		return s.Begin(userID, key, method, path, hash, ttl, lease)
+	}
+	if existing.RequestHash != hash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.StatusCode == 0 {
+		// Only one retry can take over a lapsed lease
+		result := s.db.Model(&models.IdempotencyKey{}).
+			Where("id = ? AND status_code = 0 AND (locked_until IS NULL OR locked_until < ?)", existing.ID, now).
+			Update("locked_until", lockedUntil)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return nil, nil
+		}
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return &existing, nil
+}
+
+// Complete stores the response of a request so retries can replay it.
+func (s *IdempotencyService) Complete(userID uint, key string, statusCode int, body []byte) error {
+	return s.db.Model(&models.IdempotencyKey{}).
+		Where("user_id = ? AND key = ?", userID, key).
+		Updates(map[string]interface{}{
+			"status_code":   statusCode,
+			"response_body": body,
+			"locked_until":  nil,
+		}).Error
+}
+
+// Release forgets a key whose request failed on the server side, so the
+// client can retry it with the same key.
+func (s *IdempotencyService) Release(userID uint, key string) error {
+	return s.db.Where("user_id = ? AND key = ?", userID, key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// PurgeExpired deletes stored keys past their expiry and returns how many
+// were removed.
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	result := s.db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupIdempotencyTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.IdempotencyKey{})
+
+	return db
+}
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	db := setupIdempotencyTestDB()
+	idempotencyService := NewIdempotencyService(db)
+
+	body := []byte(`{"field_id":1}`)
+	hash := Fingerprint("POST", "/api/v1/bookings", body)
+
+	stored, err := idempotencyService.Begin(1, "retry-me", "POST", "/api/v1/bookings", hash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, stored, "first request owns the key")
+
+	_, err = idempotencyService.Begin(1, "retry-me", "POST", "/api/v1/bookings", hash, time.Hour, time.Minute)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+
+	assert.NoError(t, idempotencyService.Complete(1, "retry-me", 201, []byte(`{"success":true}`)))
+
+	stored, err = idempotencyService.Begin(1, "retry-me", "POST", "/api/v1/bookings", hash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 201, stored.StatusCode)
+	assert.Equal(t, `{"success":true}`, string(stored.ResponseBody))
+
+	otherHash := Fingerprint("POST", "/api/v1/bookings", []byte(`{"field_id":2}`))
+	_, err = idempotencyService.Begin(1, "retry-me", "POST", "/api/v1/bookings", otherHash, time.Hour, time.Minute)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	stored, err = idempotencyService.Begin(2, "retry-me", "POST", "/api/v1/bookings", otherHash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, stored, "keys are scoped per user")
+}
+
+func TestIdempotencyService_Lease(t *testing.T) {
+	db := setupIdempotencyTestDB()
+	idempotencyService := NewIdempotencyService(db)
+
+	hash := Fingerprint("POST", "/api/v1/orders", []byte(`{"items":[]}`))
+	_, err := idempotencyService.Begin(1, "crashed", "POST", "/api/v1/orders", hash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	_, err = idempotencyService.Begin(1, "crashed", "POST", "/api/v1/orders", hash, time.Hour, time.Minute)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+
+	// The first request never finished, so once its lease is up a retry
+	// takes the key over, and holds it against the next one
+	db.Model(&models.IdempotencyKey{}).Where("key = ?", "crashed").Update("locked_until", time.Now().Add(-time.Second))
+	stored, err := idempotencyService.Begin(1, "crashed", "POST", "/api/v1/orders", hash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+	_, err = idempotencyService.Begin(1, "crashed", "POST", "/api/v1/orders", hash, time.Hour, time.Minute)
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+
+	assert.NoError(t, idempotencyService.Complete(1, "crashed", 201, []byte(`{}`)))
+	stored, err = idempotencyService.Begin(1, "crashed", "POST", "/api/v1/orders", hash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 201, stored.StatusCode)
+	assert.Nil(t, stored.LockedUntil)
+}
+
+func TestIdempotencyService_Expiry(t *testing.T) {
+	db := setupIdempotencyTestDB()
+	idempotencyService := NewIdempotencyService(db)
+
+	hash := Fingerprint("POST", "/api/v1/payments", []byte(`{"booking_id":1}`))
+	_, err := idempotencyService.Begin(1, "old", "POST", "/api/v1/payments", hash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.NoError(t, idempotencyService.Complete(1, "old", 200, []byte(`{}`)))
+
+	db.Model(&models.IdempotencyKey{}).Where("key = ?", "old").Update("expires_at", time.Now().Add(-time.Minute))
+
+	// An expired key is free to be used again, even with another body
+	otherHash := Fingerprint("POST", "/api/v1/payments", []byte(`{"booking_id":2}`))
+	stored, err := idempotencyService.Begin(1, "old", "POST", "/api/v1/payments", otherHash, time.Hour, time.Minute)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+
+	db.Model(&models.IdempotencyKey{}).Where("key = ?", "old").Update("expires_at", time.Now().Add(-time.Minute))
+	purged, err := idempotencyService.PurgeExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
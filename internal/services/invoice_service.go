@@ -0,0 +1,584 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewInvoiceService(db *gorm.DB, cfg *config.Config) *InvoiceService {
+	return &InvoiceService{db: db, cfg: cfg}
+}
+
+func (s *InvoiceService) WithContext(ctx context.Context) *InvoiceService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// GetPaymentInvoice returns the invoice of a payment, or the invoice or
+// credit note of the payment with the given number. Users only see their
+// own invoices; admins see all of them.
+func (s *InvoiceService) GetPaymentInvoice(userID uint, isAdmin bool, paymentID uint, number string) (*models.Invoice, error) {
+	query := s.db.Preload("Lines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).Preload("Payment").Preload("CreditedInvoice").Where("payment_id = ?", paymentID)
+	if number != "" {
+		query = query.Where("number = ?", number)
+	} else {
+		query = query.Where("adjustment_id IS NULL AND refund_id IS NULL")
+	}
+
+	var invoice models.Invoice
+	if err := query.First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invoice not found")
+		}
+		return nil, err
+	}
+
+	if !isAdmin && invoice.UserID != userID {
+		return nil, errors.New("invoice not found")
+	}
+
+	return &invoice, nil
+}
+
+// GetPaymentInvoices lists the invoices and credit notes of a payment in the
+// order they were issued, without their lines.
+func (s *InvoiceService) GetPaymentInvoices(userID uint, isAdmin bool, paymentID uint) ([]models.Invoice, error) {
+	query := s.db.Where("payment_id = ?", paymentID)
+	if !isAdmin {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var invoices []models.Invoice
+	if err := query.Order("id ASC").Find(&invoices).Error; err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// issueInvoice numbers and stores the invoice for a completed payment. It
+// must run in the payment's transaction: the sequence row stays locked until
+// the payment commits, and a rolled back payment gives its number back.
+func issueInvoice(tx *gorm.DB, payment *models.Payment) (*models.Invoice, error) {
+	lines, err := invoiceLines(tx, payment)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := models.Invoice{Total: payment.Amount, Lines: lines}
+	if err := fileInvoice(tx, payment, models.InvoicePrefix, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// issueChargeInvoice invoices a follow-up charge on a payment for the
+// booking it was taken for, with tax in the same proportion as the booking's
+// price.
+func issueChargeInvoice(tx *gorm.DB, payment *models.Payment, adjustment *models.PaymentAdjustment, booking *models.Booking, field *models.Field) (*models.Invoice, error) {
+	line := invoiceAmountLine(adjustment.Amount, proportionalTax(adjustment.Amount, booking.TaxAmount, booking.TotalPrice), booking.TaxInclusive)
+	line.BookingID = &booking.ID
+	line.Description = fmt.Sprintf("%s booking #%d, %s charge", field.Name, booking.ID, adjustment.Reason)
+	line.Venue = field.Location
+	line.StartTime = booking.StartTime
+	line.EndTime = booking.EndTime
+	line.Timezone = booking.Timezone
+	line.TaxRate = booking.TaxRate
+
+	invoice := models.Invoice{AdjustmentID: &adjustment.ID, Total: adjustment.Amount, Lines: []models.InvoiceLine{line}}
+	if err := fileInvoice(tx, payment, models.InvoicePrefix, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// issueCreditNote documents a refund with a credit note against the
+// payment's invoice. The refund gives back tax in the same proportion as the
+// payment carried it.
+func issueCreditNote(tx *gorm.DB, payment *models.Payment, refund *models.Refund) (*models.Invoice, error) {
+	var credited models.Invoice
+	err := tx.Where("payment_id = ? AND adjustment_id IS NULL AND refund_id IS NULL", payment.ID).First(&credited).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	amount := refund.Amount.Neg()
+	line := invoiceAmountLine(amount, proportionalTax(amount, payment.TaxAmount, payment.Amount), false)
+	line.BookingID = payment.BookingID
+	line.Description = fmt.Sprintf("Refund of payment %s, %s", payment.TransactionID, refund.Reason)
+
+	// The refund of a single booking names its slot
+	if payment.BookingID != nil {
+		var booking models.Booking
+		if err := tx.Preload("Field").First(&booking, *payment.BookingID).Error; err != nil {
+			return nil, err
+		}
+		line.Venue = booking.Field.Location
+		line.StartTime = booking.StartTime
+		line.EndTime = booking.EndTime
+		line.Timezone = booking.Timezone
+		line.TaxRate = booking.TaxRate
+		line.TaxInclusive = booking.TaxInclusive
+		if booking.TaxInclusive {
+			line.Amount = amount
+		}
+	}
+
+	invoice := models.Invoice{RefundID: &refund.ID, Total: amount, Lines: []models.InvoiceLine{line}}
+	if credited.ID != 0 {
+		invoice.CreditedInvoiceID = &credited.ID
+	}
+	if err := fileInvoice(tx, payment, models.CreditNotePrefix, &invoice); err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// invoiceAmountLine is a line for a plain amount that contains tax. Its
+// amount is shown with the tax when the tax is inclusive.
+func invoiceAmountLine(amount, tax models.Money, taxInclusive bool) models.InvoiceLine {
+	line := models.InvoiceLine{
+		UnitPrice:    amount,
+		Amount:       amount.Sub(tax),
+		Discount:     models.NewMoney(0, amount.Currency),
+		Net:          amount.Sub(tax),
+		Tax:          tax,
+		TaxInclusive: taxInclusive,
+		Total:        amount,
+	}
+	if taxInclusive {
+		line.Amount = amount
+	}
+	return line
+}
+
+// proportionalTax is the part of tax that amount carries when it is part of
+// total, rounded half up.
+func proportionalTax(amount, tax, total models.Money) models.Money {
+	if tax.IsZero() || total.IsZero() {
+		return models.NewMoney(0, amount.Currency)
+	}
+	magnitude := amount.Amount
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+	share := (magnitude*tax.Amount + total.Amount/2) / total.Amount
+	if amount.Amount < 0 {
+		share = -share
+	}
+	return models.NewMoney(share, amount.Currency)
+}
+
+// fileInvoice bills an invoice for a payment to its payer, adds up its lines
+// and stores it under the tenant's next number with the given prefix.
+func fileInvoice(tx *gorm.DB, payment *models.Payment, prefix string, invoice *models.Invoice) error {
+	var user models.User
+	if payment.UserID != nil {
+		if err := tx.First(&user, *payment.UserID).Error; err != nil {
+			return err
+		}
+	} else if payment.BookingID != nil {
+		// Guests booked at the front desk are billed by name
+		var booking models.Booking
+		if err := tx.Select("id", "guest_name").First(&booking, *payment.BookingID).Error; err != nil {
+			return err
+		}
+		user.Name = booking.GuestName
+	}
+
+	currency := invoice.Total.Currency
+	issuedAt := time.Now().UTC()
+	sequence, err := nextInvoiceSequence(tx, payment.TenantID, issuedAt.Year())
+	if err != nil {
+		return err
+	}
+
+	invoice.TenantID = payment.TenantID
+	invoice.Number = fmt.Sprintf("%s-%d-%06d", prefix, issuedAt.Year(), sequence)
+	invoice.Year = issuedAt.Year()
+	invoice.Sequence = sequence
+	invoice.PaymentID = payment.ID
+	invoice.UserID = user.ID
+	invoice.BillToName = user.Name
+	invoice.BillToEmail = user.Email
+	invoice.Subtotal = models.NewMoney(0, currency)
+	invoice.DiscountAmount = models.NewMoney(0, currency)
+	invoice.NetAmount = models.NewMoney(0, currency)
+	invoice.TaxAmount = models.NewMoney(0, currency)
+	invoice.PaymentMethod = payment.PaymentMethod
+	invoice.IssuedAt = issuedAt
+	for _, line := range invoice.Lines {
+		if !line.Total.SameCurrency(invoice.Total) {
+			return models.ErrCurrencyMismatch
+		}
+		invoice.Subtotal = invoice.Subtotal.Add(line.Amount)
+		invoice.DiscountAmount = invoice.DiscountAmount.Add(line.Discount)
+		invoice.NetAmount = invoice.NetAmount.Add(line.Net)
+		invoice.TaxAmount = invoice.TaxAmount.Add(line.Tax)
+	}
+
+	return tx.Create(invoice).Error
+}
+
+// nextInvoiceSequence reserves the tenant's next invoice number of the year.
+// Every club numbers its own invoices.
+func nextInvoiceSequence(tx *gorm.DB, tenantID uint, year int) (int, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.InvoiceSequence{TenantID: tenantID, Year: year}).Error; err != nil {
+		return 0, err
+	}
+
+	var sequence models.InvoiceSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("tenant_id = ? AND year = ?", tenantID, year).First(&sequence).Error; err != nil {
+		return 0, err
+	}
+
+	sequence.LastNumber++
+	if err := tx.Model(&models.InvoiceSequence{}).Where("tenant_id = ? AND year = ?", tenantID, year).Update("last_number", sequence.LastNumber).Error; err != nil {
+		return 0, err
+	}
+	return sequence.LastNumber, nil
+}
+
+// invoiceLines itemises what a payment covers: one line per booking, or the
+// part of a split booking that the payment settles.
+func invoiceLines(tx *gorm.DB, payment *models.Payment) ([]models.InvoiceLine, error) {
+	var bookings []models.Booking
+	query := tx.Preload("Field").Order("start_time ASC")
+	switch {
+	case payment.OrderID != nil:
+		query = query.Where("order_id = ?", *payment.OrderID)
+	case payment.BookingID != nil:
+		query = query.Where("id = ?", *payment.BookingID)
+	default:
+		return nil, errors.New("payment covers no booking")
+	}
+	if err := query.Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	lines := make([]models.InvoiceLine, 0, len(bookings))
+	for i := range bookings {
+		booking := &bookings[i]
+		line := models.InvoiceLine{
+			BookingID:    &booking.ID,
+			Description:  fmt.Sprintf("%s booking #%d", booking.Field.Name, booking.ID),
+			Venue:        booking.Field.Location,
+			StartTime:    booking.StartTime,
+			EndTime:      booking.EndTime,
+			Timezone:     booking.Timezone,
+			UnitPrice:    booking.Field.PricePerHour,
+			Amount:       booking.Subtotal,
+			Discount:     booking.DiscountAmount.Add(booking.PlanDiscount),
+			Net:          booking.NetAmount,
+			Tax:          booking.TaxAmount,
+			TaxRate:      booking.TaxRate,
+			TaxInclusive: booking.TaxInclusive,
+			Total:        booking.TotalPrice,
+		}
+
+		// Split bookings are invoiced share by share
+		if payment.BookingID != nil && payment.Amount != booking.TotalPrice {
+			line.Description = fmt.Sprintf("Share of %s", line.Description)
+			line.UnitPrice = payment.Amount
+			line.Amount = payment.Amount
+			line.Discount = models.NewMoney(0, payment.Amount.Currency)
+			line.Net = payment.NetAmount
+			line.Tax = payment.TaxAmount
+			line.Total = payment.Amount
+			if !line.TaxInclusive {
+				line.Amount = payment.NetAmount
+			}
+		}
+
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// invoiceSeller returns the tenant that issued the invoice, with the seller
+// details to print on it.
+func (s *InvoiceService) invoiceSeller(invoice *models.Invoice) (*models.Tenant, error) {
+	var seller models.Tenant
+	if err := s.db.First(&seller, invoice.TenantID).Error; err != nil {
+		return nil, err
+	}
+	if seller.SellerName == "" {
+		seller.SellerName = seller.Name
+	}
+	return &seller, nil
+}
+
+// RenderPDF lays the invoice out on a single A4 page.
+func (s *InvoiceService) RenderPDF(invoice *models.Invoice) ([]byte, error) {
+	seller, err := s.invoiceSeller(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(invoice.IssuedAt)
+	pdf.SetModificationDate(invoice.IssuedAt)
+	pdf.SetTitle(invoice.Number, true)
+	pdf.SetAuthor(seller.SellerName, true)
+	pdf.SetMargins(15, 15, 15)
+	pdf.AddPage()
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.CellFormat(100, 10, strings.ToUpper(invoiceTitle(invoice)), "", 0, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(80, 5, tr(invoice.Number), "", 2, "R", false, 0, "")
+	pdf.CellFormat(80, 5, invoice.IssuedAt.Format("02 Jan 2006"), "", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(90, 5, tr(seller.SellerName), "", 0, "L", false, 0, "")
+	pdf.CellFormat(90, 5, "Bill to", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(90, 5, tr(seller.SellerAddress), "", 0, "L", false, 0, "")
+	pdf.CellFormat(90, 5, tr(invoice.BillToName), "", 1, "L", false, 0, "")
+	taxID := ""
+	if seller.SellerTaxID != "" {
+		taxID = "Tax ID: " + seller.SellerTaxID
+	}
+	pdf.CellFormat(90, 5, tr(taxID), "", 0, "L", false, 0, "")
+	pdf.CellFormat(90, 5, tr(invoice.BillToEmail), "", 1, "L", false, 0, "")
+	pdf.Ln(8)
+
+	widths := []float64{54, 36, 24, 22, 22, 22}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(235, 235, 235)
+	for i, heading := range []string{"Description", "Venue", "Amount", "Discount", "Tax", "Total"} {
+		align := "L"
+		if i >= 2 {
+			align = "R"
+		}
+		pdf.CellFormat(widths[i], 7, heading, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range invoice.Lines {
+		pdf.CellFormat(widths[0], 5, tr(line.Description), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 5, tr(line.Venue), "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[2], 5, line.Amount.String(), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 5, line.Discount.Neg().String(), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[4], 5, line.Tax.String(), "", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[5], 5, line.Total.String(), "", 1, "R", false, 0, "")
+		pdf.SetTextColor(110, 110, 110)
+		pdf.CellFormat(widths[0], 5, formatSlot(line.StartTime, line.EndTime, line.Timezone), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1]+widths[2]+widths[3], 5, "", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[4]+widths[5], 5, formatTaxRate(line.TaxRate, line.TaxInclusive), "B", 1, "R", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	}
+	pdf.Ln(4)
+
+	totals := []struct {
+		label  string
+		amount models.Money
+	}{
+		{"Subtotal", invoice.Subtotal},
+		{"Discount", invoice.DiscountAmount.Neg()},
+		{"Net", invoice.NetAmount},
+		{"Tax", invoice.TaxAmount},
+		{"Total", invoice.Total},
+	}
+	for i, total := range totals {
+		if i == len(totals)-1 {
+			pdf.SetFont("Helvetica", "B", 10)
+		}
+		pdf.CellFormat(widths[0]+widths[1]+widths[2], 6, "", "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[3]+widths[4], 6, total.label, "", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[5], 6, total.amount.String(), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(8)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 5, tr(invoiceNote(invoice)), "", 1, "L", false, 0, "")
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// invoiceTitle names the document: an invoice or a credit note.
+func invoiceTitle(invoice *models.Invoice) string {
+	if invoice.RefundID != nil {
+		return "Credit note"
+	}
+	return "Invoice"
+}
+
+// invoiceNote says how the invoice was paid, or which invoice a credit note
+// credits.
+func invoiceNote(invoice *models.Invoice) string {
+	if invoice.RefundID != nil {
+		if invoice.CreditedInvoice != nil {
+			return fmt.Sprintf("Credits invoice %s", invoice.CreditedInvoice.Number)
+		}
+		return "Credits a refunded payment"
+	}
+	note := fmt.Sprintf("Paid by %s", invoice.PaymentMethod)
+	if invoice.Payment != nil {
+		note += fmt.Sprintf(", transaction %s", invoice.Payment.TransactionID)
+	}
+	return note
+}
+
+var invoiceTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"slot":    formatSlot,
+	"upper":   strings.ToUpper,
+	"taxRate": formatTaxRate,
+	"date":    func(t time.Time) string { return t.Format("02 Jan 2006") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.Invoice.Number}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 14px; color: #222; max-width: 800px; margin: 2em auto; }
+table { width: 100%; border-collapse: collapse; }
+th, td { padding: 6px 4px; text-align: left; }
+.lines th { background: #ebebeb; }
+.lines td { border-bottom: 1px solid #ddd; }
+.num { text-align: right; }
+.muted { color: #6e6e6e; font-size: 12px; }
+.totals td { border: none; }
+.grand td { font-weight: bold; }
+</style>
+</head>
+<body>
+<table>
+<tr><td><h1>{{upper .Title}}</h1></td><td class="num">{{.Invoice.Number}}<br>{{date .Invoice.IssuedAt}}</td></tr>
+<tr>
+<td><strong>{{.Seller.SellerName}}</strong><br>{{.Seller.SellerAddress}}{{if .Seller.SellerTaxID}}<br>Tax ID: {{.Seller.SellerTaxID}}{{end}}</td>
+<td><strong>Bill to</strong><br>{{.Invoice.BillToName}}<br>{{.Invoice.BillToEmail}}</td>
+</tr>
+</table>
+<table class="lines">
+<tr><th>Description</th><th>Venue</th><th class="num">Amount</th><th class="num">Discount</th><th class="num">Tax</th><th class="num">Total</th></tr>
+{{range .Lines}}<tr><td>{{.Description}}<br><span class="muted">{{slot .StartTime .EndTime .Timezone}}</span></td><td>{{.Venue}}</td><td class="num">{{.Amount}}</td><td class="num">{{.Discount}}</td><td class="num">{{.Tax}}<br><span class="muted">{{taxRate .TaxRate .TaxInclusive}}</span></td><td class="num">{{.Total}}</td></tr>
+{{end}}</table>
+<table class="totals">
+<tr><td></td><td class="num">Subtotal</td><td class="num">{{.Subtotal}}</td></tr>
+<tr><td></td><td class="num">Discount</td><td class="num">{{.Discount}}</td></tr>
+<tr><td></td><td class="num">Net</td><td class="num">{{.Net}}</td></tr>
+<tr><td></td><td class="num">Tax</td><td class="num">{{.Tax}}</td></tr>
+<tr class="grand"><td></td><td class="num">Total</td><td class="num">{{.Total}}</td></tr>
+</table>
+<p class="muted">{{.Note}}</p>
+</body>
+</html>
+`))
+
+type invoiceView struct {
+	Invoice  *models.Invoice
+	Seller   *models.Tenant
+	Title    string
+	Note     string
+	Lines    []invoiceLineView
+	Subtotal string
+	Discount string
+	Net      string
+	Tax      string
+	Total    string
+}
+
+type invoiceLineView struct {
+	Description  string
+	Venue        string
+	StartTime    time.Time
+	EndTime      time.Time
+	Timezone     string
+	Amount       string
+	Discount     string
+	Tax          string
+	TaxRate      int
+	TaxInclusive bool
+	Total        string
+}
+
+// RenderHTML renders the same invoice as a standalone HTML page.
+func (s *InvoiceService) RenderHTML(invoice *models.Invoice) ([]byte, error) {
+	seller, err := s.invoiceSeller(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	view := invoiceView{
+		Invoice:  invoice,
+		Seller:   seller,
+		Title:    invoiceTitle(invoice),
+		Note:     invoiceNote(invoice),
+		Subtotal: invoice.Subtotal.String(),
+		Discount: invoice.DiscountAmount.Neg().String(),
+		Net:      invoice.NetAmount.String(),
+		Tax:      invoice.TaxAmount.String(),
+		Total:    invoice.Total.String(),
+	}
+	for _, line := range invoice.Lines {
+		view.Lines = append(view.Lines, invoiceLineView{
+			Description:  line.Description,
+			Venue:        line.Venue,
+			StartTime:    line.StartTime,
+			EndTime:      line.EndTime,
+			Timezone:     line.Timezone,
+			Amount:       line.Amount.String(),
+			Discount:     line.Discount.Neg().String(),
+			Tax:          line.Tax.String(),
+			TaxRate:      line.TaxRate,
+			TaxInclusive: line.TaxInclusive,
+			Total:        line.Total.String(),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := invoiceTemplate.Execute(&buf, view); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// formatTaxRate writes a rate in basis points as a percentage, e.g. 11%
+// incl. for a tax already contained in the price.
+func formatTaxRate(rate int, inclusive bool) string {
+	if rate == 0 {
+		return ""
+	}
+	percent := strconv.FormatFloat(float64(rate)/100, 'f', -1, 64) + "%"
+	if inclusive {
+		percent += " incl."
+	}
+	return percent
+}
+
+// formatSlot writes a slot in its timezone, or nothing for a line that is
+// not about one slot.
+func formatSlot(start, end time.Time, timezone string) string {
+	if start.IsZero() {
+		return ""
+	}
+	loc := models.LoadLocation(timezone)
+	return fmt.Sprintf("%s - %s", start.In(loc).Format("02 Jan 2006 15:04"), end.In(loc).Format("15:04 MST"))
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupInvoiceTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.Tenant{}, &models.User{}, &models.Field{}, &models.Order{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.PaymentAdjustment{}, &models.Refund{}, &models.WaitlistEntry{},
+		&models.PaymentSplit{}, &models.Wallet{}, &models.WalletTransaction{}, &models.MembershipPlan{}, &models.Subscription{},
+		&models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestInvoiceService_Numbering(t *testing.T) {
+	db := setupInvoiceTestDB()
+	cfg := bookingTestConfig()
+	invoiceService := NewInvoiceService(db, cfg)
+	paymentService := NewPaymentService(db)
+	bookingService := NewBookingService(db, cfg)
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+	other := models.User{Email: "other@example.com", Name: "Other", Role: models.RoleUser}
+	db.Create(&other)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Jl. Sudirman 1"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	var payments []*models.Payment
+	for i := 0; i < 3; i++ {
+		booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime.Add(time.Duration(i*2) * time.Hour), EndTime: startTime.Add(time.Duration(i*2+1) * time.Hour)})
+		assert.NoError(t, err)
+
+		// A wallet payment that fails must not use up a number
+		_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: models.PaymentMethodWallet})
+		assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+		payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+		assert.NoError(t, err)
+		payments = append(payments, payment)
+	}
+
+	year := time.Now().UTC().Year()
+	for i, payment := range payments {
+		invoice, err := invoiceService.GetPaymentInvoice(user.ID, false, payment.ID, "")
+		assert.NoError(t, err)
+		assert.Equal(t, i+1, invoice.Sequence)
+		assert.Equal(t, year, invoice.Year)
+		assert.True(t, strings.HasPrefix(invoice.Number, "INV-"))
+		assert.Equal(t, int64(100000), invoice.Total.Amount)
+		assert.Len(t, invoice.Lines, 1)
+		assert.Equal(t, "Jl. Sudirman 1", invoice.Lines[0].Venue)
+	}
+
+	_, err := invoiceService.GetPaymentInvoice(other.ID, false, payments[0].ID, "")
+	assert.Error(t, err, "users cannot see each other's invoices")
+	_, err = invoiceService.GetPaymentInvoice(other.ID, true, payments[0].ID, "")
+	assert.NoError(t, err)
+}
+
+func TestInvoiceService_Render(t *testing.T) {
+	db := setupInvoiceTestDB()
+	cfg := bookingTestConfig()
+	invoiceService := NewInvoiceService(db, cfg)
+	paymentService := NewPaymentService(db)
+	bookingService := NewBookingService(db, cfg)
+
+	db.Create(&models.Tenant{Slug: "default", Name: "Default", SellerName: "Test Arena", SellerTaxID: "01.234.567.8-901.000"})
+	user := models.User{Email: "player@example.com", Name: "Zoë <Player>", Role: models.RoleUser}
+	db.Create(&user)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Jl. Sudirman 1", Timezone: "Asia/Jakarta"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+	payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	invoice, err := invoiceService.GetPaymentInvoice(user.ID, false, payment.ID, "")
+	assert.NoError(t, err)
+
+	pdf, err := invoiceService.RenderPDF(invoice)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-")))
+
+	html, err := invoiceService.RenderHTML(invoice)
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), invoice.Number)
+	assert.Contains(t, string(html), "Test Arena")
+	assert.Contains(t, string(html), "Tax ID: 01.234.567.8-901.000")
+	assert.Contains(t, string(html), "IDR 200,000")
+	assert.Contains(t, string(html), "Zoë &lt;Player&gt;")
+	assert.Contains(t, string(html), payment.TransactionID)
+
+	// Slots are printed in the field's local time
+	local := startTime.In(models.LoadLocation("Asia/Jakarta"))
+	assert.Contains(t, string(html), fmt.Sprintf("%s - %s WIB", local.Format("02 Jan 2006 15:04"), local.Add(2*time.Hour).Format("15:04")))
+}
+
+func TestInvoiceService_TopUpsAndCreditNotes(t *testing.T) {
+	db := setupInvoiceTestDB()
+	cfg := bookingTestConfig()
+	invoiceService := NewInvoiceService(db, cfg)
+	paymentService := NewPaymentService(db)
+	bookingService := NewBookingService(db, cfg)
+
+	db.Create(&models.Tenant{Slug: "default", Name: "Default", SellerName: "Test Arena"})
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+	admin := models.User{Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin}
+	db.Create(&admin)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Jl. Sudirman 1"}
+	db.Create(&field)
+	db.Create(&models.TaxRate{Name: "VAT", Rate: 1100, Inclusive: true, Active: true})
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	// Moving to a longer slot invoices the top-up for the new slot
+	newStart := startTime.Add(24 * time.Hour)
+	_, err = bookingService.RescheduleBooking(user.ID, booking.ID, RescheduleBookingRequest{StartTime: newStart, EndTime: newStart.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = paymentService.RefundPayment(admin.ID, payment.ID, RefundRequest{Amount: 50000, Reason: "lights out"})
+	assert.NoError(t, err)
+
+	invoices, err := invoiceService.GetPaymentInvoices(user.ID, false, payment.ID)
+	assert.NoError(t, err)
+	if !assert.Len(t, invoices, 3) {
+		return
+	}
+	original, topUp, creditNote := invoices[0], invoices[1], invoices[2]
+
+	// The original invoice is still the default one
+	invoice, err := invoiceService.GetPaymentInvoice(user.ID, false, payment.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, original.Number, invoice.Number)
+
+	topUpInvoice, err := invoiceService.GetPaymentInvoice(user.ID, false, payment.ID, topUp.Number)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(topUp.Number, "INV-"))
+	assert.NotNil(t, topUp.AdjustmentID)
+	assert.Equal(t, int64(100000), topUp.Total.Amount)
+	assert.Equal(t, original.Sequence+1, topUp.Sequence)
+	if assert.Len(t, topUpInvoice.Lines, 1) {
+		assert.True(t, newStart.Equal(topUpInvoice.Lines[0].StartTime))
+		assert.Equal(t, int64(9910), topUpInvoice.Lines[0].Tax.Amount)
+	}
+
+	creditNoteInvoice, err := invoiceService.GetPaymentInvoice(user.ID, false, payment.ID, creditNote.Number)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(creditNote.Number, "CN-"))
+	assert.NotNil(t, creditNote.RefundID)
+	assert.Equal(t, int64(-50000), creditNote.Total.Amount)
+	assert.Equal(t, original.Sequence+2, creditNote.Sequence)
+	if assert.NotNil(t, creditNote.CreditedInvoiceID) {
+		assert.Equal(t, original.ID, *creditNote.CreditedInvoiceID)
+	}
+
+	html, err := invoiceService.RenderHTML(creditNoteInvoice)
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), "CREDIT NOTE")
+	assert.Contains(t, string(html), "Credits invoice "+original.Number)
+	assert.Contains(t, string(html), "-IDR 50,000")
+}
+
+func TestMoney_String(t *testing.T) {
+	assert.Equal(t, "IDR 0", models.NewMoney(0, "IDR").String())
+	assert.Equal(t, "IDR 999", models.NewMoney(999, "IDR").String())
+	assert.Equal(t, "IDR 1,000", models.NewMoney(1000, "IDR").String())
+	assert.Equal(t, "-IDR 1,234,567", models.NewMoney(-1234567, "IDR").String())
+	assert.Equal(t, "USD 19.99", models.NewMoney(1999, "USD").String())
+	assert.Equal(t, "USD 0.05", models.NewMoney(5, "USD").String())
+	assert.Equal(t, "KWD 1,234.500", models.NewMoney(1234500, "KWD").String())
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry does not balance")
+
+type LedgerService struct {
+	db *gorm.DB
+}
+
+func NewLedgerService(db *gorm.DB) *LedgerService {
+	return &LedgerService{db: db}
+}
+
+func (s *LedgerService) WithContext(ctx context.Context) *LedgerService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// TrialBalanceAccount is the running total of one ledger account. Balance is
+// debits minus credits, so revenue and liability accounts come out negative.
+type TrialBalanceAccount struct {
+	Account models.LedgerAccount `json:"account"`
+	Debit   models.Money         `json:"debit"`
+	Credit  models.Money         `json:"credit"`
+	Balance models.Money         `json:"balance"`
+}
+
+// TrialBalance lists every account used in one currency. The ledger is
+// sound when the total debits equal the total credits.
+type TrialBalance struct {
+	Currency    string                `json:"currency"`
+	Accounts    []TrialBalanceAccount `json:"accounts"`
+	TotalDebit  models.Money          `json:"total_debit"`
+	TotalCredit models.Money          `json:"total_credit"`
+	Balanced    bool                  `json:"balanced"`
+}
+
+// TrialBalance totals the ledger per currency and account.
+func (s *LedgerService) TrialBalance() ([]TrialBalance, error) {
+	var rows []struct {
+		Currency string
+		Account  models.LedgerAccount
+		Debit    int64
+		Credit   int64
+	}
+	if err := s.db.Model(&models.JournalLine{}).
+		Select("currency, account, SUM(debit) AS debit, SUM(credit) AS credit").
+		Group("currency, account").
+		Order("currency ASC").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	balances := []TrialBalance{}
+	byCurrency := map[string]map[models.LedgerAccount]TrialBalanceAccount{}
+	for _, row := range rows {
+		if byCurrency[row.Currency] == nil {
+			byCurrency[row.Currency] = map[models.LedgerAccount]TrialBalanceAccount{}
+			balances = append(balances, TrialBalance{Currency: row.Currency})
+		}
+		byCurrency[row.Currency][row.Account] = TrialBalanceAccount{
+			Account: row.Account,
+			Debit:   models.NewMoney(row.Debit, row.Currency),
+			Credit:  models.NewMoney(row.Credit, row.Currency),
+			Balance: models.NewMoney(row.Debit-row.Credit, row.Currency),
+		}
+	}
+
+	for i := range balances {
+		balance := &balances[i]
+		balance.TotalDebit = models.NewMoney(0, balance.Currency)
+		balance.TotalCredit = models.NewMoney(0, balance.Currency)
+		for _, account := range models.LedgerAccounts {
+			line, ok := byCurrency[balance.Currency][account]
+			if !ok {
+				continue
+			}
+			balance.Accounts = append(balance.Accounts, line)
+			balance.TotalDebit = balance.TotalDebit.Add(line.Debit)
+			balance.TotalCredit = balance.TotalCredit.Add(line.Credit)
+		}
+		balance.Balanced = balance.TotalDebit == balance.TotalCredit
+	}
+	return balances, nil
+}
+
+// journal collects the lines of a journal entry before it is posted.
+type journal struct {
+	entry models.JournalEntry
+}
+
+func newJournal(reference, description string) *journal {
+	return &journal{entry: models.JournalEntry{Reference: reference, Description: description}}
+}
+
+// post moves amount from the credit account to the debit account. Zero
+// amounts are left out, and negative ones move the other way.
+func (j *journal) post(debit, credit models.LedgerAccount, amount models.Money) *journal {
+	if amount.IsZero() {
+		return j
+	}
+	if amount.Amount < 0 {
+		debit, credit, amount = credit, debit, amount.Neg()
+	}
+	j.entry.Lines = append(j.entry.Lines,
+		models.JournalLine{Account: debit, Currency: amount.Currency, Debit: amount.Amount},
+		models.JournalLine{Account: credit, Currency: amount.Currency, Credit: amount.Amount},
+	)
+	return j
+}
+
+// postJournal stores a journal entry after checking that it balances in
+// every currency. It runs in the transaction of the business change it
+// records, so the ledger never disagrees with the tables it describes.
+func postJournal(tx *gorm.DB, j *journal) error {
+	if len(j.entry.Lines) == 0 {
+		return nil
+	}
+
+	totals := map[string]int64{}
+	for _, line := range j.entry.Lines {
+		if line.Currency == "" || (line.Debit == 0) == (line.Credit == 0) || line.Debit < 0 || line.Credit < 0 {
+			return fmt.Errorf("%w: invalid line on %s", ErrUnbalancedEntry, line.Account)
+		}
+		totals[line.Currency] += line.Debit - line.Credit
+	}
+	for currency, total := range totals {
+		if total != 0 {
+			return fmt.Errorf("%w: %s is off by %d", ErrUnbalancedEntry, currency, total)
+		}
+	}
+
+	return tx.Create(&j.entry).Error
+}
+
+// settlementAccount is where the money for a payment comes from.
+func settlementAccount(paymentMethod string) models.LedgerAccount {
+	switch paymentMethod {
+	case models.PaymentMethodWallet:
+		return models.AccountCustomerWallets
+	case models.PaymentMethodCash:
+		return models.AccountCash
+	}
+	return models.AccountGatewayClearing
+}
+
+// postPaymentJournal books a sale and its settlement: the invoiced net
+// amount and discounts are earned as venue revenue, the tax is owed, and the
+// receivable is settled from the gateway, the cash desk or the payer's
+// wallet. The share of owned fields is set aside for their owners.
+func postPaymentJournal(tx *gorm.DB, payment *models.Payment, invoice *models.Invoice) error {
+	j := newJournal(fmt.Sprintf("payment:%d", payment.ID), fmt.Sprintf("Payment %s", payment.TransactionID))
+	j.entry.PaymentID = &payment.ID
+	j.post(models.AccountReceivables, models.AccountVenueRevenue, invoice.NetAmount).
+		post(models.AccountDiscounts, models.AccountVenueRevenue, invoice.DiscountAmount).
+		post(models.AccountReceivables, models.AccountTaxPayable, invoice.TaxAmount).
+		post(settlementAccount(payment.PaymentMethod), models.AccountReceivables, payment.Amount)
+	if err := accrueSaleEarnings(tx, payment, invoice, j); err != nil {
+		return err
+	}
+	return postJournal(tx, j)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupLedgerTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Order{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.PaymentAdjustment{},
+		&models.Refund{}, &models.WaitlistEntry{}, &models.PaymentSplit{}, &models.PaymentShare{}, &models.Notification{}, &models.Wallet{},
+		&models.WalletTransaction{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{},
+		&models.InvoiceSequence{}, &models.TaxRate{}, &models.Promotion{}, &models.PromotionRedemption{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+// accountBalance is the debit balance of a ledger account in one currency.
+func accountBalance(db *gorm.DB, account models.LedgerAccount, currency string) int64 {
+	var balance int64
+	db.Model(&models.JournalLine{}).
+		Select("COALESCE(SUM(debit) - SUM(credit), 0)").
+		Where("account = ? AND currency = ?", account, currency).
+		Scan(&balance)
+	return balance
+}
+
+// assertLedgerInvariants checks that the ledger balances and agrees with the
+// payment, refund and wallet tables.
+func assertLedgerInvariants(t *testing.T, db *gorm.DB, currency string) {
+	t.Helper()
+
+	var unbalanced int64
+	db.Raw(`SELECT COUNT(*) FROM (SELECT entry_id FROM journal_lines GROUP BY entry_id, currency HAVING SUM(debit) != SUM(credit)) AS entries`).Scan(&unbalanced)
+	assert.Equal(t, int64(0), unbalanced, "every entry balances")
+
+	var oneSided int64
+	db.Model(&models.JournalLine{}).Where("(debit = 0) = (credit = 0) OR debit < 0 OR credit < 0").Count(&oneSided)
+	assert.Equal(t, int64(0), oneSided, "every line is either a debit or a credit")
+
+	balances, err := NewLedgerService(db).TrialBalance()
+	assert.NoError(t, err)
+	for _, balance := range balances {
+		assert.True(t, balance.Balanced, "trial balance in %s", balance.Currency)
+	}
+
+	assert.Equal(t, int64(0), accountBalance(db, models.AccountReceivables, currency), "every sale is settled")
+
+	var wallets int64
+	db.Model(&models.Wallet{}).Select("COALESCE(SUM(balance_amount), 0)").Where("balance_currency = ?", currency).Scan(&wallets)
+	assert.Equal(t, -wallets, accountBalance(db, models.AccountCustomerWallets, currency), "wallet liability matches wallet balances")
+
+	var tax int64
+	db.Model(&models.Payment{}).Select("COALESCE(SUM(tax_amount_amount), 0)").Where("amount_currency = ?", currency).Scan(&tax)
+	assert.Equal(t, -tax, accountBalance(db, models.AccountTaxPayable, currency), "tax owed matches taxed payments")
+
+	var refunded int64
+	db.Model(&models.Refund{}).Select("COALESCE(SUM(amount_amount), 0)").Where("amount_currency = ?", currency).Scan(&refunded)
+	assert.Equal(t, refunded, accountBalance(db, models.AccountRefunds, currency), "refunds account matches refunds")
+
+	var payments, paymentEntries, refunds, refundEntries int64
+	db.Model(&models.Payment{}).Count(&payments)
+	db.Model(&models.JournalEntry{}).Where("reference LIKE ?", "payment:%").Count(&paymentEntries)
+	db.Model(&models.Refund{}).Count(&refunds)
+	db.Model(&models.JournalEntry{}).Where("refund_id IS NOT NULL").Count(&refundEntries)
+	assert.Equal(t, payments, paymentEntries, "one entry per payment")
+	assert.Equal(t, refunds, refundEntries, "one entry per refund")
+}
+
+func TestLedger_Invariants(t *testing.T) {
+	db := setupLedgerTestDB()
+	cfg := bookingTestConfig()
+	bookingService := NewBookingService(db, cfg)
+	paymentService := NewPaymentService(db)
+	walletService := NewWalletService(db, cfg)
+	orderService := NewOrderService(db, cfg)
+	splitService := NewSplitService(db)
+	membershipService := NewMembershipService(db, cfg)
+	promotionService := NewPromotionService(db, cfg)
+	taxService := NewTaxService(db)
+
+	_, err := taxService.CreateTaxRate(CreateTaxRateRequest{Name: "VAT", Rate: 1100})
+	assert.NoError(t, err)
+	_, err = promotionService.CreatePromotion(CreatePromotionRequest{Code: "SAVE20", DiscountType: models.DiscountFixed, DiscountValue: 20000})
+	assert.NoError(t, err)
+
+	player := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	friend := models.User{Email: "friend@example.com", Name: "Friend", Role: models.RoleUser}
+	db.Create(&player)
+	db.Create(&friend)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	slot := func(from, hours int) CreateBookingRequest {
+		return CreateBookingRequest{FieldID: field.ID, StartTime: startTime.Add(time.Duration(from) * time.Hour), EndTime: startTime.Add(time.Duration(from+hours) * time.Hour)}
+	}
+
+	// Wallet top-up and a membership bought from it
+	_, err = walletService.TopUp(player.ID, TopUpRequest{Amount: 1000000, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	plan, err := membershipService.CreatePlan(CreatePlanRequest{Name: "Gold", Type: models.PlanMembership, Price: 200000, DurationDays: 30, DiscountPercent: 10})
+	assert.NoError(t, err)
+	_, err = membershipService.Subscribe(player.ID, SubscribeRequest{PlanID: plan.ID, PaymentMethod: models.PaymentMethodWallet})
+	assert.NoError(t, err)
+
+	// A discounted booking paid from the wallet
+	req := slot(0, 2)
+	req.PromoCode = "SAVE20"
+	booking, err := bookingService.CreateBooking(player.ID, req)
+	assert.NoError(t, err)
+	_, err = paymentService.ProcessPayment(player.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: models.PaymentMethodWallet})
+	assert.NoError(t, err)
+
+	// A card booking rescheduled to a longer slot, then partly refunded
+	booking, err = bookingService.CreateBooking(player.ID, slot(3, 1))
+	assert.NoError(t, err)
+	payment, err := paymentService.ProcessPayment(player.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	_, err = bookingService.RescheduleBooking(player.ID, booking.ID, RescheduleBookingRequest{StartTime: slot(3, 2).StartTime, EndTime: slot(3, 2).EndTime})
+	assert.NoError(t, err)
+	_, err = paymentService.RefundPayment(1, payment.ID, RefundRequest{Amount: 50000, Reason: "lights out"})
+	assert.NoError(t, err)
+	_, err = paymentService.RefundPayment(1, payment.ID, RefundRequest{Amount: 25000, Reason: "goodwill", ToWallet: true})
+	assert.NoError(t, err)
+
+	// An order paid by card
+	order, err := orderService.CreateOrder(player.ID, CreateOrderRequest{Items: []CreateBookingRequest{slot(6, 1), slot(7, 1)}})
+	assert.NoError(t, err)
+	_, err = paymentService.ProcessPayment(player.ID, CreatePaymentRequest{OrderID: order.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	// A split booking where the owner is charged the unpaid share
+	booking, err = bookingService.CreateBooking(player.ID, slot(9, 1))
+	assert.NoError(t, err)
+	half := booking.TotalPrice.Amount / 2
+	_, err = splitService.CreateSplit(player.ID, booking.ID, CreateSplitRequest{
+		Deadline:      time.Now().Add(time.Hour),
+		OnDeadline:    models.SplitChargeOwner,
+		PaymentMethod: "credit_card",
+		Participants: []SplitParticipant{
+			{UserID: friend.ID, Amount: half},
+			{UserID: player.ID, Amount: booking.TotalPrice.Amount - half},
+		},
+	})
+	assert.NoError(t, err)
+	_, err = paymentService.ProcessPayment(friend.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	db.Model(&models.PaymentSplit{}).Where("booking_id = ?", booking.ID).Update("deadline", time.Now().Add(-time.Minute))
+	_, err = splitService.ProcessDeadlines()
+	assert.NoError(t, err)
+
+	// Goodwill wallet credit and a chargeback
+	_, err = walletService.AdjustBalance(1, player.ID, WalletAdjustmentRequest{Amount: 10000, Reason: "sorry"})
+	assert.NoError(t, err)
+	_, err = walletService.AdjustBalance(1, player.ID, WalletAdjustmentRequest{Amount: -5000, Reason: "chargeback"})
+	assert.NoError(t, err)
+
+	assertLedgerInvariants(t, db, "IDR")
+
+	assert.Equal(t, int64(-200000), accountBalance(db, models.AccountPlanRevenue, "IDR"))
+	assert.Greater(t, accountBalance(db, models.AccountDiscounts, "IDR"), int64(20000), "promo and membership discounts")
+
+	balances, err := NewLedgerService(db).TrialBalance()
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+	assert.Equal(t, balances[0].TotalDebit, balances[0].TotalCredit)
+}
+
+func TestLedger_Atomicity(t *testing.T) {
+	db := setupLedgerTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	// The wallet is empty, so the payment and its journal entry roll back
+	_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: models.PaymentMethodWallet})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	var entries int64
+	db.Model(&models.JournalEntry{}).Count(&entries)
+	assert.Equal(t, int64(0), entries)
+
+	unbalanced := newJournal("test", "unbalanced")
+	unbalanced.entry.Lines = []models.JournalLine{
+		{Account: models.AccountReceivables, Currency: "IDR", Debit: 100},
+		{Account: models.AccountVenueRevenue, Currency: "IDR", Credit: 90},
+	}
+	assert.ErrorIs(t, postJournal(db, unbalanced), ErrUnbalancedEntry)
+
+	mixed := newJournal("test", "mixed currencies")
+	mixed.entry.Lines = []models.JournalLine{
+		{Account: models.AccountReceivables, Currency: "IDR", Debit: 100},
+		{Account: models.AccountVenueRevenue, Currency: "USD", Credit: 100},
+	}
+	assert.ErrorIs(t, postJournal(db, mixed), ErrUnbalancedEntry)
+
+	db.Model(&models.JournalEntry{}).Count(&entries)
+	assert.Equal(t, int64(0), entries)
+}
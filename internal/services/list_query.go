@@ -0,0 +1,235 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+var ErrInvalidListQuery = errors.New("invalid list query")
+
+var errInvalidCursor = fmt.Errorf("%w: invalid cursor", ErrInvalidListQuery)
+
+// ListQuery pages, sorts and filters a list. A list is read a page at a
+// time by Page and PerPage, or from the Cursor a previous page returned,
+// which keeps its place while rows are added. Sort names a sort key of the
+// list, prefixed with - for descending order. Status takes a comma separated
+// list of statuses. From and To bound the list's time, given as RFC 3339
+// times or as dates in the Timezone (UTC by default), with To taking in the
+// whole day. Upcoming keeps what has not ended yet. A list rejects the
+// filters it has no use for.
+type ListQuery struct {
+	Page     int    `query:"page"`
+	PerPage  int    `query:"per_page"`
+	Cursor   string `query:"cursor"`
+	Sort     string `query:"sort"`
+	Status   string `query:"status"`
+	From     string `query:"from"`
+	To       string `query:"to"`
+	Timezone string `query:"tz"`
+	Upcoming bool   `query:"upcoming"`
+}
+
+// listSpec says how a list may be sorted and filtered: the column behind
+// each sort key, the sort used when none is asked for, and the columns the
+// status, time range and upcoming filters apply to. A list without one of
+// these columns does not support that filter.
+type listSpec struct {
+	sorts       map[string]string
+	defaultSort string
+	status      string
+	time        string
+	upcoming    string
+}
+
+// listCursor marks the last row of a page: its ID and the value it was
+// sorted by.
+type listCursor struct {
+	Sort  string          `json:"s"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// list reads one page of query into dest, a pointer to a slice of models,
+// and says where the page sits in the list. Rows are ordered by the sort
+// column and then by ID, so every row has a fixed place for cursors to
+// point at. Offset pages are counted; cursor pages are not, so they stay
+// cheap however long the list gets.
+func (q ListQuery) list(query *gorm.DB, spec listSpec, dest interface{}) (*utils.Pagination, error) {
+	perPage := q.PerPage
+	if perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+
+	sort := q.Sort
+	if sort == "" {
+		sort = spec.defaultSort
+	}
+	descending := strings.HasPrefix(sort, "-")
+	column, ok := spec.sorts[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidListQuery, strings.TrimPrefix(sort, "-"))
+	}
+	direction, after := "ASC", ">"
+	if descending {
+		direction, after = "DESC", "<"
+	}
+
+	query, err := q.filter(query.Session(&gorm.Session{}), spec)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &gorm.Statement{DB: query}
+	if err := stmt.Parse(dest); err != nil {
+		return nil, err
+	}
+	sortField := stmt.Schema.LookUpField(column)
+	if sortField == nil {
+		return nil, fmt.Errorf("cannot sort by %q", column)
+	}
+
+	page := &utils.Pagination{PerPage: perPage}
+	ordered := query.Order(column + " " + direction).Order("id " + direction)
+	if q.Cursor != "" {
+		cursor, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cursor.Sort != sort {
+			return nil, fmt.Errorf("%w: cursor belongs to another sort order", ErrInvalidListQuery)
+		}
+		value := reflect.New(sortField.FieldType)
+		if err := json.Unmarshal(cursor.Value, value.Interface()); err != nil {
+			return nil, errInvalidCursor
+		}
+		ordered = ordered.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, after),
+			value.Elem().Interface(), value.Elem().Interface(), cursor.ID)
+	} else {
+		page.Page = q.Page
+		if page.Page < 1 {
+			page.Page = 1
+		}
+		var total int64
+		if err := query.Model(dest).Count(&total).Error; err != nil {
+			return nil, err
+		}
+		page.Total = &total
+		page.TotalPages = int((total + int64(perPage) - 1) / int64(perPage))
+		ordered = ordered.Offset((page.Page - 1) * perPage)
+	}
+
+	// One row more than the page holds tells whether there is another page
+	if err := ordered.Limit(perPage + 1).Find(dest).Error; err != nil {
+		return nil, err
+	}
+	rows := reflect.ValueOf(dest).Elem()
+	if rows.IsNil() {
+		rows.Set(reflect.MakeSlice(rows.Type(), 0, 0))
+	}
+	if rows.Len() > perPage {
+		rows.Set(rows.Slice(0, perPage))
+		page.HasMore = true
+
+		last := rows.Index(perPage - 1)
+		value, _ := sortField.ValueOf(query.Statement.Context, last)
+		id, _ := stmt.Schema.PrioritizedPrimaryField.ValueOf(query.Statement.Context, last)
+		page.NextCursor, err = encodeCursor(sort, value, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// filter applies the status, time range and upcoming filters.
+func (q ListQuery) filter(query *gorm.DB, spec listSpec) (*gorm.DB, error) {
+	unsupported := func(filter string) error {
+		return fmt.Errorf("%w: this list cannot be filtered by %s", ErrInvalidListQuery, filter)
+	}
+
+	if q.Status != "" {
+		if spec.status == "" {
+			return nil, unsupported("status")
+		}
+		var statuses []string
+		for _, status := range strings.Split(q.Status, ",") {
+			statuses = append(statuses, strings.TrimSpace(status))
+		}
+		query = query.Where(spec.status+" IN ?", statuses)
+	}
+
+	if q.From != "" || q.To != "" {
+		if spec.time == "" {
+			return nil, unsupported("from and to")
+		}
+		loc := time.UTC
+		if q.Timezone != "" {
+			var err error
+			if loc, err = time.LoadLocation(q.Timezone); err != nil {
+				return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidListQuery, q.Timezone)
+			}
+		}
+		if q.From != "" {
+			from, err := parseDateBound(q.From, loc, false)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidListQuery, err)
+			}
+			query = query.Where(spec.time+" >= ?", from)
+		}
+		if q.To != "" {
+			to, err := parseDateBound(q.To, loc, true)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidListQuery, err)
+			}
+			query = query.Where(spec.time+" < ?", to)
+		}
+	}
+
+	if q.Upcoming {
+		if spec.upcoming == "" {
+			return nil, unsupported("upcoming")
+		}
+		query = query.Where(spec.upcoming+" > ?", time.Now())
+	}
+	return query, nil
+}
+
+func encodeCursor(sort string, value, id interface{}) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(listCursor{Sort: sort, Value: raw, ID: id.(uint)})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(value string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || len(cursor.Value) == 0 {
+		return nil, errInvalidCursor
+	}
+	return &cursor, nil
+}
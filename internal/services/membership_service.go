@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+type MembershipService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewMembershipService(db *gorm.DB, cfg *config.Config) *MembershipService {
+	return &MembershipService{db: db, cfg: cfg}
+}
+
+func (s *MembershipService) WithContext(ctx context.Context) *MembershipService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type CreatePlanRequest struct {
+	Name              string            `json:"name" validate:"required"`
+	Type              models.PlanType   `json:"type" validate:"required"`
+	Price             int64             `json:"price" validate:"required,gt=0"`
+	Currency          string            `json:"currency"`
+	DurationDays      int               `json:"duration_days" validate:"required,gt=0"`
+	DiscountPercent   int               `json:"discount_percent"`
+	BookingWindowDays int               `json:"booking_window_days"`
+	Quotas            models.PlanQuotas `json:"quotas"`
+	Sessions          int               `json:"sessions"`
+}
+
+type SubscribeRequest struct {
+	PlanID        uint   `json:"plan_id" validate:"required"`
+	PaymentMethod string `json:"payment_method" validate:"required"`
+	AutoRenew     bool   `json:"auto_renew"`
+}
+
+func (s *MembershipService) CreatePlan(req CreatePlanRequest) (*models.MembershipPlan, error) {
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.Price <= 0 || req.DurationDays <= 0 {
+		return nil, errors.New("price and duration_days must be positive")
+	}
+	currency, err := currencyOrDefault(s.cfg, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case models.PlanMembership:
+		if req.DiscountPercent < 0 || req.DiscountPercent > 100 {
+			return nil, errors.New("discount_percent must be between 0 and 100")
+		}
+		if req.BookingWindowDays < 0 {
+			return nil, errors.New("booking_window_days cannot be negative")
+		}
+		if q := req.Quotas; q.MaxActiveBookings < 0 || q.MaxWeeklyFieldHours < 0 || q.MaxWeeklySportHours < 0 || q.MaxWeeklyPrimeTime < 0 {
+			return nil, errors.New("quotas cannot be negative")
+		}
+		if req.Sessions != 0 {
+			return nil, errors.New("memberships do not have sessions")
+		}
+	case models.PlanPackage:
+		if req.Sessions <= 0 {
+			return nil, errors.New("packages need a positive number of sessions")
+		}
+		if req.DiscountPercent != 0 || req.BookingWindowDays != 0 || req.Quotas != (models.PlanQuotas{}) {
+			return nil, errors.New("packages do not give discounts, booking windows or quotas")
+		}
+	default:
+		return nil, errors.New("type must be membership or package")
+	}
+
+	plan := models.MembershipPlan{
+		Name:              req.Name,
+		Type:              req.Type,
+		Price:             models.NewMoney(req.Price, currency),
+		DurationDays:      req.DurationDays,
+		DiscountPercent:   req.DiscountPercent,
+		BookingWindowDays: req.BookingWindowDays,
+		Quotas:            req.Quotas,
+		Sessions:          req.Sessions,
+		Active:            true,
+	}
+	if err := s.db.Create(&plan).Error; err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+var planListSpec = listSpec{
+	sorts: map[string]string{
+		"id":            "id",
+		"name":          "name",
+		"price":         "price_amount",
+		"duration_days": "duration_days",
+		"created_at":    "created_at",
+	},
+	defaultSort: "price",
+}
+
+func (s *MembershipService) GetPlans(q ListQuery) ([]models.MembershipPlan, *utils.Pagination, error) {
+	var plans []models.MembershipPlan
+	page, err := q.list(s.db.Where("active = ?", true), planListSpec, &plans)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plans, page, nil
+}
+
+// Subscribe sells a plan to the user, charging the wallet or the mock
+// gateway. Packages never renew.
+func (s *MembershipService) Subscribe(userID uint, req SubscribeRequest) (*models.Subscription, error) {
+	var plan models.MembershipPlan
+	if err := s.db.Where("active = ?", true).First(&plan, req.PlanID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("plan not found")
+		}
+		return nil, err
+	}
+	if req.PaymentMethod == "" {
+		return nil, errors.New("payment_method is required")
+	}
+
+	now := time.Now()
+	if plan.Type == models.PlanMembership {
+		var count int64
+		s.db.Model(&models.Subscription{}).
+			Where("user_id = ? AND plan_id = ? AND status = ? AND ends_at > ?", userID, plan.ID, models.SubscriptionActive, now).
+			Count(&count)
+		if count > 0 {
+			return nil, errors.New("you already have this membership")
+		}
+	}
+
+	subscription := models.Subscription{
+		UserID:            userID,
+		PlanID:            plan.ID,
+		Status:            models.SubscriptionActive,
+		StartsAt:          now,
+		EndsAt:            now.AddDate(0, 0, plan.DurationDays),
+		AutoRenew:         req.AutoRenew && plan.Type == models.PlanMembership,
+		SessionsRemaining: plan.Sessions,
+		PaymentMethod:     req.PaymentMethod,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&subscription).Error; err != nil {
+			return err
+		}
+		transactionID, err := chargePlan(tx, &subscription, plan, 0)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&subscription).Update("transaction_id", transactionID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.Plan = plan
+	return &subscription, nil
+}
+
+// subscriptionListSpec lists subscriptions newest first. Upcoming ones have
+// not run out yet.
+var subscriptionListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at", "ends_at": "ends_at"},
+	defaultSort: "-created_at",
+	status:      "status",
+	time:        "created_at",
+	upcoming:    "ends_at",
+}
+
+func (s *MembershipService) GetUserSubscriptions(userID uint, q ListQuery) ([]models.Subscription, *utils.Pagination, error) {
+	var subscriptions []models.Subscription
+	page, err := q.list(s.db.Preload("Plan").Where("user_id = ?", userID), subscriptionListSpec, &subscriptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return subscriptions, page, nil
+}
+
+// CancelRenewal stops a membership from renewing. It stays valid until it
+// ends.
+func (s *MembershipService) CancelRenewal(userID, id uint) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := s.db.Preload("Plan").Where("user_id = ?", userID).First(&subscription, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("subscription not found")
+		}
+		return nil, err
+	}
+	if !subscription.AutoRenew {
+		return nil, errors.New("subscription does not renew")
+	}
+
+	if err := s.db.Model(&subscription).Update("auto_renew", false).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// RenewSubscriptions processes every active subscription that has ended:
+// memberships set to renew are charged for another term, everything else
+// expires. A renewal that cannot be charged expires the membership. It
+// returns the number of renewals and expiries.
+func (s *MembershipService) RenewSubscriptions() (int, int, error) {
+	var due []models.Subscription
+	if err := s.db.Preload("Plan").
+		Where("status = ? AND ends_at <= ?", models.SubscriptionActive, time.Now()).
+		Find(&due).Error; err != nil {
+		return 0, 0, err
+	}
+
+	renewed, expired := 0, 0
+	for i := range due {
+		subscription := &due[i]
+
+		if subscription.AutoRenew && subscription.Plan.Active {
+			err := s.db.Transaction(func(tx *gorm.DB) error {
+				renewal := subscription.RenewalCount + 1
+				transactionID, err := chargePlan(tx, subscription, subscription.Plan, renewal)
+				if err != nil {
+					return err
+				}
+				return tx.Model(subscription).Updates(map[string]interface{}{
+					"starts_at":      subscription.EndsAt,
+					"ends_at":        subscription.EndsAt.AddDate(0, 0, subscription.Plan.DurationDays),
+					"renewal_count":  renewal,
+					"transaction_id": transactionID,
+				}).Error
+			})
+			if err == nil {
+				renewed++
+				continue
+			}
+			if !errors.Is(err, ErrInsufficientBalance) {
+				return renewed, expired, err
+			}
+		}
+
+		if err := s.db.Model(subscription).Updates(map[string]interface{}{
+			"status":     models.SubscriptionExpired,
+			"auto_renew": false,
+		}).Error; err != nil {
+			return renewed, expired, err
+		}
+		expired++
+	}
+
+	return renewed, expired, nil
+}
+
+// chargePlan takes the plan price from the subscriber's wallet or through
+// the mock gateway, books it as plan revenue and returns the transaction
+// reference. Renewal is the number of the term being paid for, 0 for the
+// first one, so that every term has its own journal entry.
+func chargePlan(tx *gorm.DB, subscription *models.Subscription, plan models.MembershipPlan, renewal int) (string, error) {
+	j := newJournal(fmt.Sprintf("subscription:%d:%d", subscription.ID, renewal), fmt.Sprintf("%s plan", plan.Name))
+	j.entry.SubscriptionID = &subscription.ID
+
+	// Mock payment processing
+	reference := fmt.Sprintf("TRX-P%d-%d-%d", subscription.ID, renewal, time.Now().Unix())
+	if subscription.PaymentMethod == models.PaymentMethodWallet {
+		entry, err := postWalletTransaction(tx, subscription.UserID, walletEntry{
+			Type:        models.WalletPlanPurchase,
+			Amount:      plan.Price.Neg(),
+			Description: plan.Name,
+		})
+		if err != nil {
+			return "", err
+		}
+		reference = entry.Reference
+		j.entry.WalletTransactionID = &entry.ID
+	}
+
+	j.post(settlementAccount(subscription.PaymentMethod), models.AccountPlanRevenue, plan.Price)
+	if err := postJournal(tx, j); err != nil {
+		return "", err
+	}
+	return reference, nil
+}
+
+// activeMembership returns the user's current membership with the best
+// discount, or nil if there is none.
+func activeMembership(tx *gorm.DB, userID uint) *models.Subscription {
+	var subscription models.Subscription
+	now := time.Now()
+	result := tx.Preload("Plan").
+		Joins("JOIN membership_plans ON membership_plans.id = subscriptions.plan_id").
+		Where("subscriptions.user_id = ? AND subscriptions.status = ? AND subscriptions.starts_at <= ? AND subscriptions.ends_at > ? AND membership_plans.type = ?",
+			userID, models.SubscriptionActive, now, now, models.PlanMembership).
+		Order("membership_plans.discount_percent DESC").
+		Limit(1).
+		Find(&subscription)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil
+	}
+	return &subscription
+}
+
+// usablePackage returns the user's package that expires soonest and still
+// has enough sessions, or nil if there is none.
+func usablePackage(tx *gorm.DB, userID uint, sessions int) *models.Subscription {
+	var subscription models.Subscription
+	now := time.Now()
+	result := tx.Preload("Plan").
+		Joins("JOIN membership_plans ON membership_plans.id = subscriptions.plan_id").
+		Where("subscriptions.user_id = ? AND subscriptions.status = ? AND subscriptions.ends_at > ? AND subscriptions.sessions_remaining >= ? AND membership_plans.type = ?",
+			userID, models.SubscriptionActive, now, sessions, models.PlanPackage).
+		Order("subscriptions.ends_at ASC").
+		Limit(1).
+		Find(&subscription)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil
+	}
+	return &subscription
+}
+
+// consumeSessions takes sessions off a package, failing if another booking
+// used them up in the meantime.
+func consumeSessions(tx *gorm.DB, subscriptionID uint, sessions int) error {
+	result := tx.Model(&models.Subscription{}).
+		Where("id = ? AND sessions_remaining >= ?", subscriptionID, sessions).
+		Update("sessions_remaining", gorm.Expr("sessions_remaining - ?", sessions))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("not enough package sessions left")
+	}
+	return tx.Model(&models.Subscription{}).
+		Where("id = ? AND sessions_remaining = 0", subscriptionID).
+		Update("status", models.SubscriptionExhausted).Error
+}
+
+// restoreSessions gives sessions back to a package that has not expired.
+func restoreSessions(tx *gorm.DB, subscriptionID uint, sessions int) error {
+	return tx.Model(&models.Subscription{}).
+		Where("id = ? AND status IN ? AND ends_at > ?", subscriptionID,
+			[]models.SubscriptionStatus{models.SubscriptionActive, models.SubscriptionExhausted}, time.Now()).
+		Updates(map[string]interface{}{
+			"sessions_remaining": gorm.Expr("sessions_remaining + ?", sessions),
+			"status":             models.SubscriptionActive,
+		}).Error
+}
+
+// sessionsFor is the number of package sessions a slot uses: one per
+// started hour.
+func sessionsFor(start, end time.Time) int {
+	return int(math.Ceil(end.Sub(start).Hours()))
+}
+
+// checkBookingWindow rejects slots further ahead than the user may book.
+// Members whose plan opens bookings earlier get the longer window. A zero
+// window means bookings are open indefinitely.
+func checkBookingWindow(tx *gorm.DB, cfg *config.Config, userID uint, start time.Time) error {
+	window := cfg.Booking.AdvanceWindow
+	if window == 0 {
+		return nil
+	}
+	if membership := activeMembership(tx, userID); membership != nil {
+		if planWindow := time.Duration(membership.Plan.BookingWindowDays) * 24 * time.Hour; planWindow > window {
+			window = planWindow
+		}
+	}
+
+	if start.After(time.Now().Add(window)) {
+		return newBookingRuleError("advance_window", "start_time", "bookings open %d days in advance", int(window.Hours()/24))
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupMembershipTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.WaitlistEntry{}, &models.PaymentSplit{},
+		&models.MembershipPlan{}, &models.Subscription{}, &models.Wallet{}, &models.WalletTransaction{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestMembershipService_CreatePlan(t *testing.T) {
+	db := setupMembershipTestDB()
+	membershipService := NewMembershipService(db, bookingTestConfig())
+
+	tests := []struct {
+		name    string
+		request CreatePlanRequest
+		wantErr bool
+	}{
+		{
+			name:    "Unknown type",
+			request: CreatePlanRequest{Name: "Gold", Type: "lifetime", Price: 500000, DurationDays: 30},
+			wantErr: true,
+		},
+		{
+			name:    "Package without sessions",
+			request: CreatePlanRequest{Name: "Punch card", Type: models.PlanPackage, Price: 900000, DurationDays: 90},
+			wantErr: true,
+		},
+		{
+			name:    "Membership with sessions",
+			request: CreatePlanRequest{Name: "Gold", Type: models.PlanMembership, Price: 500000, DurationDays: 30, Sessions: 10},
+			wantErr: true,
+		},
+		{
+			name:    "Successful membership",
+			request: CreatePlanRequest{Name: "Gold", Type: models.PlanMembership, Price: 500000, DurationDays: 30, DiscountPercent: 15, BookingWindowDays: 60},
+			wantErr: false,
+		},
+		{
+			name:    "Successful package",
+			request: CreatePlanRequest{Name: "Punch card", Type: models.PlanPackage, Price: 900000, DurationDays: 90, Sessions: 10},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := membershipService.CreatePlan(tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, result.Active)
+			}
+		})
+	}
+}
+
+func TestBookingService_MembershipPricing(t *testing.T) {
+	db := setupMembershipTestDB()
+	membershipService := NewMembershipService(db, bookingTestConfig())
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	member := models.User{Email: "member@example.com", Name: "Member", Role: models.RoleUser}
+	db.Create(&member)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	plan, err := membershipService.CreatePlan(CreatePlanRequest{Name: "Gold", Type: models.PlanMembership, Price: 500000, DurationDays: 30, DiscountPercent: 15, BookingWindowDays: 60})
+	assert.NoError(t, err)
+
+	farAhead := time.Now().Add(45 * 24 * time.Hour).Truncate(time.Hour)
+	_, err = bookingService.CreateBooking(member.ID, CreateBookingRequest{FieldID: field.ID, StartTime: farAhead, EndTime: farAhead.Add(time.Hour)})
+	assert.Error(t, err, "non-members are limited to the default window")
+
+	_, err = membershipService.Subscribe(member.ID, SubscribeRequest{PlanID: plan.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	booking, err := bookingService.CreateBooking(member.ID, CreateBookingRequest{FieldID: field.ID, StartTime: farAhead, EndTime: farAhead.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200000), booking.Subtotal.Amount)
+	assert.Equal(t, int64(30000), booking.PlanDiscount.Amount)
+	assert.Equal(t, int64(170000), booking.TotalPrice.Amount)
+	assert.Equal(t, models.StatusPending, booking.Status)
+}
+
+func TestBookingService_PackageSessions(t *testing.T) {
+	db := setupMembershipTestDB()
+	membershipService := NewMembershipService(db, bookingTestConfig())
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	plan, err := membershipService.CreatePlan(CreatePlanRequest{Name: "Punch card", Type: models.PlanPackage, Price: 900000, DurationDays: 90, Sessions: 3})
+	assert.NoError(t, err)
+	subscription, err := membershipService.Subscribe(user.ID, SubscribeRequest{PlanID: plan.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour), UsePackage: true})
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusPaid, booking.Status)
+	assert.Equal(t, 2, booking.PackageSessions)
+	assert.Equal(t, int64(0), booking.TotalPrice.Amount)
+
+	_, err = bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime.Add(3 * time.Hour), EndTime: startTime.Add(5 * time.Hour), UsePackage: true})
+	assert.Error(t, err, "only one session left")
+
+	// Cancelled well ahead of time, the sessions come back
+	_, err = bookingService.CancelBooking(user.ID, booking.ID)
+	assert.NoError(t, err)
+	db.First(subscription, subscription.ID)
+	assert.Equal(t, 3, subscription.SessionsRemaining)
+
+	// Too close to the start, they are forfeited
+	soon := time.Now().Add(3 * time.Hour).Truncate(time.Hour)
+	booking, err = bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: soon, EndTime: soon.Add(3 * time.Hour), UsePackage: true})
+	assert.NoError(t, err)
+	db.First(subscription, subscription.ID)
+	assert.Equal(t, models.SubscriptionExhausted, subscription.Status)
+
+	_, err = bookingService.CancelBooking(user.ID, booking.ID)
+	assert.NoError(t, err)
+	db.First(subscription, subscription.ID)
+	assert.Equal(t, 0, subscription.SessionsRemaining)
+}
+
+func TestMembershipService_RenewSubscriptions(t *testing.T) {
+	db := setupMembershipTestDB()
+	membershipService := NewMembershipService(db, bookingTestConfig())
+	walletService := NewWalletService(db, bookingTestConfig())
+
+	plan, err := membershipService.CreatePlan(CreatePlanRequest{Name: "Gold", Type: models.PlanMembership, Price: 500000, DurationDays: 30, DiscountPercent: 15})
+	assert.NoError(t, err)
+
+	var subscriptions []*models.Subscription
+	for i, credit := range []int64{600000, 500000} {
+		user := models.User{Email: []string{"rich@example.com", "broke@example.com"}[i], Name: "Member", Role: models.RoleUser}
+		db.Create(&user)
+		_, err := walletService.TopUp(user.ID, TopUpRequest{Amount: credit, PaymentMethod: "credit_card"})
+		assert.NoError(t, err)
+
+		subscription, err := membershipService.Subscribe(user.ID, SubscribeRequest{PlanID: plan.ID, PaymentMethod: models.PaymentMethodWallet, AutoRenew: true})
+		assert.NoError(t, err)
+		db.Model(subscription).Update("ends_at", time.Now().Add(-time.Minute))
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	renewed, expired, err := membershipService.RenewSubscriptions()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, renewed, "neither wallet can cover a second month")
+	assert.Equal(t, 2, expired)
+
+	_, err = walletService.TopUp(subscriptions[0].UserID, TopUpRequest{Amount: 500000, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	db.Model(subscriptions[0]).Updates(map[string]interface{}{"status": models.SubscriptionActive, "auto_renew": true})
+
+	renewed, _, err = membershipService.RenewSubscriptions()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, renewed)
+
+	db.First(subscriptions[0], subscriptions[0].ID)
+	assert.Equal(t, 1, subscriptions[0].RenewalCount)
+	assert.True(t, subscriptions[0].EndsAt.After(time.Now()))
+
+	// The first term and the renewal are journaled apart
+	var references []string
+	db.Model(&models.JournalEntry{}).Where("subscription_id = ?", subscriptions[0].ID).Order("id").Pluck("reference", &references)
+	assert.Equal(t, []string{fmt.Sprintf("subscription:%d:0", subscriptions[0].ID), fmt.Sprintf("subscription:%d:1", subscriptions[0].ID)}, references)
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationService struct {
+	db *gorm.DB
+}
+
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+func (s *NotificationService) WithContext(ctx context.Context) *NotificationService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+var notificationListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at"},
+	defaultSort: "-created_at",
+	time:        "created_at",
+}
+
+func (s *NotificationService) GetUserNotifications(userID uint, unreadOnly bool, q ListQuery) ([]models.Notification, *utils.Pagination, error) {
+	var notifications []models.Notification
+	query := s.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	page, err := q.list(query, notificationListSpec, &notifications)
+	if err != nil {
+		return nil, nil, err
+	}
+	return notifications, page, nil
+}
+
+func (s *NotificationService) MarkAsRead(userID, id uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := s.db.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notification not found")
+		}
+		return nil, err
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := s.db.Model(&notification).Update("read_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &notification, nil
+}
+
+// notify stores a notification. Notifications carrying a dedupe key are only
+// ever created once, so concurrent job runs cannot alert a user twice.
+func notify(db *gorm.DB, notification *models.Notification) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(notification).Error
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+type OrderService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewOrderService(db *gorm.DB, cfg *config.Config) *OrderService {
+	return &OrderService{db: db, cfg: cfg}
+}
+
+func (s *OrderService) WithContext(ctx context.Context) *OrderService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type CreateOrderRequest struct {
+	Items []CreateBookingRequest `json:"items" validate:"required,min=1,dive"`
+}
+
+// CreateOrder holds every requested slot in one transaction. If any slot is
+// unavailable, nothing is held and the whole order fails.
+func (s *OrderService) CreateOrder(userID uint, req CreateOrderRequest) (*models.Order, error) {
+	if len(req.Items) == 0 {
+		return nil, errors.New("order must contain at least one slot")
+	}
+
+	order := models.Order{
+		UserID: userID,
+		Status: models.OrderPending,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&order).Error; err != nil {
+			return err
+		}
+
+		var total models.Money
+		for i, item := range req.Items {
+			// Slots held earlier in this transaction count as taken, so
+			// overlapping items within the same order are rejected too
+			if item.UsePackage {
+				return fmt.Errorf("slot %d: package sessions cannot be used in an order", i+1)
+			}
+			booking, err := holdSlot(tx, s.cfg, userID, item, nil)
+			if err != nil {
+				var ruleErr *BookingRuleError
+				if errors.As(err, &ruleErr) {
+					for j := range ruleErr.Violations {
+						ruleErr.Violations[j].Field = fmt.Sprintf("items[%d].%s", i, ruleErr.Violations[j].Field)
+					}
+				}
+				return fmt.Errorf("slot %d: %w", i+1, err)
+			}
+			if err := tx.Model(booking).Update("order_id", order.ID).Error; err != nil {
+				return err
+			}
+			// One payment settles the order, so its slots share a currency
+			if !total.SameCurrency(booking.TotalPrice) {
+				return fmt.Errorf("slot %d: %w: the order is in %s", i+1, models.ErrCurrencyMismatch, total.Currency)
+			}
+			total = total.Add(booking.TotalPrice)
+		}
+
+		order.TotalAmount = total
+		return tx.Model(&order).Updates(map[string]interface{}{"total_amount_amount": total.Amount, "total_amount_currency": total.Currency}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetOrderByID(userID, order.ID)
+}
+
+var orderListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at", "total_amount": "total_amount_amount"},
+	defaultSort: "-created_at",
+	status:      "status",
+	time:        "created_at",
+}
+
+func (s *OrderService) GetUserOrders(userID uint, q ListQuery) ([]models.Order, *utils.Pagination, error) {
+	var orders []models.Order
+	page, err := q.list(s.db.Preload("Bookings").Where("user_id = ?", userID), orderListSpec, &orders)
+	if err != nil {
+		return nil, nil, err
+	}
+	return orders, page, nil
+}
+
+func (s *OrderService) GetOrderByID(userID, id uint) (*models.Order, error) {
+	var order models.Order
+	if err := s.db.Preload("Bookings.Field").Preload("Payment").Where("user_id = ?", userID).First(&order, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("order not found")
+		}
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CancelOrder releases every slot of an unpaid order.
+func (s *OrderService) CancelOrder(userID, id uint) (*models.Order, error) {
+	order, err := s.GetOrderByID(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderPending {
+		return nil, fmt.Errorf("order is %s and cannot be cancelled", order.Status)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := transitionBookings(tx, models.StatusCancelled, &userID, "order cancelled",
+			"order_id = ? AND status = ?", order.ID, models.StatusPending); err != nil {
+			return err
+		}
+		return tx.Model(order).Update("status", models.OrderCancelled).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetOrderByID(userID, id)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupOrderTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Order{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.WaitlistEntry{}, &models.PaymentSplit{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestOrderService_CreateOrder(t *testing.T) {
+	db := setupOrderTestDB()
+	orderService := NewOrderService(db, bookingTestConfig())
+
+	user := models.User{Email: "organiser@example.com", Name: "Organiser", Role: models.RoleUser}
+	db.Create(&user)
+
+	var courts []models.Field
+	for _, name := range []string{"Court 1", "Court 2", "Court 3"} {
+		court := models.Field{Name: name, PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+		db.Create(&court)
+		courts = append(courts, court)
+	}
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	endTime := startTime.Add(2 * time.Hour)
+
+	// Court 3 is already taken by someone else
+	db.Create(&models.Booking{UserID: user.ID + 1, FieldID: courts[2].ID, StartTime: startTime, EndTime: endTime, Status: models.StatusPaid})
+
+	tests := []struct {
+		name    string
+		request CreateOrderRequest
+		wantErr bool
+	}{
+		{
+			name:    "Empty order",
+			request: CreateOrderRequest{},
+			wantErr: true,
+		},
+		{
+			name: "One conflicting slot fails the whole order",
+			request: CreateOrderRequest{Items: []CreateBookingRequest{
+				{FieldID: courts[0].ID, StartTime: startTime, EndTime: endTime},
+				{FieldID: courts[2].ID, StartTime: startTime, EndTime: endTime},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Overlapping slots within the order",
+			request: CreateOrderRequest{Items: []CreateBookingRequest{
+				{FieldID: courts[0].ID, StartTime: startTime, EndTime: endTime},
+				{FieldID: courts[0].ID, StartTime: startTime.Add(time.Hour), EndTime: endTime.Add(time.Hour)},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Successful order",
+			request: CreateOrderRequest{Items: []CreateBookingRequest{
+				{FieldID: courts[0].ID, StartTime: startTime, EndTime: endTime},
+				{FieldID: courts[1].ID, StartTime: startTime, EndTime: endTime},
+			}},
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := orderService.CreateOrder(user.ID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, result.Bookings, 2)
+				assert.Equal(t, int64(400000), result.TotalAmount.Amount)
+				assert.Equal(t, models.OrderPending, result.Status)
+			}
+		})
+	}
+
+	// Failed orders must not leave any holds behind
+	var held int64
+	db.Model(&models.Booking{}).Where("user_id = ?", user.ID).Count(&held)
+	assert.Equal(t, int64(2), held)
+}
+
+func TestOrderService_PayOrder(t *testing.T) {
+	db := setupOrderTestDB()
+	orderService := NewOrderService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+
+	user := models.User{Email: "organiser@example.com", Name: "Organiser", Role: models.RoleUser}
+	db.Create(&user)
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	order, err := orderService.CreateOrder(user.ID, CreateOrderRequest{Items: []CreateBookingRequest{
+		{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)},
+		{FieldID: field.ID, StartTime: startTime.Add(time.Hour), EndTime: startTime.Add(2 * time.Hour)},
+	}})
+	assert.NoError(t, err)
+
+	_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: order.Bookings[0].ID, PaymentMethod: "credit_card"})
+	assert.Error(t, err, "order bookings are paid through the order")
+
+	payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{OrderID: order.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200000), payment.Amount.Amount)
+
+	_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{OrderID: order.ID, PaymentMethod: "credit_card"})
+	assert.Error(t, err)
+
+	var paid int64
+	db.Model(&models.Booking{}).Where("order_id = ? AND status = ?", order.ID, models.StatusPaid).Count(&paid)
+	assert.Equal(t, int64(2), paid)
+
+	_, err = orderService.CancelOrder(user.ID, order.ID)
+	assert.Error(t, err, "paid orders cannot be cancelled as a whole")
+}
+
+func TestOrderService_MixedCurrencies(t *testing.T) {
+	db := setupOrderTestDB()
+	orderService := NewOrderService(db, bookingTestConfig())
+
+	user := models.User{Email: "traveller@example.com", Name: "Traveller", Role: models.RoleUser}
+	db.Create(&user)
+
+	jakarta := models.Field{Name: "Jakarta Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Jakarta"}
+	singapore := models.Field{Name: "Singapore Court", PricePerHour: models.NewMoney(3000, "SGD"), Location: "Singapore"}
+	db.Create(&jakarta)
+	db.Create(&singapore)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	_, err := orderService.CreateOrder(user.ID, CreateOrderRequest{Items: []CreateBookingRequest{
+		{FieldID: jakarta.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)},
+		{FieldID: singapore.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)},
+	}})
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+
+	var held int64
+	db.Model(&models.Booking{}).Count(&held)
+	assert.Equal(t, int64(0), held, "a rejected order holds no slots")
+
+	order, err := orderService.CreateOrder(user.ID, CreateOrderRequest{Items: []CreateBookingRequest{
+		{FieldID: singapore.ID, StartTime: startTime, EndTime: startTime.Add(90 * time.Minute)},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(4500, "SGD"), order.TotalAmount)
+}
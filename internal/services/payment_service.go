@@ -1,12 +1,16 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentService struct {
@@ -17,12 +21,26 @@ func NewPaymentService(db *gorm.DB) *PaymentService {
 	return &PaymentService{db: db}
 }
 
+func (s *PaymentService) WithContext(ctx context.Context) *PaymentService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
 type CreatePaymentRequest struct {
-	BookingID     uint   `json:"booking_id" validate:"required"`
+	BookingID     uint   `json:"booking_id"`
+	OrderID       uint   `json:"order_id"`
 	PaymentMethod string `json:"payment_method" validate:"required"`
 }
 
-func (s *PaymentService) ProcessPayment(req CreatePaymentRequest) (*models.Payment, error) {
+func (s *PaymentService) ProcessPayment(userID uint, req CreatePaymentRequest) (*models.Payment, error) {
+	if (req.BookingID == 0) == (req.OrderID == 0) {
+		return nil, errors.New("either booking_id or order_id is required")
+	}
+	if req.OrderID != 0 {
+		return s.processOrderPayment(userID, req)
+	}
+
 	// Get booking
 	var booking models.Booking
 	if err := s.db.First(&booking, req.BookingID).Error; err != nil {
@@ -32,15 +50,22 @@ func (s *PaymentService) ProcessPayment(req CreatePaymentRequest) (*models.Payme
 		return nil, err
 	}
 
+	if booking.OrderID != nil {
+		return nil, errors.New("booking is part of an order, pay for the order instead")
+	}
+
 	// Check if booking is already paid
-	if booking.Status == models.StatusPaid {
+	if booking.IsPaid() {
 		return nil, errors.New("booking is already paid")
 	}
+	if !booking.Status.CanTransitionTo(models.StatusPaid) {
+		return nil, fmt.Errorf("booking is %s", booking.Status)
+	}
 
-	// Check if payment already exists
-	var existingPayment models.Payment
-	if err := s.db.Where("booking_id = ?", req.BookingID).First(&existingPayment).Error; err == nil {
-		return nil, errors.New("payment already exists for this booking")
+	// Split bookings are paid share by share
+	var split models.PaymentSplit
+	if err := s.db.Where("booking_id = ? AND status = ?", booking.ID, models.SplitOpen).First(&split).Error; err == nil {
+		return s.processSharePayment(userID, &booking, &split, req)
 	}
 
 	// Mock payment processing
@@ -48,8 +73,11 @@ func (s *PaymentService) ProcessPayment(req CreatePaymentRequest) (*models.Payme
 
 	// Create payment
 	payment := models.Payment{
-		BookingID:     req.BookingID,
+		BookingID:     &booking.ID,
+		UserID:        &userID,
 		Amount:        booking.TotalPrice,
+		NetAmount:     booking.NetAmount,
+		TaxAmount:     booking.TaxAmount,
 		Status:        models.PaymentCompleted,
 		PaymentMethod: req.PaymentMethod,
 		TransactionID: transactionID,
@@ -63,14 +91,43 @@ func (s *PaymentService) ProcessPayment(req CreatePaymentRequest) (*models.Payme
 		}
 	}()
 
+	// Lock the booking, so that a concurrent payment for it waits here and
+	// then finds this one
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, booking.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	var existingPayment models.Payment
+	if err := tx.Where("booking_id = ?", booking.ID).First(&existingPayment).Error; err == nil {
+		tx.Rollback()
+		return nil, errors.New("payment already exists for this booking")
+	}
+
 	// Create payment
 	if err := tx.Create(&payment).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	if payment.PaymentMethod == models.PaymentMethodWallet {
+		if err := chargeWallet(tx, &payment, payment.Amount, fmt.Sprintf("Booking %d", booking.ID)); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	invoice, err := issueInvoice(tx, &payment)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := postPaymentJournal(tx, &payment, invoice); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Update booking status
-	if err := tx.Model(&booking).Update("status", models.StatusPaid).Error; err != nil {
+	if err := transitionBooking(tx, &booking, models.StatusPaid, &userID, "paid"); err != nil {
 		tx.Rollback()
 		return nil, err
 	}
@@ -85,3 +142,442 @@ func (s *PaymentService) ProcessPayment(req CreatePaymentRequest) (*models.Payme
 
 	return &payment, nil
 }
+
+// recordCashPayment marks a pending booking paid with cash taken at the
+// front desk by cashierID. A guest booking has no payer, so its payment is
+// refunded in cash rather than to a wallet.
+func recordCashPayment(tx *gorm.DB, booking *models.Booking, cashierID uint) (*models.Payment, error) {
+	payment := models.Payment{
+		BookingID:     &booking.ID,
+		CashierID:     &cashierID,
+		Amount:        booking.TotalPrice,
+		NetAmount:     booking.NetAmount,
+		TaxAmount:     booking.TaxAmount,
+		Status:        models.PaymentCompleted,
+		PaymentMethod: models.PaymentMethodCash,
+		TransactionID: fmt.Sprintf("CASH-%d-%d", booking.ID, time.Now().Unix()),
+	}
+	if booking.GuestName == "" {
+		payment.UserID = &booking.UserID
+	}
+
+	if err := tx.Create(&payment).Error; err != nil {
+		return nil, err
+	}
+	invoice, err := issueInvoice(tx, &payment)
+	if err != nil {
+		return nil, err
+	}
+	if err := postPaymentJournal(tx, &payment, invoice); err != nil {
+		return nil, err
+	}
+	if err := transitionBooking(tx, booking, models.StatusPaid, &cashierID, "paid in cash"); err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (s *PaymentService) processOrderPayment(userID uint, req CreatePaymentRequest) (*models.Payment, error) {
+	var order models.Order
+	if err := s.db.Preload("Bookings").First(&order, req.OrderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("order not found")
+		}
+		return nil, err
+	}
+
+	if order.Status == models.OrderPaid {
+		return nil, errors.New("order is already paid")
+	}
+	if order.Status != models.OrderPending {
+		return nil, fmt.Errorf("order is %s", order.Status)
+	}
+
+	// Every slot of the order must still be held
+	tax := models.NewMoney(0, order.TotalAmount.Currency)
+	for _, booking := range order.Bookings {
+		if booking.Status != models.StatusPending {
+			return nil, fmt.Errorf("booking %d of this order is %s", booking.ID, booking.Status)
+		}
+		if !booking.TotalPrice.SameCurrency(order.TotalAmount) {
+			return nil, models.ErrCurrencyMismatch
+		}
+		tax = tax.Add(booking.TaxAmount)
+	}
+
+	// Mock payment processing
+	transactionID := fmt.Sprintf("TRX-O%d-%d", order.ID, time.Now().Unix())
+
+	payment := models.Payment{
+		OrderID:       &order.ID,
+		UserID:        &userID,
+		Amount:        order.TotalAmount,
+		NetAmount:     order.TotalAmount.Sub(tax),
+		TaxAmount:     tax,
+		Status:        models.PaymentCompleted,
+		PaymentMethod: req.PaymentMethod,
+		TransactionID: transactionID,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if payment.PaymentMethod == models.PaymentMethodWallet {
+			if err := chargeWallet(tx, &payment, payment.Amount, fmt.Sprintf("Order %d", order.ID)); err != nil {
+				return err
+			}
+		}
+		invoice, err := issueInvoice(tx, &payment)
+		if err != nil {
+			return err
+		}
+		if err := postPaymentJournal(tx, &payment, invoice); err != nil {
+			return err
+		}
+		for i := range order.Bookings {
+			if err := transitionBooking(tx, &order.Bookings[i], models.StatusPaid, &userID, fmt.Sprintf("order %d paid", order.ID)); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&order).Update("status", models.OrderPaid).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Load relations
+	s.db.Preload("Order.Bookings.Field").First(&payment, payment.ID)
+
+	return &payment, nil
+}
+
+// processSharePayment pays the calling participant's share of a split
+// booking. The booking becomes paid once the last share is settled.
+func (s *PaymentService) processSharePayment(userID uint, booking *models.Booking, split *models.PaymentSplit, req CreatePaymentRequest) (*models.Payment, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	var share models.PaymentShare
+	if err := s.db.Where("split_id = ? AND (user_id = ? OR (user_id IS NULL AND LOWER(email) = ?))", split.ID, userID, strings.ToLower(user.Email)).
+		First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("you have no share in this booking")
+		}
+		return nil, err
+	}
+
+	var payment models.Payment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the booking and then the split, in the order cancellations
+		// take them, so that the other shares and the deadline job wait here
+		// and then see this share as paid
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(booking, booking.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(split, split.ID).Error; err != nil {
+			return err
+		}
+		if split.Status != models.SplitOpen {
+			return fmt.Errorf("payment split is %s", split.Status)
+		}
+		if err := tx.First(&share, share.ID).Error; err != nil {
+			return err
+		}
+		if share.Status != models.SharePending {
+			return fmt.Errorf("your share is already %s", share.Status)
+		}
+
+		tax, err := shareTax(tx, booking, &share)
+		if err != nil {
+			return err
+		}
+
+		// Mock payment processing
+		payment = models.Payment{
+			BookingID:     &booking.ID,
+			UserID:        &userID,
+			ShareID:       &share.ID,
+			Amount:        share.Amount,
+			NetAmount:     share.Amount.Sub(tax),
+			TaxAmount:     tax,
+			Status:        models.PaymentCompleted,
+			PaymentMethod: req.PaymentMethod,
+			TransactionID: fmt.Sprintf("TRX-%d-S%d-%d", booking.ID, share.ID, time.Now().Unix()),
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if payment.PaymentMethod == models.PaymentMethodWallet {
+			if err := chargeWallet(tx, &payment, payment.Amount, fmt.Sprintf("Share of booking %d", booking.ID)); err != nil {
+				return err
+			}
+		}
+		invoice, err := issueInvoice(tx, &payment)
+		if err != nil {
+			return err
+		}
+		if err := postPaymentJournal(tx, &payment, invoice); err != nil {
+			return err
+		}
+		if err := tx.Model(&share).Updates(map[string]interface{}{
+			"status":     models.SharePaid,
+			"user_id":    userID,
+			"payment_id": payment.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		var pending int64
+		if err := tx.Model(&models.PaymentShare{}).Where("split_id = ? AND status = ?", split.ID, models.SharePending).Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return nil
+		}
+
+		if err := transitionBooking(tx, booking, models.StatusPaid, &userID, "last share paid"); err != nil {
+			return err
+		}
+		return tx.Model(split).Update("status", models.SplitCompleted).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Load relations
+	s.db.Preload("Booking.Field").First(&payment, payment.ID)
+
+	return &payment, nil
+}
+
+// shareTax is the part of a booking's tax carried by one share, in
+// proportion to its amount. The last share to be paid takes whatever is left
+// so the shares' tax adds up to the booking's.
+func shareTax(tx *gorm.DB, booking *models.Booking, share *models.PaymentShare) (models.Money, error) {
+	none := models.NewMoney(0, booking.TotalPrice.Currency)
+	if booking.TaxAmount.IsZero() || booking.TotalPrice.IsZero() {
+		return none, nil
+	}
+
+	var pending int64
+	if err := tx.Model(&models.PaymentShare{}).Where("split_id = ? AND status = ?", share.SplitID, models.SharePending).Count(&pending).Error; err != nil {
+		return none, err
+	}
+	if pending > 1 {
+		total := booking.TotalPrice.Amount
+		return models.NewMoney((share.Amount.Amount*booking.TaxAmount.Amount+total/2)/total, booking.TotalPrice.Currency), nil
+	}
+
+	var paid int64
+	if err := tx.Model(&models.Payment{}).Where("booking_id = ? AND share_id IS NOT NULL", booking.ID).
+		Select("COALESCE(SUM(tax_amount_amount), 0)").Scan(&paid).Error; err != nil {
+		return none, err
+	}
+	return booking.TaxAmount.Sub(models.NewMoney(paid, booking.TaxAmount.Currency)), nil
+}
+
+// RefundRequest gives back Amount minor units of the payment's currency.
+type RefundRequest struct {
+	Amount   int64  `json:"amount" validate:"required,gt=0"`
+	Reason   string `json:"reason" validate:"required"`
+	ToWallet bool   `json:"to_wallet"`
+}
+
+// RefundPayment gives part or all of a captured payment back to the payer,
+// either through the gateway or as wallet credit. The bookings the payment
+// covers follow the payment's refund status.
+func (s *PaymentService) RefundPayment(adminID, paymentID uint, req RefundRequest) (*models.Refund, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, errors.New("refund reason is required")
+	}
+
+	var refund *models.Refund
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		if err := tx.First(&payment, paymentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("payment not found")
+			}
+			return err
+		}
+
+		var err error
+		refund, err = issueRefund(tx, &payment, models.NewMoney(req.Amount, payment.Amount.Currency), req.Reason, &adminID, req.ToWallet)
+		if err != nil {
+			return err
+		}
+		return syncRefundedBookings(tx, &payment, &adminID, req.Reason)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+var refundListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at", "amount": "amount_amount"},
+	defaultSort: "created_at",
+	status:      "status",
+	time:        "created_at",
+}
+
+func (s *PaymentService) GetPaymentRefunds(paymentID uint, q ListQuery) ([]models.Refund, *utils.Pagination, error) {
+	if err := s.db.First(&models.Payment{}, paymentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("payment not found")
+		}
+		return nil, nil, err
+	}
+
+	var refunds []models.Refund
+	page, err := q.list(s.db.Where("payment_id = ?", paymentID), refundListSpec, &refunds)
+	if err != nil {
+		return nil, nil, err
+	}
+	return refunds, page, nil
+}
+
+// capturedAmount is what the payer has been charged in total: the payment
+// itself plus any settled follow-up charges.
+func capturedAmount(tx *gorm.DB, payment *models.Payment) models.Money {
+	var charged int64
+	tx.Model(&models.PaymentAdjustment{}).
+		Select("COALESCE(SUM(amount_amount), 0)").
+		Where("payment_id = ? AND type = ? AND status = ?", payment.ID, models.AdjustmentCharge, models.PaymentCompleted).
+		Scan(&charged)
+	return payment.Amount.Add(models.NewMoney(charged, payment.Amount.Currency))
+}
+
+func refundedAmount(tx *gorm.DB, payment *models.Payment) models.Money {
+	var refunded int64
+	tx.Model(&models.Refund{}).
+		Select("COALESCE(SUM(amount_amount), 0)").
+		Where("payment_id = ? AND status IN ?", payment.ID, []models.RefundStatus{models.RefundPending, models.RefundCompleted}).
+		Scan(&refunded)
+	return models.NewMoney(refunded, payment.Amount.Currency)
+}
+
+// issueRefund refunds amount of a payment through the mock gateway, which
+// settles immediately, and moves the payment to partially_refunded or
+// refunded. Refunds can never exceed what was captured. Wallet payments are
+// always refunded to the wallet; others only when toWallet is set, and
+// otherwise the way they were paid.
+func issueRefund(tx *gorm.DB, payment *models.Payment, amount models.Money, reason string, processedBy *uint, toWallet bool) (*models.Refund, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(payment, payment.ID).Error; err != nil {
+		return nil, err
+	}
+	if payment.Status != models.PaymentCompleted && payment.Status != models.PaymentPartiallyRefunded {
+		return nil, fmt.Errorf("payment is %s and cannot be refunded", payment.Status)
+	}
+
+	if !amount.SameCurrency(payment.Amount) {
+		return nil, models.ErrCurrencyMismatch
+	}
+	captured := capturedAmount(tx, payment)
+	refunded := refundedAmount(tx, payment)
+	refundable := captured.Sub(refunded)
+	if amount.Amount > refundable.Amount {
+		return nil, fmt.Errorf("refund of %s exceeds the refundable amount of %s", amount, refundable)
+	}
+
+	var seq int64
+	tx.Model(&models.Refund{}).Where("payment_id = ?", payment.ID).Count(&seq)
+
+	toWallet = toWallet || payment.PaymentMethod == models.PaymentMethodWallet
+	if toWallet && payment.UserID == nil {
+		return nil, errors.New("payment has no payer to credit")
+	}
+
+	refund := models.Refund{
+		TenantID:         payment.TenantID,
+		PaymentID:        payment.ID,
+		Amount:           amount,
+		Reason:           reason,
+		Status:           models.RefundCompleted,
+		GatewayReference: fmt.Sprintf("RFD-%d-%d-%d", payment.ID, seq+1, time.Now().Unix()),
+		ToWallet:         toWallet,
+		ProcessedBy:      processedBy,
+	}
+	if err := tx.Create(&refund).Error; err != nil {
+		return nil, err
+	}
+	if _, err := issueCreditNote(tx, payment, &refund); err != nil {
+		return nil, err
+	}
+
+	j := newJournal(fmt.Sprintf("refund:%d", refund.ID), fmt.Sprintf("Refund %s: %s", refund.GatewayReference, reason))
+	j.entry.PaymentID = &payment.ID
+	j.entry.RefundID = &refund.ID
+	if toWallet {
+		entry, err := postWalletTransaction(tx, *payment.UserID, walletEntry{
+			Type:        models.WalletRefundCredit,
+			Amount:      amount,
+			PaymentID:   &payment.ID,
+			RefundID:    &refund.ID,
+			Description: reason,
+		})
+		if err != nil {
+			return nil, err
+		}
+		j.entry.WalletTransactionID = &entry.ID
+		j.post(models.AccountRefunds, models.AccountCustomerWallets, amount)
+	} else {
+		j.post(models.AccountRefunds, settlementAccount(payment.PaymentMethod), amount)
+	}
+	if err := reverseEarnings(tx, payment, &refund, captured, refunded, j); err != nil {
+		return nil, err
+	}
+	if err := postJournal(tx, j); err != nil {
+		return nil, err
+	}
+
+	status := models.PaymentPartiallyRefunded
+	if refunded.Add(amount) == captured {
+		status = models.PaymentRefunded
+	}
+	if err := tx.Model(payment).Update("status", status).Error; err != nil {
+		return nil, err
+	}
+
+	return &refund, nil
+}
+
+// syncRefundedBookings carries a payment's refund status over to the
+// bookings it covers. A paid booking becomes partially_refunded or refunded;
+// any other booking that took money is only marked once fully refunded.
+func syncRefundedBookings(tx *gorm.DB, payment *models.Payment, actorID *uint, reason string) error {
+	var column string
+	var id uint
+	switch {
+	case payment.OrderID != nil:
+		column, id = "order_id", *payment.OrderID
+	case payment.BookingID != nil:
+		column, id = "id", *payment.BookingID
+	default:
+		return nil
+	}
+
+	fully := payment.Status == models.PaymentRefunded
+	if fully && payment.BookingID != nil {
+		// A split booking is only refunded once every share has been
+		var outstanding int64
+		tx.Model(&models.Payment{}).
+			Where("booking_id = ? AND id != ? AND status != ?", *payment.BookingID, payment.ID, models.PaymentRefunded).
+			Count(&outstanding)
+		fully = outstanding == 0
+	}
+
+	to := models.StatusPartiallyRefunded
+	if fully {
+		to = models.StatusRefunded
+	}
+	_, err := transitionBookings(tx, to, actorID, reason, column+" = ?", id)
+	return err
+}
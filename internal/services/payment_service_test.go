@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupPaymentTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Order{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.PaymentAdjustment{},
+		&models.Refund{}, &models.WaitlistEntry{}, &models.PaymentSplit{}, &models.PaymentShare{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestPaymentService_RefundPayment(t *testing.T) {
+	db := setupPaymentTestDB()
+	paymentService := NewPaymentService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+	admin := models.User{Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin}
+	db.Create(&admin)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+
+	payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		request       RefundRequest
+		wantErr       bool
+		wantPayment   models.PaymentStatus
+		wantBooking   models.BookingStatus
+		wantRefundSum int64
+	}{
+		{
+			name:    "Missing reason",
+			request: RefundRequest{Amount: 50000},
+			wantErr: true,
+		},
+		{
+			name:    "More than captured",
+			request: RefundRequest{Amount: 250000, Reason: "goodwill"},
+			wantErr: true,
+		},
+		{
+			name:          "Partial refund",
+			request:       RefundRequest{Amount: 50000, Reason: "lights out for half an hour"},
+			wantPayment:   models.PaymentPartiallyRefunded,
+			wantBooking:   models.StatusPartiallyRefunded,
+			wantRefundSum: 50000,
+		},
+		{
+			name:    "Exceeds what is left",
+			request: RefundRequest{Amount: 200000, Reason: "goodwill"},
+			wantErr: true,
+		},
+		{
+			name:          "Refund the rest",
+			request:       RefundRequest{Amount: 150000, Reason: "field closed"},
+			wantPayment:   models.PaymentRefunded,
+			wantBooking:   models.StatusRefunded,
+			wantRefundSum: 200000,
+		},
+		{
+			name:    "Already fully refunded",
+			request: RefundRequest{Amount: 1, Reason: "goodwill"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refund, err := paymentService.RefundPayment(admin.ID, payment.ID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, refund)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, models.RefundCompleted, refund.Status)
+			assert.Equal(t, admin.ID, *refund.ProcessedBy)
+			assert.NotEmpty(t, refund.GatewayReference)
+
+			var updated models.Payment
+			db.Preload("Refunds").First(&updated, payment.ID)
+			assert.Equal(t, tt.wantPayment, updated.Status)
+
+			var sum int64
+			for _, r := range updated.Refunds {
+				sum += r.Amount.Amount
+			}
+			assert.Equal(t, tt.wantRefundSum, sum)
+
+			db.First(booking, booking.ID)
+			assert.Equal(t, tt.wantBooking, booking.Status)
+		})
+	}
+}
@@ -0,0 +1,622 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrPayoutBatchExists = errors.New("payout batch for this week already exists")
+
+type PayoutService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewPayoutService(db *gorm.DB, cfg *config.Config) *PayoutService {
+	return &PayoutService{db: db, cfg: cfg}
+}
+
+func (s *PayoutService) WithContext(ctx context.Context) *PayoutService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type PayoutAccountRequest struct {
+	HolderName string `json:"holder_name" validate:"required"`
+	IBAN       string `json:"iban" validate:"required"`
+	BIC        string `json:"bic"`
+}
+
+// EarningsSummary totals an owner's earnings in one currency. Net is split
+// into what has been paid out, what is in a payout awaiting transfer, and
+// what has not been batched yet.
+type EarningsSummary struct {
+	Currency   string       `json:"currency"`
+	Gross      models.Money `json:"gross"`
+	Commission models.Money `json:"commission"`
+	Net        models.Money `json:"net"`
+	PaidOut    models.Money `json:"paid_out"`
+	Scheduled  models.Money `json:"scheduled"`
+	Unpaid     models.Money `json:"unpaid"`
+}
+
+type OwnerEarnings struct {
+	Totals   []EarningsSummary     `json:"totals"`
+	Earnings []models.OwnerEarning `json:"earnings"`
+}
+
+// prorate returns amount * num / den rounded half away from zero.
+func prorate(amount, num, den int64) int64 {
+	if den == 0 {
+		return 0
+	}
+	p := amount * num
+	if p < 0 {
+		return -((-p + den/2) / den)
+	}
+	return (p + den/2) / den
+}
+
+// earningFor splits revenue from an owned field into the platform
+// commission and the owner's share.
+func earningFor(field *models.Field, gross models.Money) models.OwnerEarning {
+	commission := models.NewMoney(prorate(gross.Amount, int64(field.CommissionRate), 10000), gross.Currency)
+	return models.OwnerEarning{
+		TenantID:       field.TenantID,
+		OwnerID:        *field.OwnerID,
+		FieldID:        field.ID,
+		Gross:          gross,
+		CommissionRate: field.CommissionRate,
+		Commission:     commission,
+		Net:            gross.Sub(commission),
+	}
+}
+
+// createEarning stores an earning and moves the owner's share of the venue
+// revenue to owner payables in the same journal entry.
+func createEarning(tx *gorm.DB, j *journal, earning models.OwnerEarning) error {
+	if earning.Gross.IsZero() && earning.Commission.IsZero() {
+		return nil
+	}
+	if err := tx.Create(&earning).Error; err != nil {
+		return err
+	}
+	j.post(models.AccountVenueRevenue, models.AccountOwnerPayables, earning.Net)
+	return nil
+}
+
+// accrueSaleEarnings credits the owners of the booked fields with the
+// invoiced revenue of a payment, before tax and after discounts.
+func accrueSaleEarnings(tx *gorm.DB, payment *models.Payment, invoice *models.Invoice, j *journal) error {
+	for _, line := range invoice.Lines {
+		if line.BookingID == nil {
+			continue
+		}
+		var booking models.Booking
+		if err := tx.Unscoped().First(&booking, *line.BookingID).Error; err != nil {
+			return err
+		}
+		var field models.Field
+		if err := tx.Unscoped().First(&field, booking.FieldID).Error; err != nil {
+			return err
+		}
+		if field.OwnerID == nil {
+			continue
+		}
+
+		earning := earningFor(&field, line.Net)
+		earning.Type = models.EarningSale
+		earning.BookingID = line.BookingID
+		earning.PaymentID = payment.ID
+		if err := createEarning(tx, j, earning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// accrueChargeEarning credits the field's owner with a follow-up charge,
+// booked gross like the charge itself.
+func accrueChargeEarning(tx *gorm.DB, payment *models.Payment, adjustment *models.PaymentAdjustment, booking *models.Booking, field *models.Field, j *journal) error {
+	if field.OwnerID == nil {
+		return nil
+	}
+	earning := earningFor(field, adjustment.Amount)
+	earning.Type = models.EarningCharge
+	earning.BookingID = &booking.ID
+	earning.PaymentID = payment.ID
+	earning.AdjustmentID = &adjustment.ID
+	return createEarning(tx, j, earning)
+}
+
+// reverseEarnings takes back the owners' share of a refund, in proportion
+// to what the payment captured. The refund that completes a full refund
+// reverses whatever is left, so rounding never leaves a remainder.
+func reverseEarnings(tx *gorm.DB, payment *models.Payment, refund *models.Refund, captured, refunded models.Money, j *journal) error {
+	var earnings []models.OwnerEarning
+	if err := tx.Where("payment_id = ?", payment.ID).Order("id ASC").Find(&earnings).Error; err != nil {
+		return err
+	}
+
+	type fieldTotal struct {
+		earning    models.OwnerEarning
+		gross      int64
+		commission int64
+	}
+	var totals []*fieldTotal
+	byField := map[uint]*fieldTotal{}
+	full := refunded.Add(refund.Amount) == captured
+	for _, earning := range earnings {
+		if !full && earning.Type == models.EarningRefund {
+			continue
+		}
+		total, ok := byField[earning.FieldID]
+		if !ok {
+			total = &fieldTotal{earning: earning}
+			byField[earning.FieldID] = total
+			totals = append(totals, total)
+		}
+		total.gross += earning.Gross.Amount
+		total.commission += earning.Commission.Amount
+	}
+
+	for _, total := range totals {
+		gross, commission := total.gross, total.commission
+		if !full {
+			gross = prorate(gross, refund.Amount.Amount, captured.Amount)
+			commission = prorate(commission, refund.Amount.Amount, captured.Amount)
+		}
+		currency := total.earning.Gross.Currency
+		earning := models.OwnerEarning{
+			TenantID:       total.earning.TenantID,
+			OwnerID:        total.earning.OwnerID,
+			FieldID:        total.earning.FieldID,
+			BookingID:      total.earning.BookingID,
+			PaymentID:      payment.ID,
+			RefundID:       &refund.ID,
+			Type:           models.EarningRefund,
+			Gross:          models.NewMoney(-gross, currency),
+			CommissionRate: total.earning.CommissionRate,
+			Commission:     models.NewMoney(-commission, currency),
+			Net:            models.NewMoney(commission-gross, currency),
+		}
+		if err := createEarning(tx, j, earning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetEarnings lists an owner's earnings, newest first, with totals per
+// currency.
+func (s *PayoutService) GetEarnings(ownerID uint) (*OwnerEarnings, error) {
+	result := &OwnerEarnings{}
+	if err := s.db.Where("owner_id = ?", ownerID).Order("created_at DESC, id DESC").Find(&result.Earnings).Error; err != nil {
+		return nil, err
+	}
+
+	var payouts []models.Payout
+	if err := s.db.Where("owner_id = ?", ownerID).Find(&payouts).Error; err != nil {
+		return nil, err
+	}
+	status := map[uint]models.PayoutStatus{}
+	for _, payout := range payouts {
+		status[payout.ID] = payout.Status
+	}
+
+	byCurrency := map[string]*EarningsSummary{}
+	for _, earning := range result.Earnings {
+		currency := earning.Net.Currency
+		summary, ok := byCurrency[currency]
+		if !ok {
+			zero := models.NewMoney(0, currency)
+			summary = &EarningsSummary{Currency: currency, Gross: zero, Commission: zero, Net: zero, PaidOut: zero, Scheduled: zero, Unpaid: zero}
+			byCurrency[currency] = summary
+		}
+		summary.Gross = summary.Gross.Add(earning.Gross)
+		summary.Commission = summary.Commission.Add(earning.Commission)
+		summary.Net = summary.Net.Add(earning.Net)
+		switch {
+		case earning.PayoutID == nil:
+			summary.Unpaid = summary.Unpaid.Add(earning.Net)
+		case status[*earning.PayoutID] == models.PayoutPaid:
+			summary.PaidOut = summary.PaidOut.Add(earning.Net)
+		default:
+			summary.Scheduled = summary.Scheduled.Add(earning.Net)
+		}
+	}
+
+	result.Totals = []EarningsSummary{}
+	for _, earning := range result.Earnings {
+		if summary, ok := byCurrency[earning.Net.Currency]; ok {
+			result.Totals = append(result.Totals, *summary)
+			delete(byCurrency, earning.Net.Currency)
+		}
+	}
+	return result, nil
+}
+
+var payoutListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at", "amount": "amount_amount"},
+	defaultSort: "-created_at",
+	status:      "status",
+	time:        "created_at",
+}
+
+func (s *PayoutService) GetPayouts(ownerID uint, q ListQuery) ([]models.Payout, *utils.Pagination, error) {
+	var payouts []models.Payout
+	page, err := q.list(s.db.Where("owner_id = ?", ownerID), payoutListSpec, &payouts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payouts, page, nil
+}
+
+// SetPayoutAccount creates or replaces the bank account an owner is paid
+// out to. Payouts already batched keep the account they were made with.
+func (s *PayoutService) SetPayoutAccount(ownerID uint, req PayoutAccountRequest) (*models.PayoutAccount, error) {
+	holderName := strings.TrimSpace(req.HolderName)
+	if holderName == "" {
+		return nil, errors.New("holder_name is required")
+	}
+	iban := strings.ToUpper(strings.ReplaceAll(req.IBAN, " ", ""))
+	if !validIBAN(iban) {
+		return nil, errors.New("invalid IBAN")
+	}
+	bic := strings.ToUpper(strings.TrimSpace(req.BIC))
+	if bic != "" && len(bic) != 8 && len(bic) != 11 {
+		return nil, errors.New("BIC must have 8 or 11 characters")
+	}
+
+	var account models.PayoutAccount
+	if err := s.db.Where("owner_id = ?", ownerID).First(&account).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	account.OwnerID = ownerID
+	account.HolderName = holderName
+	account.IBAN = iban
+	account.BIC = bic
+	if err := s.db.Save(&account).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// validIBAN checks the length, country code and ISO 7064 check digits of an
+// IBAN without spaces.
+func validIBAN(iban string) bool {
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	for i, r := range iban {
+		switch {
+		case i < 2 && (r < 'A' || r > 'Z'):
+			return false
+		case i >= 2 && i < 4 && (r < '0' || r > '9'):
+			return false
+		case (r < 'A' || r > 'Z') && (r < '0' || r > '9'):
+			return false
+		}
+	}
+
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' {
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// weekStart returns midnight UTC on the Monday of t's week.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+// GeneratePayoutBatch pays out every owner's unpaid earnings made before
+// this week started, one payout per owner and currency. Fields on a weekly
+// schedule are paid every week, monthly ones in the first batch of each
+// month. Owners without a payout account, or whose earnings net to zero or
+// less after refunds, are carried over to the next batch. Only one batch is
+// made per week.
+func (s *PayoutService) GeneratePayoutBatch(now time.Time) (*models.PayoutBatch, error) {
+	periodEnd := weekStart(now)
+	schedules := []models.PayoutSchedule{models.PayoutWeekly}
+	if periodEnd.AddDate(0, 0, -7).Month() != periodEnd.Month() {
+		schedules = append(schedules, models.PayoutMonthly)
+	}
+	year, week := periodEnd.AddDate(0, 0, -1).ISOWeek()
+
+	batch := models.PayoutBatch{
+		Reference: fmt.Sprintf("PB-%d-W%02d", year, week),
+		PeriodEnd: periodEnd,
+		Status:    models.PayoutPending,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&models.PayoutBatch{}).Where("period_end = ?", periodEnd).Count(&count)
+		if count > 0 {
+			return ErrPayoutBatchExists
+		}
+		if err := tx.Create(&batch).Error; err != nil {
+			return err
+		}
+
+		due := func() *gorm.DB {
+			return tx.Model(&models.OwnerEarning{}).
+				Where("payout_id IS NULL AND created_at < ?", periodEnd).
+				Where("field_id IN (?)", tx.Unscoped().Model(&models.Field{}).Select("id").Where("payout_schedule IN ?", schedules))
+		}
+
+		var owed []struct {
+			TenantID uint
+			OwnerID  uint
+			Currency string
+			Amount   int64
+			Earnings int
+		}
+		if err := due().
+			Select("tenant_id, owner_id, net_currency AS currency, SUM(net_amount) AS amount, COUNT(*) AS earnings").
+			Group("tenant_id, owner_id, net_currency").
+			Order("owner_id ASC, net_currency ASC").
+			Scan(&owed).Error; err != nil {
+			return err
+		}
+
+		for _, row := range owed {
+			if row.Amount <= 0 {
+				continue
+			}
+			var account models.PayoutAccount
+			if err := tx.Where("owner_id = ?", row.OwnerID).First(&account).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+
+			payout := models.Payout{
+				TenantID:   row.TenantID,
+				BatchID:    batch.ID,
+				OwnerID:    row.OwnerID,
+				Amount:     models.NewMoney(row.Amount, row.Currency),
+				Earnings:   row.Earnings,
+				HolderName: account.HolderName,
+				IBAN:       account.IBAN,
+				BIC:        account.BIC,
+				Status:     models.PayoutPending,
+			}
+			if err := tx.Create(&payout).Error; err != nil {
+				return err
+			}
+			if err := due().Where("owner_id = ? AND net_currency = ?", row.OwnerID, row.Currency).Update("payout_id", payout.ID).Error; err != nil {
+				return err
+			}
+			batch.Payouts = append(batch.Payouts, payout)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// payoutBatchListSpec lists batches latest period first. Their time is the
+// end of the period they pay out.
+var payoutBatchListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "period_end": "period_end", "created_at": "created_at"},
+	defaultSort: "-period_end",
+	status:      "status",
+	time:        "period_end",
+}
+
+func (s *PayoutService) GetBatches(q ListQuery) ([]models.PayoutBatch, *utils.Pagination, error) {
+	var batches []models.PayoutBatch
+	page, err := q.list(s.db.Preload("Payouts"), payoutBatchListSpec, &batches)
+	if err != nil {
+		return nil, nil, err
+	}
+	return batches, page, nil
+}
+
+func (s *PayoutService) GetBatch(id uint) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	if err := s.db.Preload("Payouts", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).First(&batch, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payout batch not found")
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// MarkBatchPaid records that the bank has executed a batch's transfers and
+// settles the owner payables they cover.
+func (s *PayoutService) MarkBatchPaid(id uint) (*models.PayoutBatch, error) {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var batch models.PayoutBatch
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Payouts").First(&batch, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("payout batch not found")
+			}
+			return err
+		}
+		if batch.Status != models.PayoutPending {
+			return errors.New("payout batch is already paid")
+		}
+
+		now := time.Now()
+		for _, payout := range batch.Payouts {
+			j := newJournal(fmt.Sprintf("payout:%d", payout.ID), fmt.Sprintf("Payout %s to owner %d", batch.Reference, payout.OwnerID))
+			j.post(models.AccountOwnerPayables, models.AccountGatewayClearing, payout.Amount)
+			if err := postJournal(tx, j); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Payout{}).Where("batch_id = ?", batch.ID).Updates(map[string]interface{}{"status": models.PayoutPaid, "paid_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&batch).Updates(map[string]interface{}{"status": models.PayoutPaid, "paid_at": now}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetBatch(id)
+}
+
+// ExportCSV lists a batch's payouts for upload to any bank.
+func (s *PayoutService) ExportCSV(batch *models.PayoutBatch) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"payout_id", "owner_id", "holder_name", "iban", "bic", "amount", "currency", "reference"})
+	for _, payout := range batch.Payouts {
+		w.Write([]string{
+			strconv.FormatUint(uint64(payout.ID), 10),
+			strconv.FormatUint(uint64(payout.OwnerID), 10),
+			payout.HolderName,
+			payout.IBAN,
+			payout.BIC,
+			payout.Amount.Decimal(),
+			payout.Amount.Currency,
+			fmt.Sprintf("%s-%d", batch.Reference, payout.ID),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// sepaDocument is an ISO 20022 pain.001.001.03 credit transfer initiation.
+type sepaDocument struct {
+	XMLName xml.Name `xml:"urn:iso:std:iso:20022:tech:xsd:pain.001.001.03 Document"`
+	GrpHdr  struct {
+		MsgId    string
+		CreDtTm  string
+		NbOfTxs  int
+		CtrlSum  string
+		InitgPty sepaParty
+	} `xml:"CstmrCdtTrfInitn>GrpHdr"`
+	PmtInf sepaPaymentInfo `xml:"CstmrCdtTrfInitn>PmtInf"`
+}
+
+type sepaParty struct {
+	Nm string
+}
+
+type sepaAccount struct {
+	IBAN string `xml:"Id>IBAN"`
+}
+
+type sepaAgent struct {
+	BIC  string `xml:"FinInstnId>BIC,omitempty"`
+	Othr string `xml:"FinInstnId>Othr>Id,omitempty"`
+}
+
+func newSEPAAgent(bic string) sepaAgent {
+	if bic == "" {
+		return sepaAgent{Othr: "NOTPROVIDED"}
+	}
+	return sepaAgent{BIC: bic}
+}
+
+type sepaPaymentInfo struct {
+	PmtInfId    string
+	PmtMtd      string
+	NbOfTxs     int
+	CtrlSum     string
+	SvcLvl      string `xml:"PmtTpInf>SvcLvl>Cd"`
+	ReqdExctnDt string
+	Dbtr        sepaParty
+	DbtrAcct    sepaAccount
+	DbtrAgt     sepaAgent
+	ChrgBr      string
+	CdtTrfTxInf []sepaTransfer
+}
+
+type sepaTransfer struct {
+	EndToEndId string `xml:"PmtId>EndToEndId"`
+	InstdAmt   struct {
+		Ccy   string `xml:",attr"`
+		Value string `xml:",chardata"`
+	} `xml:"Amt>InstdAmt"`
+	CdtrAgt  sepaAgent
+	Cdtr     sepaParty
+	CdtrAcct sepaAccount
+	Ustrd    string `xml:"RmtInf>Ustrd"`
+}
+
+// ExportSEPA renders a batch's euro payouts as a SEPA credit transfer file
+// debiting the platform account configured in PAYOUT_DEBTOR_*.
+func (s *PayoutService) ExportSEPA(batch *models.PayoutBatch) ([]byte, error) {
+	if s.cfg.Payout.DebtorIBAN == "" {
+		return nil, errors.New("PAYOUT_DEBTOR_IBAN is not configured")
+	}
+
+	doc := sepaDocument{}
+	total := models.NewMoney(0, "EUR")
+	for _, payout := range batch.Payouts {
+		if payout.Amount.Currency != "EUR" {
+			continue
+		}
+		transfer := sepaTransfer{
+			EndToEndId: fmt.Sprintf("%s-%d", batch.Reference, payout.ID),
+			CdtrAgt:    newSEPAAgent(payout.BIC),
+			Cdtr:       sepaParty{Nm: payout.HolderName},
+			CdtrAcct:   sepaAccount{IBAN: payout.IBAN},
+			Ustrd:      fmt.Sprintf("Payout %s", batch.Reference),
+		}
+		transfer.InstdAmt.Ccy = "EUR"
+		transfer.InstdAmt.Value = payout.Amount.Decimal()
+		doc.PmtInf.CdtTrfTxInf = append(doc.PmtInf.CdtTrfTxInf, transfer)
+		total = total.Add(payout.Amount)
+	}
+	if len(doc.PmtInf.CdtTrfTxInf) == 0 {
+		return nil, errors.New("payout batch has no euro payouts to export as SEPA")
+	}
+
+	now := time.Now().UTC()
+	count := len(doc.PmtInf.CdtTrfTxInf)
+	doc.GrpHdr.MsgId = batch.Reference
+	doc.GrpHdr.CreDtTm = now.Format("2006-01-02T15:04:05")
+	doc.GrpHdr.NbOfTxs = count
+	doc.GrpHdr.CtrlSum = total.Decimal()
+	doc.GrpHdr.InitgPty = sepaParty{Nm: s.cfg.Payout.DebtorName}
+	doc.PmtInf.PmtInfId = batch.Reference
+	doc.PmtInf.PmtMtd = "TRF"
+	doc.PmtInf.NbOfTxs = count
+	doc.PmtInf.CtrlSum = total.Decimal()
+	doc.PmtInf.SvcLvl = "SEPA"
+	doc.PmtInf.ReqdExctnDt = now.Format("2006-01-02")
+	doc.PmtInf.Dbtr = sepaParty{Nm: s.cfg.Payout.DebtorName}
+	doc.PmtInf.DbtrAcct = sepaAccount{IBAN: s.cfg.Payout.DebtorIBAN}
+	doc.PmtInf.DbtrAgt = newSEPAAgent(s.cfg.Payout.DebtorBIC)
+	doc.PmtInf.ChrgBr = "SLEV"
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupPayoutTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Order{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.PaymentAdjustment{},
+		&models.Refund{}, &models.WaitlistEntry{}, &models.PaymentSplit{}, &models.PaymentShare{}, &models.Wallet{}, &models.WalletTransaction{},
+		&models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{},
+		&models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{}, &models.PayoutAccount{}, &models.PayoutBatch{}, &models.Payout{})
+
+	return db
+}
+
+func TestPayoutService_EarningsAndPayouts(t *testing.T) {
+	db := setupPayoutTestDB()
+	cfg := bookingTestConfig()
+	fieldService := NewFieldService(db, cfg)
+	bookingService := NewBookingService(db, cfg)
+	paymentService := NewPaymentService(db)
+	payoutService := NewPayoutService(db, cfg)
+
+	_, err := NewTaxService(db).CreateTaxRate(CreateTaxRateRequest{Name: "VAT", Rate: 1100})
+	assert.NoError(t, err)
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	admin := models.User{Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin}
+	player := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&owner)
+	db.Create(&admin)
+	db.Create(&player)
+
+	_, err = fieldService.CreateField(CreateFieldRequest{Name: "Admin Court", PricePerHour: 100000, Location: "Bandung", OwnerID: &admin.ID})
+	assert.Error(t, err, "admins cannot own fields")
+	commission := 1000
+	_, err = fieldService.CreateField(CreateFieldRequest{Name: "Bad Court", PricePerHour: 100000, Location: "Bandung", OwnerID: &owner.ID, PayoutSchedule: "daily"})
+	assert.Error(t, err, "unknown payout schedule")
+
+	owned, err := fieldService.CreateField(CreateFieldRequest{Name: "Owner Court", PricePerHour: 100000, Location: "Bandung", OwnerID: &owner.ID, CommissionRate: &commission})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PayoutWeekly, owned.PayoutSchedule)
+	db.First(&owner, owner.ID)
+	assert.Equal(t, models.RoleOwner, owner.Role)
+
+	platform, err := fieldService.CreateField(CreateFieldRequest{Name: "Platform Court", PricePerHour: 100000, Location: "Bandung"})
+	assert.NoError(t, err)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(player.ID, CreateBookingRequest{FieldID: owned.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+	payment, err := paymentService.ProcessPayment(player.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(222000), payment.Amount.Amount)
+
+	other, err := bookingService.CreateBooking(player.ID, CreateBookingRequest{FieldID: platform.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	_, err = paymentService.ProcessPayment(player.ID, CreatePaymentRequest{BookingID: other.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	// Half the payment comes back, and so does half the owner's share
+	_, err = paymentService.RefundPayment(admin.ID, payment.ID, RefundRequest{Amount: 111000, Reason: "lights out"})
+	assert.NoError(t, err)
+
+	earnings, err := payoutService.GetEarnings(owner.ID)
+	assert.NoError(t, err)
+	assert.Len(t, earnings.Earnings, 2, "sale and refund; platform fields earn nothing")
+	assert.Len(t, earnings.Totals, 1)
+	totals := earnings.Totals[0]
+	assert.Equal(t, int64(100000), totals.Gross.Amount, "before tax")
+	assert.Equal(t, int64(10000), totals.Commission.Amount)
+	assert.Equal(t, int64(90000), totals.Net.Amount)
+	assert.Equal(t, int64(90000), totals.Unpaid.Amount)
+	assert.Equal(t, int64(-90000), accountBalance(db, models.AccountOwnerPayables, "IDR"))
+
+	// Owners without a payout account are carried over
+	nextWeek := time.Now().AddDate(0, 0, 7)
+	batch, err := payoutService.GeneratePayoutBatch(nextWeek)
+	assert.NoError(t, err)
+	assert.Empty(t, batch.Payouts)
+
+	_, err = payoutService.SetPayoutAccount(owner.ID, PayoutAccountRequest{HolderName: "Owner", IBAN: "DE89370400440532013001"})
+	assert.Error(t, err, "bad check digits")
+	_, err = payoutService.SetPayoutAccount(owner.ID, PayoutAccountRequest{HolderName: "Owner", IBAN: "de89 3704 0044 0532 0130 00", BIC: "COBADEFFXXX"})
+	assert.NoError(t, err)
+
+	_, err = payoutService.GeneratePayoutBatch(nextWeek)
+	assert.ErrorIs(t, err, ErrPayoutBatchExists)
+
+	batch, err = payoutService.GeneratePayoutBatch(nextWeek.AddDate(0, 0, 7))
+	assert.NoError(t, err)
+	assert.Len(t, batch.Payouts, 1)
+	assert.Equal(t, int64(90000), batch.Payouts[0].Amount.Amount)
+	assert.Equal(t, 2, batch.Payouts[0].Earnings)
+	assert.Equal(t, "DE89370400440532013000", batch.Payouts[0].IBAN)
+
+	export, err := payoutService.ExportCSV(batch)
+	assert.NoError(t, err)
+	assert.Contains(t, string(export), "Owner,DE89370400440532013000,COBADEFFXXX,90000,IDR")
+	_, err = payoutService.ExportSEPA(batch)
+	assert.Error(t, err, "no euro payouts")
+
+	paid, err := payoutService.MarkBatchPaid(batch.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.PayoutPaid, paid.Status)
+	assert.Equal(t, models.PayoutPaid, paid.Payouts[0].Status)
+	_, err = payoutService.MarkBatchPaid(batch.ID)
+	assert.Error(t, err)
+
+	earnings, err = payoutService.GetEarnings(owner.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(90000), earnings.Totals[0].PaidOut.Amount)
+	assert.Equal(t, int64(0), earnings.Totals[0].Unpaid.Amount)
+	assert.Equal(t, int64(0), accountBalance(db, models.AccountOwnerPayables, "IDR"))
+
+	payouts, _, err := payoutService.GetPayouts(owner.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, payouts, 1)
+
+	assertLedgerInvariants(t, db, "IDR")
+}
+
+func TestPayoutService_MonthlySchedule(t *testing.T) {
+	db := setupPayoutTestDB()
+	payoutService := NewPayoutService(db, bookingTestConfig())
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleOwner}
+	db.Create(&owner)
+	db.Create(&models.PayoutAccount{OwnerID: owner.ID, HolderName: "Owner", IBAN: "DE89370400440532013000"})
+
+	weekly := models.Field{Name: "Weekly", PricePerHour: models.NewMoney(100, "EUR"), Location: "Berlin", OwnerID: &owner.ID, PayoutSchedule: models.PayoutWeekly}
+	monthly := models.Field{Name: "Monthly", PricePerHour: models.NewMoney(100, "EUR"), Location: "Berlin", OwnerID: &owner.ID, PayoutSchedule: models.PayoutMonthly}
+	db.Create(&weekly)
+	db.Create(&monthly)
+
+	earned := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	for _, field := range []models.Field{weekly, monthly} {
+		db.Create(&models.OwnerEarning{OwnerID: owner.ID, FieldID: field.ID, PaymentID: 1, Type: models.EarningSale,
+			Gross: models.NewMoney(1250, "EUR"), Commission: models.NewMoney(0, "EUR"), Net: models.NewMoney(1250, "EUR"), CreatedAt: earned})
+	}
+
+	// Mid-March only weekly fields are paid out
+	batch, err := payoutService.GeneratePayoutBatch(time.Date(2026, 3, 18, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "PB-2026-W11", batch.Reference)
+	assert.Equal(t, time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC), batch.PeriodEnd)
+	assert.Len(t, batch.Payouts, 1)
+	assert.Equal(t, int64(1250), batch.Payouts[0].Amount.Amount)
+
+	// The first batch of April pays out monthly fields too
+	batch, err = payoutService.GeneratePayoutBatch(time.Date(2026, 4, 8, 9, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, batch.Payouts, 1)
+	assert.Equal(t, int64(1250), batch.Payouts[0].Amount.Amount)
+
+	_, err = payoutService.ExportSEPA(batch)
+	assert.Error(t, err, "debtor account not configured")
+
+	payoutService.cfg.Payout.DebtorName = "Sports Field Booking"
+	payoutService.cfg.Payout.DebtorIBAN = "NL91ABNA0417164300"
+	sepa, err := payoutService.ExportSEPA(batch)
+	assert.NoError(t, err)
+	xml := string(sepa)
+	assert.True(t, strings.HasPrefix(xml, "<?xml"))
+	assert.Contains(t, xml, `xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"`)
+	assert.Contains(t, xml, `<InstdAmt Ccy="EUR">12.50</InstdAmt>`)
+	assert.Contains(t, xml, "<CtrlSum>12.50</CtrlSum>")
+	assert.Contains(t, xml, "<IBAN>DE89370400440532013000</IBAN>")
+	assert.Contains(t, xml, "<Id>NOTPROVIDED</Id>")
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidPromoCode = errors.New("promo code is not valid")
+
+type PromotionService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewPromotionService(db *gorm.DB, cfg *config.Config) *PromotionService {
+	return &PromotionService{db: db, cfg: cfg}
+}
+
+func (s *PromotionService) WithContext(ctx context.Context) *PromotionService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type CreatePromotionRequest struct {
+	Code                  string              `json:"code" validate:"required"`
+	Description           string              `json:"description"`
+	DiscountType          models.DiscountType `json:"discount_type" validate:"required"`
+	DiscountValue         int64               `json:"discount_value" validate:"required,gt=0"`
+	Currency              string              `json:"currency"`
+	MinSpend              int64               `json:"min_spend"`
+	ValidFrom             *time.Time          `json:"valid_from"`
+	ValidUntil            *time.Time          `json:"valid_until"`
+	SlotStartFrom         string              `json:"slot_start_from"`
+	SlotStartBefore       string              `json:"slot_start_before"`
+	Sport                 string              `json:"sport"`
+	FieldIDs              []uint              `json:"field_ids"`
+	FirstBookingOnly      bool                `json:"first_booking_only"`
+	MaxRedemptions        int                 `json:"max_redemptions"`
+	MaxRedemptionsPerUser int                 `json:"max_redemptions_per_user"`
+}
+
+type UpdatePromotionRequest struct {
+	Description           *string    `json:"description"`
+	ValidUntil            *time.Time `json:"valid_until"`
+	MaxRedemptions        *int       `json:"max_redemptions"`
+	MaxRedemptionsPerUser *int       `json:"max_redemptions_per_user"`
+	Active                *bool      `json:"active"`
+}
+
+func (s *PromotionService) CreatePromotion(req CreatePromotionRequest) (*models.Promotion, error) {
+	code := normalizePromoCode(req.Code)
+	if code == "" {
+		return nil, errors.New("code is required")
+	}
+
+	switch req.DiscountType {
+	case models.DiscountPercentage:
+		if req.DiscountValue < 1 || req.DiscountValue > 100 {
+			return nil, errors.New("percentage discounts must be between 1 and 100")
+		}
+	case models.DiscountFixed:
+		if req.DiscountValue <= 0 {
+			return nil, errors.New("fixed discounts must be positive")
+		}
+	default:
+		return nil, errors.New("discount_type must be percentage or fixed")
+	}
+
+	if req.MinSpend < 0 || req.MaxRedemptions < 0 || req.MaxRedemptionsPerUser < 0 {
+		return nil, errors.New("min_spend and redemption caps cannot be negative")
+	}
+	// Amounts off and minimum spends only mean something in one currency
+	currency := ""
+	if req.Currency != "" || req.DiscountType == models.DiscountFixed || req.MinSpend > 0 {
+		var err error
+		if currency, err = currencyOrDefault(s.cfg, req.Currency); err != nil {
+			return nil, err
+		}
+	}
+	if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom) {
+		return nil, errors.New("valid_until must be after valid_from")
+	}
+	if err := validateSlotWindow(req.SlotStartFrom, req.SlotStartBefore); err != nil {
+		return nil, err
+	}
+
+	var count int64
+	s.db.Model(&models.Promotion{}).Where("code = ?", code).Count(&count)
+	if count > 0 {
+		return nil, errors.New("promo code already exists")
+	}
+
+	promotion := models.Promotion{
+		Code:                  code,
+		Description:           req.Description,
+		DiscountType:          req.DiscountType,
+		DiscountValue:         req.DiscountValue,
+		Currency:              currency,
+		MinSpend:              req.MinSpend,
+		ValidFrom:             req.ValidFrom,
+		ValidUntil:            req.ValidUntil,
+		SlotStartFrom:         req.SlotStartFrom,
+		SlotStartBefore:       req.SlotStartBefore,
+		Sport:                 strings.ToLower(req.Sport),
+		FirstBookingOnly:      req.FirstBookingOnly,
+		MaxRedemptions:        req.MaxRedemptions,
+		MaxRedemptionsPerUser: req.MaxRedemptionsPerUser,
+		Active:                true,
+	}
+
+	if len(req.FieldIDs) > 0 {
+		if err := s.db.Find(&promotion.Fields, req.FieldIDs).Error; err != nil {
+			return nil, err
+		}
+		if len(promotion.Fields) != len(req.FieldIDs) {
+			return nil, errors.New("one or more fields not found")
+		}
+	}
+
+	if err := s.db.Create(&promotion).Error; err != nil {
+		return nil, err
+	}
+
+	return &promotion, nil
+}
+
+var promotionListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "code": "code", "created_at": "created_at"},
+	defaultSort: "-created_at",
+	time:        "created_at",
+}
+
+func (s *PromotionService) GetPromotions(q ListQuery) ([]models.Promotion, *utils.Pagination, error) {
+	var promotions []models.Promotion
+	page, err := q.list(s.db.Preload("Fields"), promotionListSpec, &promotions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return promotions, page, nil
+}
+
+func (s *PromotionService) UpdatePromotion(id uint, req UpdatePromotionRequest) (*models.Promotion, error) {
+	var promotion models.Promotion
+	if err := s.db.First(&promotion, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.ValidUntil != nil {
+		updates["valid_until"] = *req.ValidUntil
+	}
+	if req.MaxRedemptions != nil {
+		if *req.MaxRedemptions < 0 {
+			return nil, errors.New("max_redemptions cannot be negative")
+		}
+		updates["max_redemptions"] = *req.MaxRedemptions
+	}
+	if req.MaxRedemptionsPerUser != nil {
+		if *req.MaxRedemptionsPerUser < 0 {
+			return nil, errors.New("max_redemptions_per_user cannot be negative")
+		}
+		updates["max_redemptions_per_user"] = *req.MaxRedemptionsPerUser
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if err := s.db.Model(&promotion).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	s.db.Preload("Fields").First(&promotion, promotion.ID)
+
+	return &promotion, nil
+}
+
+// applyPromoCode checks that a promo code can be used by the user on the
+// given slot and returns the promotion with the discount it gives. The
+// promotion row is locked so concurrent bookings cannot exceed its caps.
+// Excluded bookings, such as one being rescheduled, do not count against
+// them.
+func applyPromoCode(tx *gorm.DB, userID uint, code string, field models.Field, start time.Time, subtotal models.Money, exclude ...uint) (*models.Promotion, models.Money, error) {
+	none := models.NewMoney(0, subtotal.Currency)
+	var promotion models.Promotion
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Fields").
+		Where("code = ?", normalizePromoCode(code)).
+		First(&promotion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, none, ErrInvalidPromoCode
+		}
+		return nil, none, err
+	}
+
+	now := time.Now()
+	if !promotion.Active ||
+		(promotion.ValidFrom != nil && now.Before(*promotion.ValidFrom)) ||
+		(promotion.ValidUntil != nil && now.After(*promotion.ValidUntil)) {
+		return nil, none, errors.New("promo code is not active")
+	}
+
+	if promotion.Currency != "" && promotion.Currency != subtotal.Currency {
+		return nil, none, fmt.Errorf("promo code is only valid for fields priced in %s", promotion.Currency)
+	}
+	if subtotal.Amount < promotion.MinSpend {
+		return nil, none, fmt.Errorf("promo code requires a minimum spend of %s", models.NewMoney(promotion.MinSpend, promotion.Currency))
+	}
+	if promotion.Sport != "" && !strings.EqualFold(promotion.Sport, field.Sport) {
+		return nil, none, fmt.Errorf("promo code is only valid for %s", promotion.Sport)
+	}
+	if len(promotion.Fields) > 0 {
+		allowed := false
+		for _, f := range promotion.Fields {
+			if f.ID == field.ID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, none, errors.New("promo code is not valid for this field")
+		}
+	}
+	if err := checkSlotWindow(promotion, start, field.Zone()); err != nil {
+		return nil, none, err
+	}
+
+	if promotion.FirstBookingOnly {
+		var previous int64
+		query := tx.Model(&models.Booking{}).Where("user_id = ? AND status NOT IN ?", userID, models.InactiveBookingStatuses).Where(ownBooking)
+		if len(exclude) > 0 {
+			query = query.Where("id NOT IN ?", exclude)
+		}
+		query.Count(&previous)
+		if previous > 0 {
+			return nil, none, errors.New("promo code is only valid on your first booking")
+		}
+	}
+
+	if promotion.MaxRedemptions > 0 {
+		var used int64
+		activeRedemptions(tx, promotion.ID, exclude...).Count(&used)
+		if used >= int64(promotion.MaxRedemptions) {
+			return nil, none, errors.New("promo code has been fully redeemed")
+		}
+	}
+	if promotion.MaxRedemptionsPerUser > 0 {
+		var used int64
+		activeRedemptions(tx, promotion.ID, exclude...).Where("promotion_redemptions.user_id = ?", userID).Where(ownBooking).Count(&used)
+		if used >= int64(promotion.MaxRedemptionsPerUser) {
+			return nil, none, errors.New("you have already used this promo code")
+		}
+	}
+
+	return &promotion, promotion.Discount(subtotal), nil
+}
+
+// activeRedemptions scopes a query to the redemptions of a promotion whose
+// booking still stands, other than those of excluded bookings.
+func activeRedemptions(tx *gorm.DB, promotionID uint, exclude ...uint) *gorm.DB {
+	query := tx.Model(&models.PromotionRedemption{}).
+		Joins("JOIN bookings ON bookings.id = promotion_redemptions.booking_id").
+		Where("promotion_redemptions.promotion_id = ? AND bookings.status NOT IN ?", promotionID, models.InactiveBookingStatuses)
+	if len(exclude) > 0 {
+		query = query.Where("promotion_redemptions.booking_id NOT IN ?", exclude)
+	}
+	return query
+}
+
+// checkSlotWindow rejects slots starting outside the promotion's time-of-day
+// window, evaluated on the local day the slot starts in the field's timezone.
+func checkSlotWindow(promotion models.Promotion, start time.Time, loc *time.Location) error {
+	if promotion.SlotStartFrom != "" {
+		from, err := parseClock(promotion.SlotStartFrom)
+		if err != nil {
+			return err
+		}
+		if start.Before(atClock(start, loc, from)) {
+			return fmt.Errorf("promo code is only valid for slots starting from %s", promotion.SlotStartFrom)
+		}
+	}
+	if promotion.SlotStartBefore != "" {
+		before, err := parseClock(promotion.SlotStartBefore)
+		if err != nil {
+			return err
+		}
+		if !start.Before(atClock(start, loc, before)) {
+			return fmt.Errorf("promo code is only valid for slots starting before %s", promotion.SlotStartBefore)
+		}
+	}
+	return nil
+}
+
+func validateSlotWindow(from, before string) error {
+	var fromClock, beforeClock time.Duration
+	var err error
+	if from != "" {
+		if fromClock, err = parseClock(from); err != nil {
+			return errors.New("slot_start_from must be in HH:MM format")
+		}
+	}
+	if before != "" {
+		if beforeClock, err = parseClock(before); err != nil {
+			return errors.New("slot_start_before must be in HH:MM format")
+		}
+	}
+	if from != "" && before != "" && beforeClock <= fromClock {
+		return errors.New("slot_start_before must be after slot_start_from")
+	}
+	return nil
+}
+
+func normalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
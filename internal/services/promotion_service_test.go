@@ -0,0 +1,235 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupPromotionTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.WaitlistEntry{}, &models.Promotion{}, &models.PromotionRedemption{}, &models.MembershipPlan{}, &models.Subscription{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestPromotionService_CreatePromotion(t *testing.T) {
+	db := setupPromotionTestDB()
+	promotionService := NewPromotionService(db, bookingTestConfig())
+
+	tests := []struct {
+		name    string
+		request CreatePromotionRequest
+		wantErr bool
+	}{
+		{
+			name:    "Percentage over 100",
+			request: CreatePromotionRequest{Code: "HALFPRICE", DiscountType: models.DiscountPercentage, DiscountValue: 150},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown discount type",
+			request: CreatePromotionRequest{Code: "FREE", DiscountType: "bogo", DiscountValue: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Inverted slot window",
+			request: CreatePromotionRequest{Code: "LATE", DiscountType: models.DiscountFixed, DiscountValue: 10000, SlotStartFrom: "20:00", SlotStartBefore: "18:00"},
+			wantErr: true,
+		},
+		{
+			name:    "Successful creation",
+			request: CreatePromotionRequest{Code: "morning20", DiscountType: models.DiscountPercentage, DiscountValue: 20, SlotStartBefore: "12:00"},
+			wantErr: false,
+		},
+		{
+			name:    "Duplicate code",
+			request: CreatePromotionRequest{Code: "MORNING20", DiscountType: models.DiscountFixed, DiscountValue: 10000},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := promotionService.CreatePromotion(tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "MORNING20", result.Code)
+				assert.True(t, result.Active)
+			}
+		})
+	}
+}
+
+func TestBookingService_CreateBookingWithPromoCode(t *testing.T) {
+	db := setupPromotionTestDB()
+	promotionService := NewPromotionService(db, bookingTestConfig())
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+
+	futsal := models.Field{Name: "Futsal Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung", Sport: "futsal"}
+	db.Create(&futsal)
+	tennis := models.Field{Name: "Tennis Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung", Sport: "tennis"}
+	db.Create(&tennis)
+
+	_, err := promotionService.CreatePromotion(CreatePromotionRequest{
+		Code: "MORNING20", DiscountType: models.DiscountPercentage, DiscountValue: 20,
+		SlotStartBefore: "12:00", Sport: "futsal", MaxRedemptionsPerUser: 1,
+	})
+	assert.NoError(t, err)
+	_, err = promotionService.CreatePromotion(CreatePromotionRequest{
+		Code: "FIRSTGAME", DiscountType: models.DiscountFixed, DiscountValue: 50000,
+		FirstBookingOnly: true, MinSpend: 100000,
+	})
+	assert.NoError(t, err)
+
+	day := time.Now().UTC().AddDate(0, 0, 3).Truncate(24 * time.Hour)
+
+	tests := []struct {
+		name         string
+		request      CreateBookingRequest
+		wantErr      bool
+		wantDiscount int64
+	}{
+		{
+			name:    "Unknown code",
+			request: CreateBookingRequest{FieldID: futsal.ID, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(11 * time.Hour), PromoCode: "NOPE"},
+			wantErr: true,
+		},
+		{
+			name:    "Below minimum spend",
+			request: CreateBookingRequest{FieldID: futsal.ID, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(9*time.Hour + 30*time.Minute), PromoCode: "FIRSTGAME"},
+			wantErr: true,
+		},
+		{
+			name:         "First booking discount",
+			request:      CreateBookingRequest{FieldID: tennis.ID, StartTime: day.Add(14 * time.Hour), EndTime: day.Add(16 * time.Hour), PromoCode: "firstgame"},
+			wantDiscount: 50000,
+		},
+		{
+			name:    "First booking code on a second booking",
+			request: CreateBookingRequest{FieldID: tennis.ID, StartTime: day.Add(17 * time.Hour), EndTime: day.Add(18 * time.Hour), PromoCode: "FIRSTGAME"},
+			wantErr: true,
+		},
+		{
+			name:    "Morning code in the afternoon",
+			request: CreateBookingRequest{FieldID: futsal.ID, StartTime: day.Add(13 * time.Hour), EndTime: day.Add(15 * time.Hour), PromoCode: "MORNING20"},
+			wantErr: true,
+		},
+		{
+			name:    "Morning code on another sport",
+			request: CreateBookingRequest{FieldID: tennis.ID, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(11 * time.Hour), PromoCode: "MORNING20"},
+			wantErr: true,
+		},
+		{
+			name:         "Morning discount",
+			request:      CreateBookingRequest{FieldID: futsal.ID, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(11 * time.Hour), PromoCode: "MORNING20"},
+			wantDiscount: 40000,
+		},
+		{
+			name:    "Per-user cap reached",
+			request: CreateBookingRequest{FieldID: futsal.ID, StartTime: day.Add(7 * time.Hour), EndTime: day.Add(8 * time.Hour), PromoCode: "MORNING20"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := bookingService.CreateBooking(user.ID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, int64(200000), result.Subtotal.Amount)
+				assert.Equal(t, tt.wantDiscount, result.DiscountAmount.Amount)
+				assert.Equal(t, result.Subtotal.Sub(result.DiscountAmount), result.TotalPrice)
+				assert.NotNil(t, result.PromotionID)
+			}
+		})
+	}
+
+	var redemptions int64
+	db.Model(&models.PromotionRedemption{}).Count(&redemptions)
+	assert.Equal(t, int64(2), redemptions)
+}
+
+func TestBookingService_QuoteBooking(t *testing.T) {
+	db := setupPromotionTestDB()
+	promotionService := NewPromotionService(db, bookingTestConfig())
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	_, err := promotionService.CreatePromotion(CreatePromotionRequest{Code: "ONCE", DiscountType: models.DiscountFixed, DiscountValue: 30000, MaxRedemptions: 1})
+	assert.NoError(t, err)
+
+	startTime := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	request := CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour), PromoCode: "ONCE"}
+
+	quote, err := bookingService.QuoteBooking(1, request)
+	assert.NoError(t, err)
+	assert.True(t, quote.Available)
+	assert.Equal(t, int64(70000), quote.TotalPrice.Amount)
+
+	// Quoting does not redeem the code or hold the slot
+	_, err = bookingService.CreateBooking(1, request)
+	assert.NoError(t, err)
+
+	_, err = bookingService.QuoteBooking(2, CreateBookingRequest{FieldID: field.ID, StartTime: startTime.Add(time.Hour), EndTime: startTime.Add(2 * time.Hour), PromoCode: "ONCE"})
+	assert.Error(t, err, "global cap reached")
+
+	quote, err = bookingService.QuoteBooking(2, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.False(t, quote.Available)
+}
+
+func TestBookingService_RescheduleWithPromoCode(t *testing.T) {
+	db := setupPromotionTestDB()
+	promotionService := NewPromotionService(db, bookingTestConfig())
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+
+	field := models.Field{Name: "Futsal Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+
+	promotion, err := promotionService.CreatePromotion(CreatePromotionRequest{
+		Code: "MORNING20", DiscountType: models.DiscountPercentage, DiscountValue: 20,
+		SlotStartBefore: "12:00", FirstBookingOnly: true, MaxRedemptions: 1,
+	})
+	assert.NoError(t, err)
+
+	day := time.Now().UTC().AddDate(0, 0, 3).Truncate(24 * time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: day.Add(9 * time.Hour), EndTime: day.Add(10 * time.Hour), PromoCode: "MORNING20"})
+	assert.NoError(t, err)
+
+	// The booking's own redemption does not use up the code
+	booking, err = bookingService.RescheduleBooking(user.ID, booking.ID, RescheduleBookingRequest{StartTime: day.Add(10 * time.Hour), EndTime: day.Add(12 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(40000), booking.DiscountAmount.Amount)
+
+	_, err = bookingService.RescheduleBooking(user.ID, booking.ID, RescheduleBookingRequest{StartTime: day.Add(14 * time.Hour), EndTime: day.Add(15 * time.Hour)})
+	assert.Error(t, err, "outside the promotion's slot window")
+
+	db.Model(&models.Promotion{}).Where("id = ?", promotion.ID).Update("active", false)
+	_, err = bookingService.RescheduleBooking(user.ID, booking.ID, RescheduleBookingRequest{StartTime: day.Add(8 * time.Hour), EndTime: day.Add(9 * time.Hour)})
+	assert.Error(t, err, "the promotion has ended")
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrQuotaExceeded = errors.New("booking quota exceeded")
+
+// Quota codes name the limit a booking would exceed.
+const (
+	QuotaActiveBookings   = "max_active_bookings"
+	QuotaWeeklyFieldHours = "weekly_field_hours"
+	QuotaWeeklySportHours = "weekly_sport_hours"
+	QuotaWeeklyPrimeTime  = "weekly_prime_time"
+)
+
+// QuotaError says which quota a booking would exceed, its limit and how much
+// of it is already used.
+type QuotaError struct {
+	Code    string  `json:"code"`
+	Limit   float64 `json:"limit"`
+	Used    float64 `json:"used"`
+	Message string  `json:"message"`
+}
+
+func (e *QuotaError) Error() string {
+	return e.Message
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+type QuotaService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewQuotaService(db *gorm.DB, cfg *config.Config) *QuotaService {
+	return &QuotaService{db: db, cfg: cfg}
+}
+
+func (s *QuotaService) WithContext(ctx context.Context) *QuotaService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// QuotaUsage is how much of a quota a player has used. Limit and Remaining
+// are null when the quota does not apply.
+type QuotaUsage struct {
+	Limit     *float64 `json:"limit"`
+	Used      float64  `json:"used"`
+	Remaining *float64 `json:"remaining"`
+}
+
+type FieldQuota struct {
+	FieldID   uint   `json:"field_id"`
+	FieldName string `json:"field_name"`
+	QuotaUsage
+}
+
+type SportQuota struct {
+	Sport string `json:"sport"`
+	QuotaUsage
+}
+
+// UserQuotas is a player's quota usage. Weekly usage is listed for the
+// current week in each timezone the club's fields are in, since a booking
+// counts towards the week in its field's timezone.
+type UserQuotas struct {
+	Exempt                bool           `json:"exempt"`
+	ActiveBookings        QuotaUsage     `json:"active_bookings"`
+	WeeklyFieldHoursLimit *float64       `json:"weekly_field_hours_limit"`
+	WeeklySportHoursLimit *float64       `json:"weekly_sport_hours_limit"`
+	Weeks                 []WeeklyQuotas `json:"weeks"`
+}
+
+// WeeklyQuotas is a player's usage of the weekly quotas when booking a field
+// in Timezone. Hours are listed for the fields in the timezone and the
+// sports booked this week; the rest have the full weekly limits left.
+type WeeklyQuotas struct {
+	Timezone  string       `json:"timezone"`
+	WeekStart time.Time    `json:"week_start"`
+	PrimeTime QuotaUsage   `json:"prime_time"`
+	Fields    []FieldQuota `json:"fields"`
+	Sports    []SportQuota `json:"sports"`
+}
+
+// GetUserQuotas shows how much of each quota the player has left.
+func (s *QuotaService) GetUserQuotas(userID uint) (*UserQuotas, error) {
+	limits, exempt, err := quotaLimits(s.db, s.cfg, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active, err := countActiveBookings(s.db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	quotas := &UserQuotas{
+		Exempt:                exempt,
+		ActiveBookings:        newQuotaUsage(limits.MaxActiveBookings, float64(active)),
+		WeeklyFieldHoursLimit: newQuotaUsage(limits.MaxWeeklyFieldHours, 0).Limit,
+		WeeklySportHoursLimit: newQuotaUsage(limits.MaxWeeklySportHours, 0).Limit,
+		Weeks:                 []WeeklyQuotas{},
+	}
+
+	var timezones []string
+	if err := s.db.Model(&models.Field{}).Distinct().Order("timezone").Pluck("timezone", &timezones).Error; err != nil {
+		return nil, err
+	}
+	if len(timezones) == 0 {
+		timezones = []string{s.cfg.Booking.DefaultTimezone}
+	}
+
+	seen := map[string]bool{}
+	for _, timezone := range timezones {
+		loc := models.LoadLocation(timezone)
+		if seen[loc.String()] {
+			continue
+		}
+		seen[loc.String()] = true
+
+		week := WeeklyQuotas{Timezone: loc.String(), WeekStart: weekOf(now, loc), Fields: []FieldQuota{}, Sports: []SportQuota{}}
+		usage, err := weeklyUsage(s.db, s.cfg, userID, week.WeekStart)
+		if err != nil {
+			return nil, err
+		}
+
+		week.PrimeTime = newQuotaUsage(limits.MaxWeeklyPrimeTime, float64(usage.primeTime))
+		for _, field := range usage.fields {
+			if field.Zone().String() != week.Timezone {
+				continue
+			}
+			week.Fields = append(week.Fields, FieldQuota{
+				FieldID:    field.ID,
+				FieldName:  field.Name,
+				QuotaUsage: newQuotaUsage(limits.MaxWeeklyFieldHours, usage.fieldTime[field.ID].Hours()),
+			})
+		}
+		for _, sport := range usage.sports {
+			week.Sports = append(week.Sports, SportQuota{
+				Sport:      sport,
+				QuotaUsage: newQuotaUsage(limits.MaxWeeklySportHours, usage.sportTime[sport].Hours()),
+			})
+		}
+		quotas.Weeks = append(quotas.Weeks, week)
+	}
+	return quotas, nil
+}
+
+// checkBookingQuotas rejects a slot that would take the player over one of
+// their quotas. Bookings in exclude, such as the one being rescheduled, do
+// not count. The player's row stays locked for the rest of the transaction,
+// so their concurrent bookings are counted one after the other.
+func checkBookingQuotas(tx *gorm.DB, cfg *config.Config, userID uint, field models.Field, start, end time.Time, exclude ...uint) error {
+	limits, exempt, err := quotaLimits(tx, cfg, userID)
+	if err != nil || exempt {
+		return err
+	}
+	var player models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", userID).Limit(1).Find(&player).Error; err != nil {
+		return err
+	}
+
+	if limit := limits.MaxActiveBookings; limit > 0 {
+		active, err := countActiveBookings(tx, userID, time.Now(), exclude...)
+		if err != nil {
+			return err
+		}
+		if active >= int64(limit) {
+			return &QuotaError{Code: QuotaActiveBookings, Limit: float64(limit), Used: float64(active),
+				Message: fmt.Sprintf("you already have %d upcoming bookings, the most allowed", active)}
+		}
+	}
+
+	if limits.MaxWeeklyFieldHours == 0 && limits.MaxWeeklySportHours == 0 && limits.MaxWeeklyPrimeTime == 0 {
+		return nil
+	}
+	usage, err := weeklyUsage(tx, cfg, userID, weekOf(start, field.Zone()), exclude...)
+	if err != nil {
+		return err
+	}
+
+	duration := end.Sub(start)
+	if limit := limits.MaxWeeklyFieldHours; limit > 0 && usage.fieldTime[field.ID]+duration > time.Duration(limit)*time.Hour {
+		return &QuotaError{Code: QuotaWeeklyFieldHours, Limit: float64(limit), Used: usage.fieldTime[field.ID].Hours(),
+			Message: fmt.Sprintf("bookings on %s are limited to %d hours a week", field.Name, limit)}
+	}
+	if limit := limits.MaxWeeklySportHours; limit > 0 && field.Sport != "" && usage.sportTime[field.Sport]+duration > time.Duration(limit)*time.Hour {
+		return &QuotaError{Code: QuotaWeeklySportHours, Limit: float64(limit), Used: usage.sportTime[field.Sport].Hours(),
+			Message: fmt.Sprintf("%s bookings are limited to %d hours a week", field.Sport, limit)}
+	}
+	if limit := limits.MaxWeeklyPrimeTime; limit > 0 && isPrimeTime(cfg, field, start, end) && usage.primeTime >= limit {
+		return &QuotaError{Code: QuotaWeeklyPrimeTime, Limit: float64(limit), Used: float64(usage.primeTime),
+			Message: fmt.Sprintf("prime-time bookings are limited to %d a week", limit)}
+	}
+	return nil
+}
+
+// quotaLimits returns the player's quotas: the platform's, raised by their
+// membership. Staff and admins are exempt.
+func quotaLimits(tx *gorm.DB, cfg *config.Config, userID uint) (models.PlanQuotas, bool, error) {
+	var user models.User
+	if err := tx.Select("id", "role").Where("id = ?", userID).Limit(1).Find(&user).Error; err != nil {
+		return models.PlanQuotas{}, false, err
+	}
+	if user.Role == models.RoleStaff || user.Role == models.RoleAdmin {
+		return models.PlanQuotas{}, true, nil
+	}
+
+	limits := models.PlanQuotas{
+		MaxActiveBookings:   cfg.Quota.MaxActiveBookings,
+		MaxWeeklyFieldHours: cfg.Quota.MaxWeeklyFieldHours,
+		MaxWeeklySportHours: cfg.Quota.MaxWeeklySportHours,
+		MaxWeeklyPrimeTime:  cfg.Quota.MaxWeeklyPrimeTime,
+	}
+	if membership := activeMembership(tx, userID); membership != nil {
+		plan := membership.Plan.Quotas
+		limits.MaxActiveBookings = raiseLimit(limits.MaxActiveBookings, plan.MaxActiveBookings)
+		limits.MaxWeeklyFieldHours = raiseLimit(limits.MaxWeeklyFieldHours, plan.MaxWeeklyFieldHours)
+		limits.MaxWeeklySportHours = raiseLimit(limits.MaxWeeklySportHours, plan.MaxWeeklySportHours)
+		limits.MaxWeeklyPrimeTime = raiseLimit(limits.MaxWeeklyPrimeTime, plan.MaxWeeklyPrimeTime)
+	}
+	return limits, false, nil
+}
+
+// raiseLimit applies a plan's quota where it is more generous than the
+// platform's. A platform limit of 0 is already unlimited.
+func raiseLimit(platform, plan int) int {
+	if platform > 0 && plan > platform {
+		return plan
+	}
+	return platform
+}
+
+// countActiveBookings counts the player's bookings that hold a slot and have
+// not ended.
+func countActiveBookings(tx *gorm.DB, userID uint, now time.Time, exclude ...uint) (int64, error) {
+	finished := append([]models.BookingStatus{models.StatusCompleted, models.StatusNoShow}, models.InactiveBookingStatuses...)
+	query := tx.Model(&models.Booking{}).Where("user_id = ? AND status NOT IN ? AND end_time > ?", userID, finished, now).Where(ownBooking)
+	if len(exclude) > 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+	var count int64
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// bookingUsage is how much a player booked in one week.
+type bookingUsage struct {
+	fields    []models.Field
+	sports    []string
+	fieldTime map[uint]time.Duration
+	sportTime map[string]time.Duration
+	primeTime int
+}
+
+// weeklyUsage adds up the player's bookings starting in the week from
+// weekStart, which runs seven calendar days in its timezone.
+func weeklyUsage(tx *gorm.DB, cfg *config.Config, userID uint, weekStart time.Time, exclude ...uint) (*bookingUsage, error) {
+	query := tx.Preload("Field").Where("user_id = ? AND status NOT IN ? AND start_time >= ? AND start_time < ?",
+		userID, models.InactiveBookingStatuses, weekStart, weekStart.AddDate(0, 0, 7)).Where(ownBooking)
+	if len(exclude) > 0 {
+		query = query.Where("id NOT IN ?", exclude)
+	}
+	var bookings []models.Booking
+	if err := query.Order("start_time ASC").Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	usage := &bookingUsage{fieldTime: map[uint]time.Duration{}, sportTime: map[string]time.Duration{}}
+	for _, booking := range bookings {
+		duration := booking.EndTime.Sub(booking.StartTime)
+		if _, seen := usage.fieldTime[booking.FieldID]; !seen {
+			field := booking.Field
+			field.ID = booking.FieldID
+			usage.fields = append(usage.fields, field)
+		}
+		usage.fieldTime[booking.FieldID] += duration
+		if sport := booking.Field.Sport; sport != "" {
+			if _, seen := usage.sportTime[sport]; !seen {
+				usage.sports = append(usage.sports, sport)
+			}
+			usage.sportTime[sport] += duration
+		}
+		if isPrimeTime(cfg, booking.Field, booking.StartTime, booking.EndTime) {
+			usage.primeTime++
+		}
+	}
+	sort.Strings(usage.sports)
+	return usage, nil
+}
+
+// isPrimeTime reports whether a slot overlaps prime time on the local day it
+// starts on the field.
+func isPrimeTime(cfg *config.Config, field models.Field, start, end time.Time) bool {
+	if cfg.Quota.PrimeTimeStart == "" || cfg.Quota.PrimeTimeEnd == "" {
+		return false
+	}
+	from, err := parseClock(cfg.Quota.PrimeTimeStart)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(cfg.Quota.PrimeTimeEnd)
+	if err != nil {
+		return false
+	}
+
+	loc := field.Zone()
+	return start.Before(atClock(start, loc, to)) && end.After(atClock(start, loc, from))
+}
+
+func newQuotaUsage(limit int, used float64) QuotaUsage {
+	usage := QuotaUsage{Used: used}
+	if limit > 0 {
+		total := float64(limit)
+		remaining := total - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.Limit = &total
+		usage.Remaining = &remaining
+	}
+	return usage
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func quotaTestConfig(quota config.QuotaConfig) *config.Config {
+	cfg := bookingTestConfig()
+	cfg.Quota = quota
+	return cfg
+}
+
+func assertQuotaError(t *testing.T, err error, code string) {
+	t.Helper()
+	var quotaErr *QuotaError
+	if assert.ErrorAs(t, err, &quotaErr) {
+		assert.Equal(t, code, quotaErr.Code)
+	}
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+}
+
+func TestQuotaService_ActiveBookings(t *testing.T) {
+	db := setupBookingTestDB()
+	cfg := quotaTestConfig(config.QuotaConfig{MaxActiveBookings: 2})
+	bookingService := NewBookingService(db, cfg)
+	quotaService := NewQuotaService(db, cfg)
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	staff := models.User{Email: "staff@example.com", Name: "Front Desk", Role: models.RoleStaff}
+	db.Create(&staff)
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	book := func(userID uint, hours int) (*models.Booking, error) {
+		slot := start.Add(time.Duration(hours) * time.Hour)
+		return bookingService.CreateBooking(userID, CreateBookingRequest{FieldID: field.ID, StartTime: slot, EndTime: slot.Add(time.Hour)})
+	}
+
+	first, err := book(user.ID, 0)
+	assert.NoError(t, err)
+	_, err = book(user.ID, 1)
+	assert.NoError(t, err)
+	_, err = book(user.ID, 2)
+	assertQuotaError(t, err, QuotaActiveBookings)
+
+	quotas, err := quotaService.GetUserQuotas(user.ID)
+	assert.NoError(t, err)
+	assert.False(t, quotas.Exempt)
+	assert.Equal(t, 2.0, quotas.ActiveBookings.Used)
+	assert.Equal(t, 0.0, *quotas.ActiveBookings.Remaining)
+	if assert.Len(t, quotas.Weeks, 1) {
+		assert.Nil(t, quotas.Weeks[0].PrimeTime.Limit)
+	}
+
+	// Staff are exempt
+	for i := 3; i < 6; i++ {
+		_, err = book(staff.ID, i)
+		assert.NoError(t, err)
+	}
+	quotas, err = quotaService.GetUserQuotas(staff.ID)
+	assert.NoError(t, err)
+	assert.True(t, quotas.Exempt)
+
+	// A membership raises the limit
+	plan := models.MembershipPlan{Name: "Club", Type: models.PlanMembership, Price: models.NewMoney(100000, "IDR"), DurationDays: 30, Active: true, Quotas: models.PlanQuotas{MaxActiveBookings: 3}}
+	db.Create(&plan)
+	db.Create(&models.Subscription{UserID: user.ID, PlanID: plan.ID, Status: models.SubscriptionActive, StartsAt: time.Now().Add(-time.Hour), EndsAt: time.Now().Add(720 * time.Hour)})
+	_, err = book(user.ID, 6)
+	assert.NoError(t, err)
+	_, err = book(user.ID, 7)
+	assertQuotaError(t, err, QuotaActiveBookings)
+
+	// Cancelling frees a booking up
+	_, err = bookingService.CancelBooking(user.ID, first.ID)
+	assert.NoError(t, err)
+	_, err = book(user.ID, 7)
+	assert.NoError(t, err)
+}
+
+func TestQuotaService_WeeklyLimits(t *testing.T) {
+	db := setupBookingTestDB()
+	cfg := quotaTestConfig(config.QuotaConfig{
+		MaxWeeklyFieldHours: 3,
+		MaxWeeklySportHours: 4,
+		MaxWeeklyPrimeTime:  1,
+		PrimeTimeStart:      "17:00",
+		PrimeTimeEnd:        "22:00",
+	})
+	bookingService := NewBookingService(db, cfg)
+	quotaService := NewQuotaService(db, cfg)
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	court := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location", Sport: "tennis"}
+	db.Create(&court)
+	otherCourt := models.Field{Name: "Court 2", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location", Sport: "tennis"}
+	db.Create(&otherCourt)
+	pitch := models.Field{Name: "Pitch", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location", Sport: "football"}
+	db.Create(&pitch)
+
+	// Monday of next week
+	monday := weekOf(time.Now(), time.UTC).AddDate(0, 0, 7)
+	book := func(field models.Field, day, hour, hours int) error {
+		start := monday.AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour)
+		_, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: start, EndTime: start.Add(time.Duration(hours) * time.Hour)})
+		return err
+	}
+
+	assert.NoError(t, book(court, 0, 10, 2))
+	assertQuotaError(t, book(court, 1, 10, 2), QuotaWeeklyFieldHours)
+	assert.NoError(t, book(court, 1, 10, 1))
+
+	// Both courts count towards the tennis hours
+	assertQuotaError(t, book(otherCourt, 2, 10, 2), QuotaWeeklySportHours)
+	assert.NoError(t, book(otherCourt, 2, 18, 1))
+
+	// One prime-time booking a week, on any field
+	assertQuotaError(t, book(pitch, 3, 16, 2), QuotaWeeklyPrimeTime)
+	assert.NoError(t, book(pitch, 3, 14, 2))
+
+	// The week after starts afresh
+	assert.NoError(t, book(court, 7, 18, 3))
+
+	quotas, err := quotaService.GetUserQuotas(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, *quotas.WeeklyFieldHoursLimit)
+	assert.Equal(t, 4.0, *quotas.WeeklySportHoursLimit)
+	if assert.Len(t, quotas.Weeks, 1) {
+		assert.Empty(t, quotas.Weeks[0].Fields)
+		assert.Empty(t, quotas.Weeks[0].Sports)
+	}
+}
+
+func TestQuotaService_WeeksPerTimezone(t *testing.T) {
+	db := setupBookingTestDB()
+	cfg := quotaTestConfig(config.QuotaConfig{MaxWeeklyFieldHours: 3})
+	quotaService := NewQuotaService(db, cfg)
+
+	user := models.User{Email: "test@example.com", Name: "Test User", Role: models.RoleUser}
+	db.Create(&user)
+	london := models.Field{Name: "London Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "London", Timezone: "UTC"}
+	db.Create(&london)
+	auckland := models.Field{Name: "Auckland Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Auckland", Timezone: "Pacific/Auckland"}
+	db.Create(&auckland)
+
+	// Early on Monday in Auckland is still Sunday in UTC, but the booking
+	// counts towards the Auckland week it starts in
+	now := time.Now()
+	loc := auckland.Zone()
+	start := weekOf(now, loc).Add(30 * time.Minute)
+	db.Create(&models.Booking{UserID: user.ID, FieldID: auckland.ID, StartTime: start, EndTime: start.Add(2 * time.Hour), Status: models.StatusPaid})
+
+	quotas, err := quotaService.GetUserQuotas(user.ID)
+	assert.NoError(t, err)
+	if !assert.Len(t, quotas.Weeks, 2) {
+		return
+	}
+	weeks := map[string]WeeklyQuotas{}
+	for _, week := range quotas.Weeks {
+		weeks[week.Timezone] = week
+	}
+
+	assert.Equal(t, weekOf(now, loc), weeks["Pacific/Auckland"].WeekStart)
+	if assert.Len(t, weeks["Pacific/Auckland"].Fields, 1) {
+		assert.Equal(t, auckland.ID, weeks["Pacific/Auckland"].Fields[0].FieldID)
+		assert.Equal(t, 2.0, weeks["Pacific/Auckland"].Fields[0].Used)
+		assert.Equal(t, 1.0, *weeks["Pacific/Auckland"].Fields[0].Remaining)
+	}
+	assert.Equal(t, weekOf(now, time.UTC), weeks["UTC"].WeekStart)
+	assert.Empty(t, weeks["UTC"].Fields)
+}
+
+func TestIsPrimeTime(t *testing.T) {
+	cfg := quotaTestConfig(config.QuotaConfig{PrimeTimeStart: "17:00", PrimeTimeEnd: "22:00"})
+	field := models.Field{}
+	day := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+
+	assert.False(t, isPrimeTime(cfg, field, day.Add(15*time.Hour), day.Add(17*time.Hour)))
+	assert.True(t, isPrimeTime(cfg, field, day.Add(16*time.Hour), day.Add(18*time.Hour)))
+	assert.True(t, isPrimeTime(cfg, field, day.Add(21*time.Hour), day.Add(23*time.Hour)))
+	assert.False(t, isPrimeTime(cfg, field, day.Add(22*time.Hour), day.Add(23*time.Hour)))
+	assert.False(t, isPrimeTime(bookingTestConfig(), field, day.Add(18*time.Hour), day.Add(19*time.Hour)))
+
+	// Prime time is the field's: 17:00 in Jakarta is 10:00 UTC
+	field.Timezone = "Asia/Jakarta"
+	assert.True(t, isPrimeTime(cfg, field, day.Add(10*time.Hour), day.Add(11*time.Hour)))
+	assert.False(t, isPrimeTime(cfg, field, day.Add(17*time.Hour), day.Add(18*time.Hour)))
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Settlement statuses reported by the gateway. Records with any other
+// status, such as refunds, are not captures and are skipped.
+const (
+	SettlementCaptured = "captured"
+	SettlementFailed   = "failed"
+)
+
+type ReconciliationService struct {
+	db *gorm.DB
+}
+
+func NewReconciliationService(db *gorm.DB) *ReconciliationService {
+	return &ReconciliationService{db: db}
+}
+
+func (s *ReconciliationService) WithContext(ctx context.Context) *ReconciliationService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// SettlementRecord is one transaction of a gateway settlement report. An
+// empty status counts as captured.
+type SettlementRecord struct {
+	TransactionID string    `json:"transaction_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	SettledAt     time.Time `json:"settled_at"`
+}
+
+type ReconciliationResult struct {
+	Source  string `json:"source"`
+	Records int    `json:"records"`
+	Matched int    `json:"matched"`
+	Issues  int    `json:"issues"`
+}
+
+type ReconciliationSummary struct {
+	Open   int64                                    `json:"open"`
+	ByType map[models.ReconciliationIssueType]int64 `json:"by_type"`
+}
+
+type ResolveIssueRequest struct {
+	Resolution string `json:"resolution" validate:"required"`
+}
+
+// ParseSettlementFile reads a .csv or .json settlement report.
+func ParseSettlementFile(path string) ([]SettlementRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseSettlement(f, strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."))
+}
+
+// ParseSettlement reads a settlement report in the given format. JSON
+// reports are an array of records; CSV reports have a header row naming the
+// transaction_id, amount, currency, status and settled_at columns, of which
+// the first two are required. Times are RFC 3339.
+func ParseSettlement(r io.Reader, format string) ([]SettlementRecord, error) {
+	var records []SettlementRecord
+	switch format {
+	case "json":
+		if err := json.NewDecoder(r).Decode(&records); err != nil {
+			return nil, fmt.Errorf("invalid settlement report: %w", err)
+		}
+	case "csv":
+		rows, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid settlement report: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil, errors.New("settlement report has no header row")
+		}
+
+		columns := map[string]int{}
+		for i, name := range rows[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		for _, required := range []string{"transaction_id", "amount"} {
+			if _, ok := columns[required]; !ok {
+				return nil, fmt.Errorf("settlement report has no %s column", required)
+			}
+		}
+		value := func(row []string, name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+
+		for n, row := range rows[1:] {
+			amount, err := strconv.ParseInt(value(row, "amount"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid amount", n+2)
+			}
+			record := SettlementRecord{
+				TransactionID: value(row, "transaction_id"),
+				Amount:        amount,
+				Currency:      value(row, "currency"),
+				Status:        value(row, "status"),
+			}
+			if settledAt := value(row, "settled_at"); settledAt != "" {
+				if record.SettledAt, err = time.Parse(time.RFC3339, settledAt); err != nil {
+					return nil, fmt.Errorf("row %d: invalid settled_at", n+2)
+				}
+			}
+			records = append(records, record)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported settlement format %q", format)
+	}
+
+	for i, record := range records {
+		if record.TransactionID == "" {
+			return nil, fmt.Errorf("record %d has no transaction_id", i+1)
+		}
+	}
+	return records, nil
+}
+
+// Reconcile matches a settlement report against payments and follow-up
+// charges by transaction ID and amount, and flags every discrepancy. Gateway
+// payments made during the period the report covers (its earliest to latest
+// settled_at) that it does not mention are flagged as not captured. Wallet
+// payments never reach the gateway and are left out.
+func (s *ReconciliationService) Reconcile(source string, records []SettlementRecord) (*ReconciliationResult, error) {
+	result := &ReconciliationResult{Source: source, Records: len(records)}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		flag := func(issue models.ReconciliationIssue) error {
+			// A report ingested again does not reopen what it already flagged
+			var resolved int64
+			if err := tx.Model(&models.ReconciliationIssue{}).
+				Where("type = ? AND transaction_id = ? AND source = ? AND status = ?", issue.Type, issue.TransactionID, source, models.IssueResolved).
+				Count(&resolved).Error; err != nil || resolved > 0 {
+				return err
+			}
+
+			issue.Source = source
+			issue.Status = models.IssueOpen
+			created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&issue)
+			if created.Error != nil {
+				return created.Error
+			}
+			result.Issues += int(created.RowsAffected)
+			return nil
+		}
+
+		seen := map[string]bool{}
+		var from, to time.Time
+		for _, record := range records {
+			if !record.SettledAt.IsZero() {
+				if from.IsZero() || record.SettledAt.Before(from) {
+					from = record.SettledAt
+				}
+				if record.SettledAt.After(to) {
+					to = record.SettledAt
+				}
+			}
+
+			status := strings.ToLower(record.Status)
+			if status == "" {
+				status = SettlementCaptured
+			}
+			if status != SettlementCaptured && status != SettlementFailed {
+				continue
+			}
+			seen[record.TransactionID] = true
+
+			settled := models.NewMoney(record.Amount, strings.ToUpper(record.Currency))
+			expected, paymentID, paid, found, err := localCapture(tx, record.TransactionID)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case status == SettlementFailed && paid:
+				err = flag(models.ReconciliationIssue{Type: models.IssueNotCaptured, TransactionID: record.TransactionID, PaymentID: paymentID,
+					Expected: expected, Settled: settled, Detail: "paid locally but reported as failed by the gateway"})
+			case status == SettlementFailed:
+				result.Matched++
+			case !found:
+				err = flag(models.ReconciliationIssue{Type: models.IssueNotRecorded, TransactionID: record.TransactionID,
+					Settled: settled, Detail: "captured by the gateway but unknown locally"})
+			case !paid:
+				err = flag(models.ReconciliationIssue{Type: models.IssueNotRecorded, TransactionID: record.TransactionID, PaymentID: paymentID,
+					Expected: expected, Settled: settled, Detail: "captured by the gateway but not paid locally"})
+			case settled.Amount != expected.Amount || !settled.SameCurrency(expected):
+				err = flag(models.ReconciliationIssue{Type: models.IssueAmountMismatch, TransactionID: record.TransactionID, PaymentID: paymentID,
+					Expected: expected, Settled: settled, Detail: fmt.Sprintf("settled %s, recorded %s", settled, expected)})
+			default:
+				result.Matched++
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if from.IsZero() {
+			return nil
+		}
+
+		// Wallet and cash payments never reach the gateway
+		offGateway := []string{models.PaymentMethodWallet, models.PaymentMethodCash}
+		captured := []models.PaymentStatus{models.PaymentCompleted, models.PaymentPartiallyRefunded, models.PaymentRefunded}
+		var payments []models.Payment
+		if err := tx.Where("payment_method NOT IN ? AND status IN ? AND created_at BETWEEN ? AND ?", offGateway, captured, from, to).
+			Find(&payments).Error; err != nil {
+			return err
+		}
+		for _, payment := range payments {
+			if seen[payment.TransactionID] {
+				continue
+			}
+			if err := flag(models.ReconciliationIssue{Type: models.IssueNotCaptured, TransactionID: payment.TransactionID, PaymentID: &payment.ID,
+				Expected: payment.Amount, Detail: "paid locally but missing from the settlement report"}); err != nil {
+				return err
+			}
+		}
+
+		var charges []models.PaymentAdjustment
+		if err := tx.Joins("JOIN payments ON payments.id = payment_adjustments.payment_id").
+			Where("payments.payment_method NOT IN ? AND payment_adjustments.type = ? AND payment_adjustments.status = ?", offGateway, models.AdjustmentCharge, models.PaymentCompleted).
+			Where("payment_adjustments.created_at BETWEEN ? AND ?", from, to).
+			Find(&charges).Error; err != nil {
+			return err
+		}
+		for _, charge := range charges {
+			if seen[charge.TransactionID] {
+				continue
+			}
+			if err := flag(models.ReconciliationIssue{Type: models.IssueNotCaptured, TransactionID: charge.TransactionID, PaymentID: &charge.PaymentID,
+				Expected: charge.Amount, Detail: "charged locally but missing from the settlement report"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// localCapture looks up what was captured locally under a gateway
+// transaction ID, which is either a payment or a follow-up charge.
+func localCapture(tx *gorm.DB, transactionID string) (amount models.Money, paymentID *uint, paid, found bool, err error) {
+	var payment models.Payment
+	err = tx.Where("transaction_id = ?", transactionID).First(&payment).Error
+	if err == nil {
+		paid = payment.Status == models.PaymentCompleted || payment.Status == models.PaymentPartiallyRefunded || payment.Status == models.PaymentRefunded
+		return payment.Amount, &payment.ID, paid, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+
+	var charge models.PaymentAdjustment
+	err = tx.Where("transaction_id = ? AND type = ?", transactionID, models.AdjustmentCharge).First(&charge).Error
+	if err == nil {
+		return charge.Amount, &charge.PaymentID, charge.Status == models.PaymentCompleted, true, nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	return
+}
+
+// ReconcileDirectory reconciles every .csv and .json report in dir, oldest
+// name first, and moves each into a processed/ subdirectory, or failed/ if it
+// cannot be read. It returns the number of new issues.
+func (s *ReconciliationService) ReconcileDirectory(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if !entry.IsDir() && (ext == ".csv" || ext == ".json") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	issues := 0
+	var errs []error
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		target := "processed"
+
+		records, err := ParseSettlementFile(path)
+		if err == nil {
+			var result *ReconciliationResult
+			if result, err = s.Reconcile(name, records); err != nil {
+				// Leave the report in place to retry on the next run
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+				continue
+			}
+			issues += result.Issues
+		} else {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			target = "failed"
+		}
+
+		if err := os.MkdirAll(filepath.Join(dir, target), 0o755); err != nil {
+			return issues, err
+		}
+		if err := os.Rename(path, filepath.Join(dir, target, name)); err != nil {
+			return issues, err
+		}
+	}
+
+	return issues, errors.Join(errs...)
+}
+
+var reconciliationIssueListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at"},
+	defaultSort: "-created_at",
+	status:      "status",
+	time:        "created_at",
+}
+
+// GetIssues lists reconciliation issues, newest first. Only open issues are
+// listed unless the query asks for other statuses, or for all of them; an
+// empty issue type matches every type.
+func (s *ReconciliationService) GetIssues(issueType models.ReconciliationIssueType, q ListQuery) ([]models.ReconciliationIssue, *utils.Pagination, error) {
+	switch q.Status {
+	case "":
+		q.Status = string(models.IssueOpen)
+	case "all":
+		q.Status = ""
+	}
+
+	query := s.db.Model(&models.ReconciliationIssue{})
+	if issueType != "" {
+		query = query.Where("type = ?", issueType)
+	}
+
+	var issues []models.ReconciliationIssue
+	page, err := q.list(query, reconciliationIssueListSpec, &issues)
+	if err != nil {
+		return nil, nil, err
+	}
+	return issues, page, nil
+}
+
+// GetSummary counts the open issues, in all and per type.
+func (s *ReconciliationService) GetSummary() (*ReconciliationSummary, error) {
+	var counts []struct {
+		Type  models.ReconciliationIssueType
+		Count int64
+	}
+	if err := s.db.Model(&models.ReconciliationIssue{}).
+		Select("type, COUNT(*) AS count").
+		Where("status = ?", models.IssueOpen).
+		Group("type").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	summary := &ReconciliationSummary{ByType: map[models.ReconciliationIssueType]int64{}}
+	for _, count := range counts {
+		summary.ByType[count.Type] = count.Count
+		summary.Open += count.Count
+	}
+	return summary, nil
+}
+
+// ResolveIssue closes an issue once an admin has dealt with it, for example
+// by refunding a payment the gateway never captured.
+func (s *ReconciliationService) ResolveIssue(adminID, id uint, req ResolveIssueRequest) (*models.ReconciliationIssue, error) {
+	if strings.TrimSpace(req.Resolution) == "" {
+		return nil, errors.New("resolution is required")
+	}
+
+	var issue models.ReconciliationIssue
+	if err := s.db.First(&issue, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reconciliation issue not found")
+		}
+		return nil, err
+	}
+	if issue.Status == models.IssueResolved {
+		return nil, errors.New("reconciliation issue is already resolved")
+	}
+
+	now := time.Now()
+	issue.Status = models.IssueResolved
+	issue.ResolvedAt = &now
+	issue.ResolvedBy = &adminID
+	issue.Resolution = req.Resolution
+	if err := s.db.Save(&issue).Error; err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
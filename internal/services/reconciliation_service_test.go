@@ -0,0 +1,200 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupReconciliationTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Order{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.PaymentAdjustment{},
+		&models.Refund{}, &models.WaitlistEntry{}, &models.PaymentSplit{}, &models.PaymentShare{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{}, &models.ReconciliationIssue{})
+
+	return db
+}
+
+func TestParseSettlement(t *testing.T) {
+	csvReport := "transaction_id,amount,currency,status,settled_at\nTRX-1,100000,IDR,captured,2026-01-02T10:00:00Z\nTRX-2,5000,IDR,,\n"
+	records, err := ParseSettlement(strings.NewReader(csvReport), "csv")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, "TRX-1", records[0].TransactionID)
+	assert.Equal(t, int64(100000), records[0].Amount)
+	assert.Equal(t, time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC), records[0].SettledAt)
+	assert.True(t, records[1].SettledAt.IsZero())
+
+	jsonReport := `[{"transaction_id": "TRX-1", "amount": 100000, "currency": "IDR", "status": "failed"}]`
+	records, err = ParseSettlement(strings.NewReader(jsonReport), "json")
+	assert.NoError(t, err)
+	assert.Equal(t, SettlementFailed, records[0].Status)
+
+	_, err = ParseSettlement(strings.NewReader("transaction_id,currency\nTRX-1,IDR\n"), "csv")
+	assert.Error(t, err, "missing amount column")
+	_, err = ParseSettlement(strings.NewReader("transaction_id,amount\nTRX-1,ten\n"), "csv")
+	assert.Error(t, err, "invalid amount")
+	_, err = ParseSettlement(strings.NewReader(`[{"amount": 1}]`), "json")
+	assert.Error(t, err, "missing transaction ID")
+	_, err = ParseSettlement(strings.NewReader(""), "xml")
+	assert.Error(t, err)
+}
+
+func TestReconciliationService_Reconcile(t *testing.T) {
+	db := setupReconciliationTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+	reconciliationService := NewReconciliationService(db)
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	var payments []*models.Payment
+	for i := 0; i < 4; i++ {
+		booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime.Add(time.Duration(i) * time.Hour), EndTime: startTime.Add(time.Duration(i+1) * time.Hour)})
+		assert.NoError(t, err)
+		payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+		assert.NoError(t, err)
+		payments = append(payments, payment)
+	}
+
+	// Wallet payments never reach the gateway
+	db.Create(&models.Payment{Amount: models.NewMoney(100000, "IDR"), Status: models.PaymentCompleted, PaymentMethod: models.PaymentMethodWallet, TransactionID: "TRX-WALLET"})
+	// A payment the gateway captured after it failed locally
+	pending := models.Payment{Amount: models.NewMoney(100000, "IDR"), Status: models.PaymentFailed, PaymentMethod: "credit_card", TransactionID: "TRX-FAILED"}
+	db.Create(&pending)
+
+	now := time.Now()
+	records := []SettlementRecord{
+		{TransactionID: payments[0].TransactionID, Amount: 100000, Currency: "IDR", SettledAt: now.Add(-time.Hour)},
+		{TransactionID: payments[1].TransactionID, Amount: 90000, Currency: "IDR", SettledAt: now},
+		{TransactionID: payments[2].TransactionID, Amount: 100000, Currency: "IDR", Status: "failed", SettledAt: now},
+		{TransactionID: "TRX-FAILED", Amount: 100000, Currency: "IDR", Status: "captured", SettledAt: now},
+		{TransactionID: "TRX-STRANGER", Amount: 50000, Currency: "IDR", SettledAt: now.Add(time.Hour)},
+		{TransactionID: payments[0].TransactionID + "-R", Amount: 100000, Currency: "IDR", Status: "refunded", SettledAt: now},
+	}
+
+	result, err := reconciliationService.Reconcile("settlement.csv", records)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, result.Records)
+	assert.Equal(t, 1, result.Matched)
+	assert.Equal(t, 5, result.Issues)
+
+	summary, err := reconciliationService.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), summary.Open)
+	assert.Equal(t, int64(2), summary.ByType[models.IssueNotCaptured], "reported failed and missing from the report")
+	assert.Equal(t, int64(2), summary.ByType[models.IssueNotRecorded], "failed locally and unknown")
+	assert.Equal(t, int64(1), summary.ByType[models.IssueAmountMismatch])
+
+	issues, page, err := reconciliationService.GetIssues("", ListQuery{PerPage: 3})
+	assert.NoError(t, err)
+	assert.Len(t, issues, 3)
+	assert.Equal(t, int64(5), *page.Total)
+	issues, _, err = reconciliationService.GetIssues("", ListQuery{})
+	assert.NoError(t, err)
+	flagged := map[string]models.ReconciliationIssueType{}
+	for _, issue := range issues {
+		flagged[issue.TransactionID] = issue.Type
+		assert.Equal(t, "settlement.csv", issue.Source)
+	}
+	assert.Equal(t, models.IssueAmountMismatch, flagged[payments[1].TransactionID])
+	assert.Equal(t, models.IssueNotCaptured, flagged[payments[2].TransactionID])
+	assert.Equal(t, models.IssueNotCaptured, flagged[payments[3].TransactionID])
+	assert.Equal(t, models.IssueNotRecorded, flagged["TRX-FAILED"])
+	assert.Equal(t, models.IssueNotRecorded, flagged["TRX-STRANGER"])
+	assert.NotContains(t, flagged, "TRX-WALLET")
+
+	// Ingesting the same report again flags nothing new
+	result, err = reconciliationService.Reconcile("settlement.csv", records)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Issues)
+
+	var mismatch models.ReconciliationIssue
+	db.Where("type = ?", models.IssueAmountMismatch).First(&mismatch)
+	_, err = reconciliationService.ResolveIssue(1, mismatch.ID, ResolveIssueRequest{})
+	assert.Error(t, err, "resolution is required")
+	resolved, err := reconciliationService.ResolveIssue(1, mismatch.ID, ResolveIssueRequest{Resolution: "gateway fee deducted"})
+	assert.NoError(t, err)
+	assert.NotNil(t, resolved.ResolvedAt)
+	assert.Equal(t, models.IssueResolved, resolved.Status)
+	_, err = reconciliationService.ResolveIssue(1, mismatch.ID, ResolveIssueRequest{Resolution: "again"})
+	assert.Error(t, err)
+
+	issues, _, err = reconciliationService.GetIssues(models.IssueAmountMismatch, ListQuery{Status: "open"})
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+	summary, err = reconciliationService.GetSummary()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), summary.Open)
+
+	issues, _, err = reconciliationService.GetIssues("", ListQuery{Status: "resolved"})
+	assert.NoError(t, err)
+	assert.Len(t, issues, 1)
+
+	_, _, err = reconciliationService.GetIssues("", ListQuery{Upcoming: true})
+	assert.ErrorIs(t, err, ErrInvalidListQuery)
+
+	// A resolved discrepancy stays resolved when its report comes in again,
+	// but a later report that still shows it flags it anew
+	result, err = reconciliationService.Reconcile("settlement.csv", records)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Issues)
+	result, err = reconciliationService.Reconcile("settlement-2.csv", records[1:2])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Issues)
+	issues, _, err = reconciliationService.GetIssues(models.IssueAmountMismatch, ListQuery{Status: "all"})
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 2) {
+		assert.Equal(t, models.IssueOpen, issues[0].Status)
+		assert.Equal(t, "settlement-2.csv", issues[0].Source)
+		assert.Equal(t, models.IssueResolved, issues[1].Status)
+	}
+}
+
+func TestReconciliationService_ReconcileDirectory(t *testing.T) {
+	db := setupReconciliationTestDB()
+	reconciliationService := NewReconciliationService(db)
+
+	payment := models.Payment{Amount: models.NewMoney(100000, "IDR"), Status: models.PaymentCompleted, PaymentMethod: "credit_card", TransactionID: "TRX-1"}
+	db.Create(&payment)
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "2026-01-01.csv"), []byte("transaction_id,amount,currency\nTRX-1,100000,IDR\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "2026-01-02.json"), []byte(`[{"transaction_id": "TRX-2", "amount": 5000, "currency": "IDR"}]`), 0o644)
+	os.WriteFile(filepath.Join(dir, "2026-01-03.json"), []byte(`not json`), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`ignored`), 0o644)
+
+	issues, err := reconciliationService.ReconcileDirectory(dir)
+	assert.Error(t, err, "the malformed report is reported")
+	assert.Equal(t, 1, issues)
+
+	for _, name := range []string{"2026-01-01.csv", "2026-01-02.json"} {
+		assert.FileExists(t, filepath.Join(dir, "processed", name))
+	}
+	assert.FileExists(t, filepath.Join(dir, "failed", "2026-01-03.json"))
+	assert.FileExists(t, filepath.Join(dir, "notes.txt"))
+
+	// Processed reports are not picked up again
+	issues, err = reconciliationService.ReconcileDirectory(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, issues)
+
+	var issue models.ReconciliationIssue
+	db.First(&issue)
+	assert.Equal(t, "TRX-2", issue.TransactionID)
+	assert.Equal(t, "2026-01-02.json", issue.Source)
+}
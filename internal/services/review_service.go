@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+type ReviewService struct {
+	db *gorm.DB
+}
+
+func NewReviewService(db *gorm.DB) *ReviewService {
+	return &ReviewService{db: db}
+}
+
+func (s *ReviewService) WithContext(ctx context.Context) *ReviewService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type CreateReviewRequest struct {
+	BookingID uint   `json:"booking_id" validate:"required"`
+	Rating    int    `json:"rating" validate:"required,min=1,max=5"`
+	Comment   string `json:"comment"`
+}
+
+type ModerateReviewRequest struct {
+	Status models.ReviewStatus `json:"status" validate:"required"`
+}
+
+func (s *ReviewService) CreateReview(userID uint, req CreateReviewRequest) (*models.Review, error) {
+	if req.Rating < 1 || req.Rating > 5 {
+		return nil, errors.New("rating must be between 1 and 5")
+	}
+
+	var booking models.Booking
+	if err := s.db.First(&booking, req.BookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("booking not found")
+		}
+		return nil, err
+	}
+
+	// Only the player who paid for a finished booking may review it
+	if booking.UserID != userID {
+		return nil, errors.New("booking does not belong to user")
+	}
+	if !booking.IsPaid() {
+		return nil, errors.New("only paid bookings can be reviewed")
+	}
+	if booking.EndTime.After(time.Now()) {
+		return nil, errors.New("booking has not ended yet")
+	}
+
+	var count int64
+	s.db.Model(&models.Review{}).Where("booking_id = ?", booking.ID).Count(&count)
+	if count > 0 {
+		return nil, errors.New("booking has already been reviewed")
+	}
+
+	review := models.Review{
+		BookingID: booking.ID,
+		UserID:    userID,
+		FieldID:   booking.FieldID,
+		Rating:    req.Rating,
+		Comment:   req.Comment,
+		Status:    models.ReviewVisible,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+		return refreshFieldRating(tx, review.FieldID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+var reviewListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at", "rating": "rating"},
+	defaultSort: "-created_at",
+	time:        "created_at",
+}
+
+func (s *ReviewService) GetFieldReviews(fieldID uint, q ListQuery) ([]models.Review, *utils.Pagination, error) {
+	var reviews []models.Review
+	page, err := q.list(s.db.Preload("User").Where("field_id = ? AND status != ?", fieldID, models.ReviewHidden), reviewListSpec, &reviews)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reviews, page, nil
+}
+
+// GetReviews lists reviews for moderation. It shows flagged reviews unless
+// the query asks for other statuses.
+func (s *ReviewService) GetReviews(q ListQuery) ([]models.Review, *utils.Pagination, error) {
+	if q.Status == "" {
+		q.Status = string(models.ReviewFlagged)
+	}
+	spec := reviewListSpec
+	spec.status = "status"
+
+	var reviews []models.Review
+	page, err := q.list(s.db.Preload("User"), spec, &reviews)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reviews, page, nil
+}
+
+func (s *ReviewService) ModerateReview(id uint, req ModerateReviewRequest) (*models.Review, error) {
+	switch req.Status {
+	case models.ReviewVisible, models.ReviewFlagged, models.ReviewHidden:
+	default:
+		return nil, errors.New("invalid review status")
+	}
+
+	var review models.Review
+	if err := s.db.First(&review, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("review not found")
+		}
+		return nil, err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&review).Update("status", req.Status).Error; err != nil {
+			return err
+		}
+		return refreshFieldRating(tx, review.FieldID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// refreshFieldRating recomputes the cached rating aggregate on a field,
+// leaving hidden reviews out of both the average and the count.
+func refreshFieldRating(tx *gorm.DB, fieldID uint) error {
+	var agg struct {
+		Avg   float64
+		Count int
+	}
+	if err := tx.Model(&models.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS avg, COUNT(*) AS count").
+		Where("field_id = ? AND status != ?", fieldID, models.ReviewHidden).
+		Scan(&agg).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Field{}).Where("id = ?", fieldID).Updates(map[string]interface{}{
+		"rating_avg":   agg.Avg,
+		"rating_count": agg.Count,
+	}).Error
+}
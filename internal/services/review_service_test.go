@@ -0,0 +1,137 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupReviewTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Review{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestReviewService_CreateReview(t *testing.T) {
+	db := setupReviewTestDB()
+	reviewService := NewReviewService(db)
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+	other := models.User{Email: "other@example.com", Name: "Other", Role: models.RoleUser}
+	db.Create(&other)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	past := time.Now().Add(-48 * time.Hour)
+	future := time.Now().Add(48 * time.Hour)
+
+	played := models.Booking{UserID: user.ID, FieldID: field.ID, StartTime: past, EndTime: past.Add(time.Hour), Status: models.StatusPaid}
+	db.Create(&played)
+	unpaid := models.Booking{UserID: user.ID, FieldID: field.ID, StartTime: past.Add(2 * time.Hour), EndTime: past.Add(3 * time.Hour), Status: models.StatusPending}
+	db.Create(&unpaid)
+	upcoming := models.Booking{UserID: user.ID, FieldID: field.ID, StartTime: future, EndTime: future.Add(time.Hour), Status: models.StatusPaid}
+	db.Create(&upcoming)
+
+	tests := []struct {
+		name    string
+		userID  uint
+		request CreateReviewRequest
+		wantErr bool
+	}{
+		{
+			name:    "Successful review",
+			userID:  user.ID,
+			request: CreateReviewRequest{BookingID: played.ID, Rating: 4, Comment: "Good pitch"},
+			wantErr: false,
+		},
+		{
+			name:    "Second review for same booking",
+			userID:  user.ID,
+			request: CreateReviewRequest{BookingID: played.ID, Rating: 5},
+			wantErr: true,
+		},
+		{
+			name:    "Booking of another user",
+			userID:  other.ID,
+			request: CreateReviewRequest{BookingID: played.ID, Rating: 1},
+			wantErr: true,
+		},
+		{
+			name:    "Unpaid booking",
+			userID:  user.ID,
+			request: CreateReviewRequest{BookingID: unpaid.ID, Rating: 3},
+			wantErr: true,
+		},
+		{
+			name:    "Booking not ended yet",
+			userID:  user.ID,
+			request: CreateReviewRequest{BookingID: upcoming.ID, Rating: 3},
+			wantErr: true,
+		},
+		{
+			name:    "Rating out of range",
+			userID:  user.ID,
+			request: CreateReviewRequest{BookingID: played.ID, Rating: 6},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := reviewService.CreateReview(tt.userID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, result)
+				assert.Equal(t, field.ID, result.FieldID)
+				assert.Equal(t, models.ReviewVisible, result.Status)
+			}
+		})
+	}
+
+	var updated models.Field
+	db.First(&updated, field.ID)
+	assert.Equal(t, 1, updated.RatingCount)
+	assert.Equal(t, 4.0, updated.RatingAvg)
+}
+
+func TestReviewService_ModerateReview(t *testing.T) {
+	db := setupReviewTestDB()
+	reviewService := NewReviewService(db)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	db.Create(&models.Review{BookingID: 1, UserID: 1, FieldID: field.ID, Rating: 5, Status: models.ReviewVisible})
+	spam := models.Review{BookingID: 2, UserID: 2, FieldID: field.ID, Rating: 1, Status: models.ReviewVisible}
+	db.Create(&spam)
+
+	_, err := reviewService.ModerateReview(spam.ID, ModerateReviewRequest{Status: models.ReviewHidden})
+	assert.NoError(t, err)
+
+	var updated models.Field
+	db.First(&updated, field.ID)
+	assert.Equal(t, 1, updated.RatingCount)
+	assert.Equal(t, 5.0, updated.RatingAvg)
+
+	reviews, _, err := reviewService.GetFieldReviews(field.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, reviews, 1)
+
+	_, err = reviewService.ModerateReview(spam.ID, ModerateReviewRequest{Status: "deleted"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+type SavedSearchService struct {
+	db *gorm.DB
+}
+
+func NewSavedSearchService(db *gorm.DB) *SavedSearchService {
+	return &SavedSearchService{db: db}
+}
+
+func (s *SavedSearchService) WithContext(ctx context.Context) *SavedSearchService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type CreateSavedSearchRequest struct {
+	Name            string        `json:"name"`
+	FieldID         *uint         `json:"field_id"`
+	Location        string        `json:"location"`
+	MaxPricePerHour int64         `json:"max_price_per_hour"`
+	Currency        string        `json:"currency"`
+	FavoritesOnly   bool          `json:"favorites_only"`
+	Weekday         *time.Weekday `json:"weekday"`
+	TimeFrom        string        `json:"time_from" validate:"required"`
+	TimeTo          string        `json:"time_to" validate:"required"`
+}
+
+func (s *SavedSearchService) CreateSavedSearch(userID uint, req CreateSavedSearchRequest) (*models.SavedSearch, error) {
+	from, err := parseClock(req.TimeFrom)
+	if err != nil {
+		return nil, errors.New("time_from must be in HH:MM format")
+	}
+	to, err := parseClock(req.TimeTo)
+	if err != nil {
+		return nil, errors.New("time_to must be in HH:MM format")
+	}
+	if to <= from {
+		return nil, errors.New("time_to must be after time_from")
+	}
+	if req.Weekday != nil && (*req.Weekday < time.Sunday || *req.Weekday > time.Saturday) {
+		return nil, errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+
+	// A price cap only compares against fields priced in its currency
+	var maxPrice models.Money
+	if req.MaxPricePerHour > 0 {
+		currency, err := models.NormalizeCurrency(req.Currency)
+		if err != nil {
+			return nil, fmt.Errorf("max_price_per_hour needs a currency: %w", err)
+		}
+		maxPrice = models.NewMoney(req.MaxPricePerHour, currency)
+	}
+
+	if req.FieldID != nil {
+		var field models.Field
+		if err := s.db.First(&field, *req.FieldID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("field not found")
+			}
+			return nil, err
+		}
+	}
+
+	search := models.SavedSearch{
+		UserID:          userID,
+		Name:            req.Name,
+		FieldID:         req.FieldID,
+		Location:        req.Location,
+		MaxPricePerHour: maxPrice,
+		FavoritesOnly:   req.FavoritesOnly,
+		Weekday:         req.Weekday,
+		TimeFrom:        req.TimeFrom,
+		TimeTo:          req.TimeTo,
+		Active:          true,
+	}
+
+	if err := s.db.Create(&search).Error; err != nil {
+		return nil, err
+	}
+
+	return &search, nil
+}
+
+var savedSearchListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "name": "name", "created_at": "created_at"},
+	defaultSort: "-created_at",
+}
+
+func (s *SavedSearchService) GetSavedSearches(userID uint, q ListQuery) ([]models.SavedSearch, *utils.Pagination, error) {
+	var searches []models.SavedSearch
+	page, err := q.list(s.db.Where("user_id = ?", userID), savedSearchListSpec, &searches)
+	if err != nil {
+		return nil, nil, err
+	}
+	return searches, page, nil
+}
+
+func (s *SavedSearchService) DeleteSavedSearch(userID, id uint) error {
+	result := s.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.SavedSearch{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("saved search not found")
+	}
+	return nil
+}
+
+// freedSlot is a slot released by a booking, with the key that tells its
+// alerts apart from those of other releases.
+type freedSlot struct {
+	booking models.Booking
+	key     string
+}
+
+// MatchFreedSlots looks at bookings that were cancelled, expired or moved
+// away since the given time and notifies every user whose saved search
+// covers the slot that was released. It returns the number of notifications
+// created.
+func (s *SavedSearchService) MatchFreedSlots(since time.Time) (int, error) {
+	now := time.Now()
+
+	var released []models.Booking
+	if err := s.db.Preload("Field").
+		Where("status IN ? AND updated_at > ? AND end_time > ?", models.InactiveBookingStatuses, since, now).
+		Find(&released).Error; err != nil {
+		return 0, err
+	}
+	var moves []models.BookingReschedule
+	if err := s.db.Preload("Field").
+		Where("created_at > ? AND end_time > ?", since, now).
+		Find(&moves).Error; err != nil {
+		return 0, err
+	}
+
+	freed := make([]freedSlot, 0, len(released)+len(moves))
+	for _, booking := range released {
+		freed = append(freed, freedSlot{booking: booking, key: fmt.Sprint(booking.ID)})
+	}
+	for _, move := range moves {
+		booking := models.Booking{
+			ID:        move.BookingID,
+			TenantID:  move.TenantID,
+			UserID:    move.UserID,
+			FieldID:   move.FieldID,
+			Field:     move.Field,
+			StartTime: move.StartTime,
+			EndTime:   move.EndTime,
+		}
+		freed = append(freed, freedSlot{booking: booking, key: fmt.Sprintf("%d-moved-%d", move.BookingID, move.ID)})
+	}
+	if len(freed) == 0 {
+		return 0, nil
+	}
+
+	var searches []models.SavedSearch
+	if err := s.db.Where("active = ?", true).Find(&searches).Error; err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, slot := range freed {
+		booking := slot.booking
+
+		// Someone may have grabbed the slot already, and waitlisted users
+		// get the first shot at it
+		if isSlotTaken(s.db, booking.FieldID, booking.StartTime, booking.EndTime) ||
+			isSlotOffered(s.db, booking.FieldID, booking.StartTime, booking.EndTime, 0) {
+			continue
+		}
+
+		for _, search := range searches {
+			// The job sees every tenant, so keep each club's searches to its own slots
+			if search.TenantID != booking.TenantID || search.UserID == booking.UserID || !s.matches(search, booking) {
+				continue
+			}
+
+			fieldID, bookingID := booking.FieldID, booking.ID
+			start, end := booking.StartTime, booking.EndTime
+			key := fmt.Sprintf("slot-freed:%d:%s", search.ID, slot.key)
+			loc := booking.Field.Zone()
+			notification := models.Notification{
+				TenantID:  search.TenantID,
+				UserID:    search.UserID,
+				Type:      models.NotificationSlotAvailable,
+				Message:   fmt.Sprintf("%s is available from %s to %s", booking.Field.Name, start.In(loc).Format(time.RFC3339), end.In(loc).Format(time.RFC3339)),
+				FieldID:   &fieldID,
+				BookingID: &bookingID,
+				StartTime: &start,
+				EndTime:   &end,
+				DedupeKey: &key,
+			}
+			if err := notify(s.db, &notification); err != nil {
+				return sent, err
+			}
+			if notification.ID != 0 {
+				sent++
+			}
+		}
+	}
+
+	return sent, nil
+}
+
+func (s *SavedSearchService) matches(search models.SavedSearch, booking models.Booking) bool {
+	field := booking.Field
+
+	if search.FieldID != nil {
+		if *search.FieldID != booking.FieldID {
+			return false
+		}
+	} else {
+		if search.Location != "" && !strings.Contains(strings.ToLower(field.Location), strings.ToLower(search.Location)) {
+			return false
+		}
+		if limit := search.MaxPricePerHour; limit.Amount > 0 &&
+			(field.PricePerHour.Currency != limit.Currency || field.PricePerHour.Amount > limit.Amount) {
+			return false
+		}
+		if search.FavoritesOnly {
+			var count int64
+			s.db.Model(&models.FavoriteField{}).Where("user_id = ? AND field_id = ?", search.UserID, booking.FieldID).Count(&count)
+			if count == 0 {
+				return false
+			}
+		}
+	}
+
+	// Weekdays and times of day are the field's
+	loc := field.Zone()
+	if search.Weekday != nil && booking.StartTime.In(loc).Weekday() != *search.Weekday {
+		return false
+	}
+
+	from, err := parseClock(search.TimeFrom)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(search.TimeTo)
+	if err != nil {
+		return false
+	}
+
+	windowStart, windowEnd := atClock(booking.StartTime, loc, from), atClock(booking.StartTime, loc, to)
+	return booking.StartTime.Before(windowEnd) && booking.EndTime.After(windowStart)
+}
+
+// parseClock parses an HH:MM wall clock time into an offset from midnight.
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSavedSearchTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.FavoriteField{}, &models.SavedSearch{}, &models.Notification{}, &models.WaitlistEntry{}, &models.PaymentSplit{}, &models.MembershipPlan{}, &models.Subscription{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestSavedSearchService_CreateSavedSearch(t *testing.T) {
+	db := setupSavedSearchTestDB()
+	savedSearchService := NewSavedSearchService(db)
+
+	friday := time.Friday
+	badDay := time.Weekday(9)
+	missingField := uint(42)
+
+	tests := []struct {
+		name    string
+		request CreateSavedSearchRequest
+		wantErr bool
+	}{
+		{
+			name:    "Successful search",
+			request: CreateSavedSearchRequest{Weekday: &friday, TimeFrom: "18:00", TimeTo: "21:00"},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid time format",
+			request: CreateSavedSearchRequest{TimeFrom: "6pm", TimeTo: "21:00"},
+			wantErr: true,
+		},
+		{
+			name:    "Empty time window",
+			request: CreateSavedSearchRequest{TimeFrom: "21:00", TimeTo: "18:00"},
+			wantErr: true,
+		},
+		{
+			name:    "Invalid weekday",
+			request: CreateSavedSearchRequest{Weekday: &badDay, TimeFrom: "18:00", TimeTo: "21:00"},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown field",
+			request: CreateSavedSearchRequest{FieldID: &missingField, TimeFrom: "18:00", TimeTo: "21:00"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := savedSearchService.CreateSavedSearch(1, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, result)
+				assert.True(t, result.Active)
+			}
+		})
+	}
+}
+
+func TestSavedSearchService_MatchFreedSlots(t *testing.T) {
+	db := setupSavedSearchTestDB()
+	savedSearchService := NewSavedSearchService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	db.Create(&owner)
+	watcher := models.User{Email: "watcher@example.com", Name: "Watcher", Role: models.RoleUser}
+	db.Create(&watcher)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+	other := models.Field{Name: "Court 2", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Jakarta"}
+	db.Create(&other)
+
+	// Next week's evening slot, on whatever weekday that is
+	start := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour).Add(19 * time.Hour)
+	weekday := start.Weekday()
+
+	booking, err := bookingService.CreateBooking(owner.ID, CreateBookingRequest{FieldID: field.ID, StartTime: start, EndTime: start.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	fieldID := field.ID
+	savedSearchService.CreateSavedSearch(watcher.ID, CreateSavedSearchRequest{FieldID: &fieldID, Weekday: &weekday, TimeFrom: "18:00", TimeTo: "21:00"})
+	savedSearchService.CreateSavedSearch(watcher.ID, CreateSavedSearchRequest{Location: "jakarta", TimeFrom: "18:00", TimeTo: "21:00"})
+	savedSearchService.CreateSavedSearch(watcher.ID, CreateSavedSearchRequest{FieldID: &fieldID, TimeFrom: "07:00", TimeTo: "09:00"})
+	savedSearchService.CreateSavedSearch(owner.ID, CreateSavedSearchRequest{FieldID: &fieldID, TimeFrom: "18:00", TimeTo: "21:00"})
+
+	since := time.Now().Add(-time.Minute)
+
+	sent, err := savedSearchService.MatchFreedSlots(since)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	_, err = bookingService.CancelBooking(owner.ID, booking.ID)
+	assert.NoError(t, err)
+
+	sent, err = savedSearchService.MatchFreedSlots(since)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	// A second run over the same window must not alert again
+	sent, err = savedSearchService.MatchFreedSlots(since)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	var notifications []models.Notification
+	db.Where("user_id = ?", watcher.ID).Find(&notifications)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, models.NotificationSlotAvailable, notifications[0].Type)
+}
+
+func TestSavedSearchService_MatchRescheduledSlots(t *testing.T) {
+	db := setupSavedSearchTestDB()
+	savedSearchService := NewSavedSearchService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	db.Create(&owner)
+	watcher := models.User{Email: "watcher@example.com", Name: "Watcher", Role: models.RoleUser}
+	db.Create(&watcher)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+
+	start := time.Now().UTC().AddDate(0, 0, 7).Truncate(24 * time.Hour).Add(19 * time.Hour)
+	booking, err := bookingService.CreateBooking(owner.ID, CreateBookingRequest{FieldID: field.ID, StartTime: start, EndTime: start.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	fieldID := field.ID
+	savedSearchService.CreateSavedSearch(watcher.ID, CreateSavedSearchRequest{FieldID: &fieldID, TimeFrom: "18:00", TimeTo: "21:00"})
+
+	since := time.Now().Add(-time.Minute)
+
+	// Moving the booking to the morning releases the evening slot
+	_, err = bookingService.RescheduleBooking(owner.ID, booking.ID, RescheduleBookingRequest{StartTime: start.Add(-10 * time.Hour), EndTime: start.Add(-9 * time.Hour)})
+	assert.NoError(t, err)
+
+	sent, err := savedSearchService.MatchFreedSlots(since)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	var notification models.Notification
+	db.Where("user_id = ?", watcher.ID).First(&notification)
+	assert.True(t, start.Equal(*notification.StartTime))
+
+	// Moving it back takes the slot again, and the morning is outside the search
+	_, err = bookingService.RescheduleBooking(owner.ID, booking.ID, RescheduleBookingRequest{StartTime: start, EndTime: start.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	sent, err = savedSearchService.MatchFreedSlots(since)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
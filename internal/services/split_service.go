@@ -0,0 +1,379 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SplitService struct {
+	db *gorm.DB
+}
+
+func NewSplitService(db *gorm.DB) *SplitService {
+	return &SplitService{db: db}
+}
+
+func (s *SplitService) WithContext(ctx context.Context) *SplitService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type SplitParticipant struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+	// Amount is in minor units of the booking's currency
+	Amount int64 `json:"amount" validate:"required,gt=0"`
+}
+
+type CreateSplitRequest struct {
+	Deadline   time.Time            `json:"deadline" validate:"required"`
+	OnDeadline models.SplitFallback `json:"on_deadline" validate:"required"`
+	// PaymentMethod charges the owner for unpaid shares, from their wallet
+	// unless a gateway method is given
+	PaymentMethod string             `json:"payment_method"`
+	Participants  []SplitParticipant `json:"participants" validate:"required,min=1,dive"`
+}
+
+// CreateSplit divides an unpaid booking among participants. The shares must
+// add up to the booking price; the owner takes part by listing themselves.
+func (s *SplitService) CreateSplit(ownerID, bookingID uint, req CreateSplitRequest) (*models.PaymentSplit, error) {
+	var booking models.Booking
+	if err := s.db.First(&booking, bookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("booking not found")
+		}
+		return nil, err
+	}
+
+	if booking.UserID != ownerID {
+		return nil, errors.New("booking does not belong to user")
+	}
+	if booking.Status != models.StatusPending {
+		return nil, errors.New("only unpaid bookings can be split")
+	}
+	if booking.OrderID != nil {
+		return nil, errors.New("bookings that are part of an order cannot be split")
+	}
+	if hasPaymentSplit(s.db, booking.ID) {
+		return nil, errors.New("booking is already split")
+	}
+
+	switch req.OnDeadline {
+	case models.SplitChargeOwner, models.SplitRelease:
+	default:
+		return nil, errors.New("on_deadline must be charge_owner or release")
+	}
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = models.PaymentMethodWallet
+	}
+	if req.PaymentMethod == models.PaymentMethodCash {
+		return nil, errors.New("the owner cannot be charged in cash at the deadline")
+	}
+	if !req.Deadline.After(time.Now()) || !req.Deadline.Before(booking.StartTime) {
+		return nil, errors.New("deadline must be in the future and before the booking starts")
+	}
+	if len(req.Participants) == 0 {
+		return nil, errors.New("at least one participant is required")
+	}
+
+	split := models.PaymentSplit{
+		TenantID:      booking.TenantID,
+		BookingID:     booking.ID,
+		OwnerID:       ownerID,
+		Deadline:      req.Deadline,
+		OnDeadline:    req.OnDeadline,
+		PaymentMethod: req.PaymentMethod,
+		Status:        models.SplitOpen,
+	}
+
+	total := models.NewMoney(0, booking.TotalPrice.Currency)
+	seen := map[string]bool{}
+	for _, participant := range req.Participants {
+		if participant.Amount <= 0 {
+			return nil, errors.New("share amounts must be positive")
+		}
+
+		share, err := s.resolveParticipant(participant)
+		if err != nil {
+			return nil, err
+		}
+		if seen[share.Email] {
+			return nil, fmt.Errorf("%s is listed more than once", share.Email)
+		}
+		seen[share.Email] = true
+
+		share.TenantID = booking.TenantID
+		share.BookingID = booking.ID
+		share.Amount = models.NewMoney(participant.Amount, booking.TotalPrice.Currency)
+		share.Status = models.SharePending
+		split.Shares = append(split.Shares, share)
+		total = total.Add(share.Amount)
+	}
+	if total != booking.TotalPrice {
+		return nil, fmt.Errorf("shares add up to %s but the booking costs %s", total, booking.TotalPrice)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&split).Error; err != nil {
+			return err
+		}
+		// The slot stays held until the deadline rather than the hold TTL
+		if err := transitionBooking(tx, &booking, models.StatusConfirmed, &ownerID, "payment split"); err != nil {
+			return err
+		}
+
+		for _, share := range split.Shares {
+			if share.UserID == nil || *share.UserID == ownerID {
+				continue
+			}
+			bookingID := booking.ID
+			key := fmt.Sprintf("payment-share:%d", share.ID)
+			if err := notify(tx, &models.Notification{
+				TenantID:  booking.TenantID,
+				UserID:    *share.UserID,
+				Type:      models.NotificationPaymentShare,
+				Message:   fmt.Sprintf("You have been invited to pay %s towards booking %d before %s", share.Amount, booking.ID, req.Deadline.In(models.LoadLocation(booking.Timezone)).Format(time.RFC3339)),
+				BookingID: &bookingID,
+				DedupeKey: &key,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &split, nil
+}
+
+// GetSplit returns the split of a booking to its owner or a participant.
+func (s *SplitService) GetSplit(userID, bookingID uint) (*models.PaymentSplit, error) {
+	var split models.PaymentSplit
+	if err := s.db.Preload("Shares").Where("booking_id = ?", bookingID).First(&split).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment split not found")
+		}
+		return nil, err
+	}
+
+	if split.OwnerID == userID {
+		return &split, nil
+	}
+
+	var user models.User
+	s.db.First(&user, userID)
+	for _, share := range split.Shares {
+		if (share.UserID != nil && *share.UserID == userID) || strings.EqualFold(share.Email, user.Email) {
+			return &split, nil
+		}
+	}
+
+	return nil, errors.New("payment split not found")
+}
+
+var shareListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at", "amount": "amount_amount"},
+	defaultSort: "-created_at",
+	status:      "status",
+	time:        "created_at",
+}
+
+// GetUserShares lists the shares the user has been invited to pay.
+func (s *SplitService) GetUserShares(userID uint, q ListQuery) ([]models.PaymentShare, *utils.Pagination, error) {
+	var user models.User
+	if err := s.db.First(&user, userID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var shares []models.PaymentShare
+	page, err := q.list(s.db.Where("(user_id = ? OR (user_id IS NULL AND LOWER(email) = ?))", userID, strings.ToLower(user.Email)), shareListSpec, &shares)
+	if err != nil {
+		return nil, nil, err
+	}
+	return shares, page, nil
+}
+
+// ProcessDeadlines settles every open split whose deadline has passed: the
+// owner is charged the unpaid remainder, or the booking is released and the
+// paid shares are refunded, depending on the split's fallback. It returns
+// the number of splits settled.
+func (s *SplitService) ProcessDeadlines() (int, error) {
+	var splits []models.PaymentSplit
+	if err := s.db.Where("status = ? AND deadline < ?", models.SplitOpen, time.Now()).
+		Find(&splits).Error; err != nil {
+		return 0, err
+	}
+
+	settled := 0
+	for i := range splits {
+		split := &splits[i]
+		done := false
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// Lock the booking and then the split and re-read it, so that a
+			// share being paid or the booking being cancelled right now is
+			// either finished first or sees the split settled
+			var booking models.Booking
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, split.BookingID).Error; err != nil {
+				return err
+			}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(split, split.ID).Error; err != nil {
+				return err
+			}
+			if split.Status != models.SplitOpen {
+				return nil
+			}
+			if err := tx.Where("split_id = ?", split.ID).Find(&split.Shares).Error; err != nil {
+				return err
+			}
+
+			done = true
+			reason := "split deadline passed"
+			if split.OnDeadline == models.SplitChargeOwner {
+				// The charge runs in a savepoint, so that a wallet that cannot
+				// cover it leaves nothing behind and the booking is released
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					return chargeSplitOwner(tx, split)
+				})
+				if !errors.Is(err, ErrInsufficientBalance) {
+					return err
+				}
+				reason = "owner could not be charged for unpaid shares"
+			}
+			if _, err := transitionBookings(tx, models.StatusCancelled, nil, reason, "id = ?", split.BookingID); err != nil {
+				return err
+			}
+			return releaseSplit(tx, split)
+		})
+		if err != nil {
+			return settled, err
+		}
+		if done {
+			settled++
+		}
+	}
+
+	return settled, nil
+}
+
+func (s *SplitService) resolveParticipant(participant SplitParticipant) (models.PaymentShare, error) {
+	var share models.PaymentShare
+
+	var user models.User
+	switch {
+	case participant.UserID != 0:
+		if err := s.db.First(&user, participant.UserID).Error; err != nil {
+			return share, fmt.Errorf("user %d not found", participant.UserID)
+		}
+	case participant.Email != "":
+		// Unregistered invitees are kept by email and matched when they pay
+		if err := s.db.Where("LOWER(email) = ?", strings.ToLower(participant.Email)).First(&user).Error; err != nil {
+			share.Email = strings.ToLower(participant.Email)
+			return share, nil
+		}
+	default:
+		return share, errors.New("each participant needs a user_id or an email")
+	}
+
+	share.UserID = &user.ID
+	share.Email = strings.ToLower(user.Email)
+	return share, nil
+}
+
+// chargeSplitOwner charges the owner whatever is left unpaid, from their
+// wallet or through the mock gateway, and marks the booking as paid.
+func chargeSplitOwner(tx *gorm.DB, split *models.PaymentSplit) error {
+	var remaining models.Money
+	for _, share := range split.Shares {
+		if share.Status == models.SharePending {
+			remaining = remaining.Add(share.Amount)
+		}
+	}
+
+	if remaining.Amount > 0 {
+		var booking models.Booking
+		if err := tx.First(&booking, split.BookingID).Error; err != nil {
+			return err
+		}
+		var paidTax int64
+		if err := tx.Model(&models.Payment{}).Where("booking_id = ? AND share_id IS NOT NULL", booking.ID).
+			Select("COALESCE(SUM(tax_amount_amount), 0)").Scan(&paidTax).Error; err != nil {
+			return err
+		}
+		tax := booking.TaxAmount.Sub(models.NewMoney(paidTax, booking.TaxAmount.Currency))
+
+		payment := models.Payment{
+			TenantID:      booking.TenantID,
+			BookingID:     &split.BookingID,
+			UserID:        &split.OwnerID,
+			Amount:        remaining,
+			NetAmount:     remaining.Sub(tax),
+			TaxAmount:     tax,
+			Status:        models.PaymentCompleted,
+			PaymentMethod: split.PaymentMethod,
+			TransactionID: fmt.Sprintf("TRX-S%d-%d", split.ID, time.Now().Unix()),
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		if payment.PaymentMethod == models.PaymentMethodWallet {
+			if err := chargeWallet(tx, &payment, payment.Amount, fmt.Sprintf("Unpaid shares of booking %d", booking.ID)); err != nil {
+				return err
+			}
+		}
+		invoice, err := issueInvoice(tx, &payment)
+		if err != nil {
+			return err
+		}
+		if err := postPaymentJournal(tx, &payment, invoice); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PaymentShare{}).
+			Where("split_id = ? AND status = ?", split.ID, models.SharePending).
+			Update("status", models.ShareCancelled).Error; err != nil {
+			return err
+		}
+	}
+
+	if _, err := transitionBookings(tx, models.StatusPaid, nil, "owner charged for unpaid shares", "id = ?", split.BookingID); err != nil {
+		return err
+	}
+	return tx.Model(split).Update("status", models.SplitOwnerCharged).Error
+}
+
+// releaseSplit closes a split whose booking is no longer going ahead and
+// refunds every share that was already paid.
+func releaseSplit(tx *gorm.DB, split *models.PaymentSplit) error {
+	var payments []models.Payment
+	if err := tx.Where("booking_id = ? AND share_id IS NOT NULL", split.BookingID).Find(&payments).Error; err != nil {
+		return err
+	}
+	for i := range payments {
+		if _, err := issueRefund(tx, &payments[i], payments[i].Amount, "split_released", nil, false); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.PaymentShare{}).
+		Where("split_id = ? AND status = ?", split.ID, models.SharePending).
+		Update("status", models.ShareCancelled).Error; err != nil {
+		return err
+	}
+	return tx.Model(split).Update("status", models.SplitReleased).Error
+}
+
+func hasPaymentSplit(db *gorm.DB, bookingID uint) bool {
+	var count int64
+	db.Model(&models.PaymentSplit{}).Where("booking_id = ?", bookingID).Count(&count)
+	return count > 0
+}
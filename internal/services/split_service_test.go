@@ -0,0 +1,321 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSplitTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.PaymentAdjustment{}, &models.Refund{},
+		&models.PaymentSplit{}, &models.PaymentShare{}, &models.Notification{}, &models.WaitlistEntry{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{},
+		&models.Wallet{}, &models.WalletTransaction{})
+
+	return db
+}
+
+type splitFixture struct {
+	owner   models.User
+	friend  models.User
+	booking *models.Booking
+}
+
+func newSplitFixture(t *testing.T, db *gorm.DB) splitFixture {
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	db.Create(&owner)
+	friend := models.User{Email: "friend@example.com", Name: "Friend", Role: models.RoleUser}
+	db.Create(&friend)
+
+	field := models.Field{Name: "Pitch", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := NewBookingService(db, bookingTestConfig()).CreateBooking(owner.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	return splitFixture{owner: owner, friend: friend, booking: booking}
+}
+
+func TestSplitService_CreateSplit(t *testing.T) {
+	db := setupSplitTestDB()
+	splitService := NewSplitService(db)
+	f := newSplitFixture(t, db)
+
+	deadline := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name    string
+		userID  uint
+		request CreateSplitRequest
+		wantErr bool
+	}{
+		{
+			name:    "Not the owner",
+			userID:  f.friend.ID,
+			request: CreateSplitRequest{Deadline: deadline, OnDeadline: models.SplitRelease, Participants: []SplitParticipant{{UserID: f.friend.ID, Amount: 100000}}},
+			wantErr: true,
+		},
+		{
+			name:    "Shares do not add up",
+			userID:  f.owner.ID,
+			request: CreateSplitRequest{Deadline: deadline, OnDeadline: models.SplitRelease, Participants: []SplitParticipant{{UserID: f.friend.ID, Amount: 40000}}},
+			wantErr: true,
+		},
+		{
+			name:    "Deadline after the booking starts",
+			userID:  f.owner.ID,
+			request: CreateSplitRequest{Deadline: f.booking.StartTime.Add(time.Hour), OnDeadline: models.SplitRelease, Participants: []SplitParticipant{{UserID: f.friend.ID, Amount: 100000}}},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown fallback",
+			userID:  f.owner.ID,
+			request: CreateSplitRequest{Deadline: deadline, OnDeadline: "ignore", Participants: []SplitParticipant{{UserID: f.friend.ID, Amount: 100000}}},
+			wantErr: true,
+		},
+		{
+			name:   "Successful split",
+			userID: f.owner.ID,
+			request: CreateSplitRequest{Deadline: deadline, OnDeadline: models.SplitRelease, Participants: []SplitParticipant{
+				{UserID: f.owner.ID, Amount: 40000},
+				{UserID: f.friend.ID, Amount: 30000},
+				{Email: "newcomer@example.com", Amount: 30000},
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "Already split",
+			userID:  f.owner.ID,
+			request: CreateSplitRequest{Deadline: deadline, OnDeadline: models.SplitRelease, Participants: []SplitParticipant{{UserID: f.friend.ID, Amount: 100000}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := splitService.CreateSplit(tt.userID, f.booking.ID, tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, result.Shares, 3)
+				assert.Equal(t, models.SplitOpen, result.Status)
+			}
+		})
+	}
+}
+
+func TestSplitService_PayShares(t *testing.T) {
+	db := setupSplitTestDB()
+	splitService := NewSplitService(db)
+	paymentService := NewPaymentService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+	f := newSplitFixture(t, db)
+
+	_, err := splitService.CreateSplit(f.owner.ID, f.booking.ID, CreateSplitRequest{
+		Deadline:   time.Now().Add(24 * time.Hour),
+		OnDeadline: models.SplitRelease,
+		Participants: []SplitParticipant{
+			{UserID: f.owner.ID, Amount: 50000},
+			{Email: "friend@example.com", Amount: 50000},
+		},
+	})
+	assert.NoError(t, err)
+
+	// Held past the usual TTL while the split is open
+	expired, err := bookingService.ExpirePendingBookings(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), expired)
+
+	payment, err := paymentService.ProcessPayment(f.friend.ID, CreatePaymentRequest{BookingID: f.booking.ID, PaymentMethod: "e_wallet"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50000), payment.Amount.Amount)
+
+	_, err = paymentService.ProcessPayment(f.friend.ID, CreatePaymentRequest{BookingID: f.booking.ID, PaymentMethod: "e_wallet"})
+	assert.Error(t, err, "a share can only be paid once")
+
+	stranger := models.User{Email: "stranger@example.com", Name: "Stranger", Role: models.RoleUser}
+	db.Create(&stranger)
+	_, err = paymentService.ProcessPayment(stranger.ID, CreatePaymentRequest{BookingID: f.booking.ID, PaymentMethod: "e_wallet"})
+	assert.Error(t, err)
+
+	db.First(f.booking, f.booking.ID)
+	assert.Equal(t, models.StatusConfirmed, f.booking.Status)
+
+	_, err = paymentService.ProcessPayment(f.owner.ID, CreatePaymentRequest{BookingID: f.booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	db.First(f.booking, f.booking.ID)
+	assert.Equal(t, models.StatusPaid, f.booking.Status)
+
+	split, err := splitService.GetSplit(f.friend.ID, f.booking.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SplitCompleted, split.Status)
+}
+
+func TestSplitService_ProcessDeadlines(t *testing.T) {
+	for _, fallback := range []models.SplitFallback{models.SplitChargeOwner, models.SplitRelease} {
+		t.Run(string(fallback), func(t *testing.T) {
+			db := setupSplitTestDB()
+			splitService := NewSplitService(db)
+			paymentService := NewPaymentService(db)
+			f := newSplitFixture(t, db)
+
+			split, err := splitService.CreateSplit(f.owner.ID, f.booking.ID, CreateSplitRequest{
+				Deadline:      time.Now().Add(time.Hour),
+				OnDeadline:    fallback,
+				PaymentMethod: "credit_card",
+				Participants: []SplitParticipant{
+					{UserID: f.owner.ID, Amount: 60000},
+					{UserID: f.friend.ID, Amount: 40000},
+				},
+			})
+			assert.NoError(t, err)
+
+			_, err = paymentService.ProcessPayment(f.friend.ID, CreatePaymentRequest{BookingID: f.booking.ID, PaymentMethod: "e_wallet"})
+			assert.NoError(t, err)
+
+			db.Model(split).Update("deadline", time.Now().Add(-time.Minute))
+
+			settled, err := splitService.ProcessDeadlines()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, settled)
+
+			db.First(f.booking, f.booking.ID)
+			db.First(split, split.ID)
+
+			var refunds int64
+			db.Model(&models.Refund{}).Where("reason = ?", "split_released").Count(&refunds)
+
+			if fallback == models.SplitChargeOwner {
+				assert.Equal(t, models.StatusPaid, f.booking.Status)
+				assert.Equal(t, models.SplitOwnerCharged, split.Status)
+
+				var ownerPayment models.Payment
+				db.Where("booking_id = ? AND user_id = ?", f.booking.ID, f.owner.ID).First(&ownerPayment)
+				assert.Equal(t, int64(60000), ownerPayment.Amount.Amount)
+				assert.Equal(t, int64(0), refunds)
+			} else {
+				assert.Equal(t, models.StatusCancelled, f.booking.Status)
+				assert.Equal(t, models.SplitReleased, split.Status)
+				assert.Equal(t, int64(1), refunds)
+			}
+		})
+	}
+}
+
+func TestSplitService_ChargeOwnerWallet(t *testing.T) {
+	for _, funded := range []bool{true, false} {
+		name := "empty"
+		if funded {
+			name = "funded"
+		}
+		t.Run(name, func(t *testing.T) {
+			db := setupSplitTestDB()
+			splitService := NewSplitService(db)
+			paymentService := NewPaymentService(db)
+			walletService := NewWalletService(db, bookingTestConfig())
+			f := newSplitFixture(t, db)
+
+			if funded {
+				_, err := walletService.TopUp(f.owner.ID, TopUpRequest{Amount: 100000, PaymentMethod: "credit_card"})
+				assert.NoError(t, err)
+			}
+
+			// The owner is charged from their wallet unless they chose a method
+			split, err := splitService.CreateSplit(f.owner.ID, f.booking.ID, CreateSplitRequest{
+				Deadline:   time.Now().Add(time.Hour),
+				OnDeadline: models.SplitChargeOwner,
+				Participants: []SplitParticipant{
+					{UserID: f.owner.ID, Amount: 60000},
+					{UserID: f.friend.ID, Amount: 40000},
+				},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, models.PaymentMethodWallet, split.PaymentMethod)
+
+			_, err = paymentService.ProcessPayment(f.friend.ID, CreatePaymentRequest{BookingID: f.booking.ID, PaymentMethod: "e_wallet"})
+			assert.NoError(t, err)
+
+			db.Model(split).Update("deadline", time.Now().Add(-time.Minute))
+			settled, err := splitService.ProcessDeadlines()
+			assert.NoError(t, err)
+			assert.Equal(t, 1, settled)
+
+			db.First(f.booking, f.booking.ID)
+			db.First(split, split.ID)
+			wallet, err := walletService.GetWallet(f.owner.ID)
+			assert.NoError(t, err)
+			var ownerPayments, refunds int64
+			db.Model(&models.Payment{}).Where("booking_id = ? AND user_id = ?", f.booking.ID, f.owner.ID).Count(&ownerPayments)
+			db.Model(&models.Refund{}).Where("reason = ?", "split_released").Count(&refunds)
+
+			if funded {
+				assert.Equal(t, models.StatusPaid, f.booking.Status)
+				assert.Equal(t, models.SplitOwnerCharged, split.Status)
+				assert.Equal(t, int64(40000), wallet.Balance.Amount)
+				assert.Equal(t, int64(1), ownerPayments)
+				assert.Equal(t, int64(0), refunds)
+			} else {
+				// Nothing is left of the failed charge, and the slot is freed
+				assert.Equal(t, models.StatusCancelled, f.booking.Status)
+				assert.Equal(t, models.SplitReleased, split.Status)
+				assert.Equal(t, int64(0), wallet.Balance.Amount)
+				assert.Equal(t, int64(0), ownerPayments)
+				assert.Equal(t, int64(1), refunds)
+			}
+		})
+	}
+}
+
+func TestSplitService_ShareAfterDeadline(t *testing.T) {
+	db := setupSplitTestDB()
+	splitService := NewSplitService(db)
+	paymentService := NewPaymentService(db)
+	f := newSplitFixture(t, db)
+
+	split, err := splitService.CreateSplit(f.owner.ID, f.booking.ID, CreateSplitRequest{
+		Deadline:      time.Now().Add(time.Hour),
+		OnDeadline:    models.SplitChargeOwner,
+		PaymentMethod: "credit_card",
+		Participants: []SplitParticipant{
+			{UserID: f.owner.ID, Amount: 60000},
+			{UserID: f.friend.ID, Amount: 40000},
+		},
+	})
+	assert.NoError(t, err)
+
+	// The friend's payment read the split and booking just before the
+	// deadline job charged the owner for everything
+	var stale models.PaymentSplit
+	db.First(&stale, split.ID)
+	var booking models.Booking
+	db.First(&booking, f.booking.ID)
+
+	db.Model(split).Update("deadline", time.Now().Add(-time.Minute))
+	settled, err := splitService.ProcessDeadlines()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, settled)
+
+	_, err = paymentService.processSharePayment(f.friend.ID, &booking, &stale, CreatePaymentRequest{BookingID: f.booking.ID, PaymentMethod: "e_wallet"})
+	assert.Error(t, err, "the share was already charged to the owner")
+
+	var payments int64
+	db.Model(&models.Payment{}).Where("booking_id = ?", f.booking.ID).Count(&payments)
+	assert.Equal(t, int64(1), payments)
+
+	settled, err = splitService.ProcessDeadlines()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, settled)
+}
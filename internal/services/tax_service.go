@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+type TaxService struct {
+	db *gorm.DB
+}
+
+func NewTaxService(db *gorm.DB) *TaxService {
+	return &TaxService{db: db}
+}
+
+func (s *TaxService) WithContext(ctx context.Context) *TaxService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+type CreateTaxRateRequest struct {
+	Name      string `json:"name" validate:"required"`
+	Category  string `json:"category"`
+	Rate      int    `json:"rate" validate:"gte=0"`
+	Inclusive bool   `json:"inclusive"`
+}
+
+type UpdateTaxRateRequest struct {
+	Name      *string `json:"name"`
+	Rate      *int    `json:"rate"`
+	Inclusive *bool   `json:"inclusive"`
+	Active    *bool   `json:"active"`
+}
+
+func (s *TaxService) CreateTaxRate(req CreateTaxRateRequest) (*models.TaxRate, error) {
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if err := validateTaxRate(req.Rate); err != nil {
+		return nil, err
+	}
+
+	taxRate := models.TaxRate{
+		Name:      req.Name,
+		Category:  strings.ToLower(req.Category),
+		Rate:      req.Rate,
+		Inclusive: req.Inclusive,
+		Active:    true,
+	}
+	if err := s.checkCategoryFree(taxRate.Category, 0); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(&taxRate).Error; err != nil {
+		return nil, err
+	}
+	return &taxRate, nil
+}
+
+var taxRateListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "name": "name", "category": "category", "rate": "rate"},
+	defaultSort: "category",
+}
+
+func (s *TaxService) GetTaxRates(q ListQuery) ([]models.TaxRate, *utils.Pagination, error) {
+	var taxRates []models.TaxRate
+	page, err := q.list(s.db, taxRateListSpec, &taxRates)
+	if err != nil {
+		return nil, nil, err
+	}
+	return taxRates, page, nil
+}
+
+// UpdateTaxRate changes a tax rate for future bookings. Existing bookings
+// keep the rate they were priced with.
+func (s *TaxService) UpdateTaxRate(id uint, req UpdateTaxRateRequest) (*models.TaxRate, error) {
+	var taxRate models.TaxRate
+	if err := s.db.First(&taxRate, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tax rate not found")
+		}
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		if *req.Name == "" {
+			return nil, errors.New("name cannot be empty")
+		}
+		updates["name"] = *req.Name
+	}
+	if req.Rate != nil {
+		if err := validateTaxRate(*req.Rate); err != nil {
+			return nil, err
+		}
+		updates["rate"] = *req.Rate
+	}
+	if req.Inclusive != nil {
+		updates["inclusive"] = *req.Inclusive
+	}
+	if req.Active != nil {
+		if *req.Active && !taxRate.Active {
+			if err := s.checkCategoryFree(taxRate.Category, taxRate.ID); err != nil {
+				return nil, err
+			}
+		}
+		updates["active"] = *req.Active
+	}
+
+	if err := s.db.Model(&taxRate).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &taxRate, nil
+}
+
+// checkCategoryFree makes sure only one active rate covers each category,
+// including the default one.
+func (s *TaxService) checkCategoryFree(category string, exceptID uint) error {
+	var count int64
+	s.db.Model(&models.TaxRate{}).Where("category = ? AND active = ? AND id != ?", category, true, exceptID).Count(&count)
+	if count == 0 {
+		return nil
+	}
+	if category == "" {
+		return errors.New("an active default tax rate already exists")
+	}
+	return errors.New("an active tax rate already exists for " + category)
+}
+
+func validateTaxRate(rate int) error {
+	if rate < 0 || rate > 10000 {
+		return errors.New("rate must be between 0 and 10000 basis points")
+	}
+	return nil
+}
+
+// resolveTaxRate finds the tax that applies to a field: its own rate, else
+// the rate for its sport, else the default rate. It returns nil when no
+// rate applies.
+func resolveTaxRate(tx *gorm.DB, field models.Field) (*models.TaxRate, error) {
+	var taxRate models.TaxRate
+	if field.TaxRateID != nil {
+		result := tx.Where("active = ?", true).Limit(1).Find(&taxRate, *field.TaxRateID)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return &taxRate, nil
+		}
+	}
+
+	categories := []string{""}
+	if field.Sport != "" {
+		categories = []string{field.Sport, ""}
+	}
+	for _, category := range categories {
+		result := tx.Where("category = ? AND active = ?", category, true).Order("id ASC").Limit(1).Find(&taxRate)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			return &taxRate, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTaxTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.WaitlistEntry{}, &models.PaymentSplit{},
+		&models.PaymentShare{}, &models.Notification{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{},
+		&models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestTaxRate_Split(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    *models.TaxRate
+		amount  int64
+		wantNet int64
+		wantTax int64
+	}{
+		{name: "No rate", rate: nil, amount: 100000, wantNet: 100000, wantTax: 0},
+		{name: "Exclusive", rate: &models.TaxRate{Rate: 1100}, amount: 100000, wantNet: 100000, wantTax: 11000},
+		{name: "Exclusive rounds half up", rate: &models.TaxRate{Rate: 1050}, amount: 333, wantNet: 333, wantTax: 35},
+		{name: "Inclusive", rate: &models.TaxRate{Rate: 1100, Inclusive: true}, amount: 100000, wantNet: 90090, wantTax: 9910},
+		{name: "Inclusive rounds net", rate: &models.TaxRate{Rate: 1000, Inclusive: true}, amount: 200000, wantNet: 181818, wantTax: 18182},
+		{name: "Free slot", rate: &models.TaxRate{Rate: 1100}, amount: 0, wantNet: 0, wantTax: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			net, tax := tt.rate.Split(models.NewMoney(tt.amount, "IDR"))
+			assert.Equal(t, models.NewMoney(tt.wantNet, "IDR"), net)
+			assert.Equal(t, models.NewMoney(tt.wantTax, "IDR"), tax)
+		})
+	}
+}
+
+func TestTaxService_CreateTaxRate(t *testing.T) {
+	db := setupTaxTestDB()
+	taxService := NewTaxService(db)
+
+	tests := []struct {
+		name    string
+		request CreateTaxRateRequest
+		wantErr bool
+	}{
+		{name: "Missing name", request: CreateTaxRateRequest{Rate: 1100}, wantErr: true},
+		{name: "Rate over 100%", request: CreateTaxRateRequest{Name: "VAT", Rate: 10001}, wantErr: true},
+		{name: "Default rate", request: CreateTaxRateRequest{Name: "VAT", Rate: 1100}, wantErr: false},
+		{name: "Second default rate", request: CreateTaxRateRequest{Name: "VAT 12", Rate: 1200}, wantErr: true},
+		{name: "Category rate", request: CreateTaxRateRequest{Name: "Padel VAT", Category: "Padel", Rate: 500, Inclusive: true}, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := taxService.CreateTaxRate(tt.request)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, result.Active)
+			}
+		})
+	}
+}
+
+func TestBookingService_TaxedPricing(t *testing.T) {
+	db := setupTaxTestDB()
+	taxService := NewTaxService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+
+	defaultRate, err := taxService.CreateTaxRate(CreateTaxRateRequest{Name: "VAT", Rate: 1000, Inclusive: true})
+	assert.NoError(t, err)
+	tennisRate, err := taxService.CreateTaxRate(CreateTaxRateRequest{Name: "Tennis VAT", Category: "tennis", Rate: 1100})
+	assert.NoError(t, err)
+	venueRate, err := taxService.CreateTaxRate(CreateTaxRateRequest{Name: "Venue levy", Category: "levy", Rate: 500})
+	assert.NoError(t, err)
+
+	futsal := models.Field{Name: "Futsal", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Hall A", Sport: "futsal"}
+	tennis := models.Field{Name: "Tennis", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Court 1", Sport: "tennis"}
+	venue := models.Field{Name: "Tennis Club", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Club", Sport: "tennis", TaxRateID: &venueRate.ID}
+	db.Create(&futsal)
+	db.Create(&tennis)
+	db.Create(&venue)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	tests := []struct {
+		name      string
+		field     models.Field
+		wantRate  uint
+		wantNet   int64
+		wantTax   int64
+		wantTotal int64
+	}{
+		{name: "Default inclusive rate", field: futsal, wantRate: defaultRate.ID, wantNet: 181818, wantTax: 18182, wantTotal: 200000},
+		{name: "Sport rate added on top", field: tennis, wantRate: tennisRate.ID, wantNet: 200000, wantTax: 22000, wantTotal: 222000},
+		{name: "Field's own rate wins", field: venue, wantRate: venueRate.ID, wantNet: 200000, wantTax: 10000, wantTotal: 210000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CreateBookingRequest{FieldID: tt.field.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour)}
+
+			quote, err := bookingService.QuoteBooking(user.ID, req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTax, quote.TaxAmount.Amount)
+			assert.Equal(t, tt.wantTotal, quote.TotalPrice.Amount)
+
+			booking, err := bookingService.CreateBooking(user.ID, req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantRate, *booking.TaxRateID)
+			assert.Equal(t, tt.wantNet, booking.NetAmount.Amount)
+			assert.Equal(t, tt.wantTax, booking.TaxAmount.Amount)
+			assert.Equal(t, tt.wantTotal, booking.TotalPrice.Amount)
+
+			payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTotal, payment.Amount.Amount)
+			assert.Equal(t, tt.wantNet, payment.NetAmount.Amount)
+			assert.Equal(t, tt.wantTax, payment.TaxAmount.Amount)
+
+			var invoice models.Invoice
+			db.Where("payment_id = ?", payment.ID).First(&invoice)
+			assert.Equal(t, tt.wantTax, invoice.TaxAmount.Amount)
+			assert.Equal(t, tt.wantTotal, invoice.Total.Amount)
+		})
+	}
+
+	// Rate changes only apply to new bookings
+	inactive := false
+	_, err = taxService.UpdateTaxRate(tennisRate.ID, UpdateTaxRateRequest{Active: &inactive})
+	assert.NoError(t, err)
+
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: tennis.ID, StartTime: startTime.Add(4 * time.Hour), EndTime: startTime.Add(5 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultRate.ID, *booking.TaxRateID)
+	assert.Equal(t, int64(100000), booking.TotalPrice.Amount)
+}
+
+func TestPaymentService_ShareTax(t *testing.T) {
+	db := setupTaxTestDB()
+	taxService := NewTaxService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+	splitService := NewSplitService(db)
+
+	_, err := taxService.CreateTaxRate(CreateTaxRateRequest{Name: "VAT", Rate: 1100})
+	assert.NoError(t, err)
+
+	var users []models.User
+	for _, email := range []string{"owner@example.com", "a@example.com", "b@example.com"} {
+		user := models.User{Email: email, Name: "Player", Role: models.RoleUser}
+		db.Create(&user)
+		users = append(users, user)
+	}
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(users[0].ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(222000), booking.TotalPrice.Amount)
+
+	var participants []SplitParticipant
+	for _, user := range users {
+		participants = append(participants, SplitParticipant{UserID: user.ID, Amount: 74000})
+	}
+	_, err = splitService.CreateSplit(users[0].ID, booking.ID, CreateSplitRequest{
+		Deadline:     time.Now().Add(24 * time.Hour),
+		OnDeadline:   models.SplitChargeOwner,
+		Participants: participants,
+	})
+	assert.NoError(t, err)
+
+	var taxes models.Money
+	for i, user := range users {
+		payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+		assert.NoError(t, err)
+		assert.Equal(t, payment.Amount, payment.NetAmount.Add(payment.TaxAmount))
+		if i < len(users)-1 {
+			assert.Equal(t, int64(7333), payment.TaxAmount.Amount)
+		}
+		taxes = taxes.Add(payment.TaxAmount)
+	}
+	assert.Equal(t, booking.TaxAmount, taxes, "the last share absorbs the rounding")
+}
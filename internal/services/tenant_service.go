@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+// slugPattern keeps tenant slugs usable as a DNS label
+var slugPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type TenantService struct {
+	db *gorm.DB
+}
+
+func NewTenantService(db *gorm.DB) *TenantService {
+	return &TenantService{db: db}
+}
+
+type CreateTenantRequest struct {
+	Slug          string `json:"slug" validate:"required"`
+	Name          string `json:"name" validate:"required"`
+	SellerName    string `json:"seller_name"`
+	SellerAddress string `json:"seller_address"`
+	SellerTaxID   string `json:"seller_tax_id"`
+}
+
+type UpdateTenantRequest struct {
+	Name          *string `json:"name"`
+	SellerName    *string `json:"seller_name"`
+	SellerAddress *string `json:"seller_address"`
+	SellerTaxID   *string `json:"seller_tax_id"`
+}
+
+func (s *TenantService) CreateTenant(req CreateTenantRequest) (*models.Tenant, error) {
+	slug := strings.ToLower(strings.TrimSpace(req.Slug))
+	if !slugPattern.MatchString(slug) {
+		return nil, errors.New("slug must be a lowercase DNS label")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+	if _, err := s.GetTenantBySlug(slug); err == nil {
+		return nil, errors.New("slug already taken")
+	}
+
+	tenant := models.Tenant{
+		Slug:          slug,
+		Name:          strings.TrimSpace(req.Name),
+		SellerName:    strings.TrimSpace(req.SellerName),
+		SellerAddress: strings.TrimSpace(req.SellerAddress),
+		SellerTaxID:   strings.TrimSpace(req.SellerTaxID),
+	}
+	if err := s.db.Create(&tenant).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// UpdateTenant renames a tenant or changes the seller details printed on its
+// invoices. Invoices are rendered with the details current at the time.
+func (s *TenantService) UpdateTenant(id uint, req UpdateTenantRequest) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := s.db.First(&tenant, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tenant not found")
+		}
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("name is required")
+		}
+		updates["name"] = name
+	}
+	if req.SellerName != nil {
+		updates["seller_name"] = strings.TrimSpace(*req.SellerName)
+	}
+	if req.SellerAddress != nil {
+		updates["seller_address"] = strings.TrimSpace(*req.SellerAddress)
+	}
+	if req.SellerTaxID != nil {
+		updates["seller_tax_id"] = strings.TrimSpace(*req.SellerTaxID)
+	}
+
+	if err := s.db.Model(&tenant).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+var tenantListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "slug": "slug", "name": "name", "created_at": "created_at"},
+	defaultSort: "id",
+}
+
+func (s *TenantService) GetTenants(q ListQuery) ([]models.Tenant, *utils.Pagination, error) {
+	var tenants []models.Tenant
+	page, err := q.list(s.db, tenantListSpec, &tenants)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tenants, page, nil
+}
+
+func (s *TenantService) GetTenantBySlug(slug string) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := s.db.Where("slug = ?", strings.ToLower(slug)).First(&tenant).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tenant not found")
+		}
+		return nil, err
+	}
+	return &tenant, nil
+}
@@ -0,0 +1,626 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTenantTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+	if err := tenant.Register(db); err != nil {
+		panic("failed to register tenant scoping")
+	}
+
+	db.AutoMigrate(&models.Tenant{}, &models.User{}, &models.Field{}, &models.Order{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.Payment{}, &models.PaymentAdjustment{},
+		&models.Refund{}, &models.WaitlistEntry{}, &models.PaymentSplit{}, &models.PaymentShare{}, &models.Notification{}, &models.Wallet{},
+		&models.WalletTransaction{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{},
+		&models.InvoiceSequence{}, &models.TaxRate{}, &models.Promotion{}, &models.PromotionRedemption{}, &models.Review{},
+		&models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{}, &models.PayoutAccount{}, &models.PayoutBatch{}, &models.Payout{},
+		&models.SavedSearch{}, &models.FavoriteField{})
+
+	return db
+}
+
+// tenantContexts creates two clubs and returns contexts scoped to each.
+func tenantContexts(t *testing.T, db *gorm.DB) (context.Context, context.Context) {
+	t.Helper()
+
+	tenantService := NewTenantService(db)
+	first, err := tenantService.CreateTenant(CreateTenantRequest{Slug: "north", Name: "North Club"})
+	assert.NoError(t, err)
+	second, err := tenantService.CreateTenant(CreateTenantRequest{Slug: "south", Name: "South Club"})
+	assert.NoError(t, err)
+
+	return tenant.NewContext(context.Background(), first.ID), tenant.NewContext(context.Background(), second.ID)
+}
+
+// tenantFixture is a player, an admin and a field of one tenant.
+type tenantFixture struct {
+	player models.User
+	admin  models.User
+	field  models.Field
+}
+
+func newTenantFixture(ctx context.Context, db *gorm.DB) tenantFixture {
+	f := tenantFixture{
+		player: models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser},
+		admin:  models.User{Email: "admin@example.com", Name: "Admin", Role: models.RoleAdmin},
+		field:  models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"},
+	}
+	scoped := db.WithContext(ctx)
+	scoped.Create(&f.player)
+	scoped.Create(&f.admin)
+	scoped.Create(&f.field)
+	return f
+}
+
+func TestTenantService_CreateTenant(t *testing.T) {
+	db := setupTenantTestDB()
+	tenantService := NewTenantService(db)
+
+	_, err := tenantService.CreateTenant(CreateTenantRequest{Slug: "North", Name: "North Club"})
+	assert.NoError(t, err)
+	_, err = tenantService.CreateTenant(CreateTenantRequest{Slug: "north", Name: "Copycat"})
+	assert.Error(t, err, "slug already taken")
+	_, err = tenantService.CreateTenant(CreateTenantRequest{Slug: "north.club", Name: "Dotted"})
+	assert.Error(t, err, "not a DNS label")
+
+	found, err := tenantService.GetTenantBySlug("NORTH")
+	assert.NoError(t, err)
+	assert.Equal(t, "North Club", found.Name)
+}
+
+func TestTenantIsolation_Auth(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	authService := NewAuthService(db, bookingTestConfig())
+
+	// The same email can sign up at both clubs, as two separate users
+	userA, err := authService.WithContext(ctxA).Register(RegisterRequest{Email: "player@example.com", Password: "north-pass", Name: "Player"})
+	assert.NoError(t, err)
+	userB, err := authService.WithContext(ctxB).Register(RegisterRequest{Email: "player@example.com", Password: "south-pass", Name: "Player"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, userA.User.ID, userB.User.ID)
+	assert.NotEqual(t, userA.User.TenantID, userB.User.TenantID)
+
+	_, err = authService.WithContext(ctxA).Register(RegisterRequest{Email: "player@example.com", Password: "again!", Name: "Player"})
+	assert.Error(t, err, "taken within the tenant")
+
+	_, err = authService.WithContext(ctxA).Login(LoginRequest{Email: "player@example.com", Password: "south-pass"})
+	assert.Error(t, err, "the other tenant's password")
+	login, err := authService.WithContext(ctxB).Login(LoginRequest{Email: "player@example.com", Password: "south-pass"})
+	assert.NoError(t, err)
+	assert.Equal(t, userB.User.ID, login.User.ID)
+}
+
+func TestTenantIsolation_Fields(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	fieldService := NewFieldService(db, bookingTestConfig())
+
+	field, err := fieldService.WithContext(ctxA).CreateField(CreateFieldRequest{Name: "North Court", PricePerHour: 100000, Location: "Bandung"})
+	assert.NoError(t, err)
+
+	fields, _, err := fieldService.WithContext(ctxB).GetAllFields(ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+	_, err = fieldService.WithContext(ctxB).GetFieldByID(field.ID)
+	assert.Error(t, err)
+	_, err = fieldService.WithContext(ctxB).UpdateField(field.ID, UpdateFieldRequest{Name: "Taken Over"})
+	assert.Error(t, err)
+	assert.Error(t, fieldService.WithContext(ctxB).DeleteField(field.ID))
+
+	fields, _, err = fieldService.WithContext(ctxA).GetAllFields(ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, fields, 1)
+	assert.Equal(t, "North Court", fields[0].Name)
+
+	// Another tenant's user cannot be made the owner
+	other := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	db.WithContext(ctxB).Create(&other)
+	_, err = fieldService.WithContext(ctxA).CreateField(CreateFieldRequest{Name: "Owned", PricePerHour: 100000, Location: "Bandung", OwnerID: &other.ID})
+	assert.Error(t, err)
+}
+
+func TestTenantIsolation_BookingsAndPayments(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	cfg := bookingTestConfig()
+	bookingService := NewBookingService(db, cfg)
+	orderService := NewOrderService(db, cfg)
+	paymentService := NewPaymentService(db)
+	invoiceService := NewInvoiceService(db, cfg)
+	splitService := NewSplitService(db)
+
+	a := newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	slot := func(fieldID uint, from int) CreateBookingRequest {
+		return CreateBookingRequest{FieldID: fieldID, StartTime: startTime.Add(time.Duration(from) * time.Hour), EndTime: startTime.Add(time.Duration(from+1) * time.Hour)}
+	}
+
+	booking, err := bookingService.WithContext(ctxA).CreateBooking(a.player.ID, slot(a.field.ID, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, a.field.TenantID, booking.TenantID)
+
+	// The other club's field cannot be booked, nor its bookings seen
+	_, err = bookingService.WithContext(ctxB).CreateBooking(b.player.ID, slot(a.field.ID, 1))
+	assert.Error(t, err)
+	_, err = orderService.WithContext(ctxB).CreateOrder(b.player.ID, CreateOrderRequest{Items: []CreateBookingRequest{slot(a.field.ID, 1)}})
+	assert.Error(t, err)
+	_, err = bookingService.WithContext(ctxB).GetBookingByID(booking.ID)
+	assert.Error(t, err)
+	bookings, _, err := bookingService.WithContext(ctxB).GetUserBookings(a.player.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, bookings)
+	_, err = bookingService.WithContext(ctxB).CancelBooking(a.player.ID, booking.ID)
+	assert.Error(t, err)
+	_, err = splitService.WithContext(ctxB).CreateSplit(a.player.ID, booking.ID, CreateSplitRequest{
+		Deadline: time.Now().Add(time.Hour), OnDeadline: models.SplitChargeOwner,
+		Participants: []SplitParticipant{{UserID: a.player.ID, Amount: booking.TotalPrice.Amount}},
+	})
+	assert.Error(t, err)
+
+	_, err = paymentService.WithContext(ctxB).ProcessPayment(a.player.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.Error(t, err)
+	payment, err := paymentService.WithContext(ctxA).ProcessPayment(a.player.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	assert.Equal(t, booking.TenantID, payment.TenantID)
+
+	_, err = paymentService.WithContext(ctxB).RefundPayment(b.admin.ID, payment.ID, RefundRequest{Amount: 1000, Reason: "not yours"})
+	assert.Error(t, err)
+	_, _, err = paymentService.WithContext(ctxB).GetPaymentRefunds(payment.ID, ListQuery{})
+	assert.Error(t, err)
+	_, err = invoiceService.WithContext(ctxB).GetPaymentInvoice(b.admin.ID, true, payment.ID, "")
+	assert.Error(t, err)
+	invoice, err := invoiceService.WithContext(ctxA).GetPaymentInvoice(a.player.ID, false, payment.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, payment.TenantID, invoice.TenantID)
+
+	order, err := orderService.WithContext(ctxA).CreateOrder(a.player.ID, CreateOrderRequest{Items: []CreateBookingRequest{slot(a.field.ID, 2)}})
+	assert.NoError(t, err)
+	_, err = orderService.WithContext(ctxB).GetOrderByID(a.player.ID, order.ID)
+	assert.Error(t, err)
+	_, err = orderService.WithContext(ctxB).CancelOrder(a.player.ID, order.ID)
+	assert.Error(t, err)
+}
+
+func TestTenantIsolation_InvoiceNumbers(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	cfg := bookingTestConfig()
+	bookingService := NewBookingService(db, cfg)
+	paymentService := NewPaymentService(db)
+	invoiceService := NewInvoiceService(db, cfg)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	pay := func(ctx context.Context, fixture tenantFixture, from int) *models.Invoice {
+		at := startTime.Add(time.Duration(from) * time.Hour)
+		booking, err := bookingService.WithContext(ctx).CreateBooking(fixture.player.ID, CreateBookingRequest{FieldID: fixture.field.ID, StartTime: at, EndTime: at.Add(time.Hour)})
+		assert.NoError(t, err)
+		payment, err := paymentService.WithContext(ctx).ProcessPayment(fixture.player.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+		assert.NoError(t, err)
+		var invoice models.Invoice
+		assert.NoError(t, db.WithContext(ctx).Where("payment_id = ?", payment.ID).First(&invoice).Error)
+		return &invoice
+	}
+
+	// Each club numbers its own invoices from 1
+	a := newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+	first := pay(ctxA, a, 0)
+	second := pay(ctxA, a, 1)
+	other := pay(ctxB, b, 0)
+	assert.Equal(t, 1, first.Sequence)
+	assert.Equal(t, 2, second.Sequence)
+	assert.Equal(t, 1, other.Sequence)
+	assert.Equal(t, first.Number, other.Number)
+	assert.Equal(t, b.field.TenantID, other.TenantID)
+
+	// And names itself as the seller
+	tenantB, _ := tenant.FromContext(ctxB)
+	sellerName := "South Sports Ltd"
+	_, err := NewTenantService(db).UpdateTenant(tenantB, UpdateTenantRequest{SellerName: &sellerName})
+	assert.NoError(t, err)
+	html, err := invoiceService.RenderHTML(first)
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), "North Club")
+	assert.NotContains(t, string(html), "South Sports Ltd")
+	html, err = invoiceService.RenderHTML(other)
+	assert.NoError(t, err)
+	assert.Contains(t, string(html), "South Sports Ltd")
+}
+
+func TestTenantIsolation_ClubSettings(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	cfg := bookingTestConfig()
+	promotionService := NewPromotionService(db, cfg)
+	taxService := NewTaxService(db)
+	membershipService := NewMembershipService(db, cfg)
+	bookingService := NewBookingService(db, cfg)
+
+	// Each club may use the same promo code for its own promotion
+	promoA, err := promotionService.WithContext(ctxA).CreatePromotion(CreatePromotionRequest{Code: "SUMMER", DiscountType: models.DiscountFixed, DiscountValue: 20000})
+	assert.NoError(t, err)
+	_, err = promotionService.WithContext(ctxB).CreatePromotion(CreatePromotionRequest{Code: "SUMMER", DiscountType: models.DiscountFixed, DiscountValue: 5000})
+	assert.NoError(t, err)
+	_, err = promotionService.WithContext(ctxA).CreatePromotion(CreatePromotionRequest{Code: "SUMMER", DiscountType: models.DiscountFixed, DiscountValue: 1000})
+	assert.Error(t, err)
+	_, err = promotionService.WithContext(ctxA).CreatePromotion(CreatePromotionRequest{Code: "NORTHONLY", DiscountType: models.DiscountFixed, DiscountValue: 1000})
+	assert.NoError(t, err)
+
+	promotions, _, err := promotionService.WithContext(ctxB).GetPromotions(ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, promotions, 1)
+	assert.Equal(t, int64(5000), promotions[0].DiscountValue)
+	active := false
+	_, err = promotionService.WithContext(ctxB).UpdatePromotion(promoA.ID, UpdatePromotionRequest{Active: &active})
+	assert.Error(t, err)
+
+	_, err = taxService.WithContext(ctxA).CreateTaxRate(CreateTaxRateRequest{Name: "VAT", Rate: 1100})
+	assert.NoError(t, err)
+	taxRates, _, err := taxService.WithContext(ctxB).GetTaxRates(ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, taxRates)
+
+	_, err = membershipService.WithContext(ctxA).CreatePlan(CreatePlanRequest{Name: "Gold", Type: models.PlanMembership, Price: 200000, DurationDays: 30, DiscountPercent: 10})
+	assert.NoError(t, err)
+	plans, _, err := membershipService.WithContext(ctxB).GetPlans(ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, plans)
+
+	// Bookings only see their own club's promotions and taxes
+	b := newTenantFixture(ctxB, db)
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	req := CreateBookingRequest{FieldID: b.field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour), PromoCode: "NORTHONLY"}
+	_, err = bookingService.WithContext(ctxB).CreateBooking(b.player.ID, req)
+	assert.Error(t, err)
+	req.PromoCode = "SUMMER"
+	booking, err := bookingService.WithContext(ctxB).CreateBooking(b.player.ID, req)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5000), booking.DiscountAmount.Amount)
+	assert.Equal(t, int64(0), booking.TaxAmount.Amount, "the other club's VAT")
+}
+
+func TestTenantIsolation_ReviewsAndWallets(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	reviewService := NewReviewService(db)
+	walletService := NewWalletService(db, bookingTestConfig())
+
+	a := newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+	review := models.Review{BookingID: 1, UserID: a.player.ID, FieldID: a.field.ID, Rating: 1, Comment: "Flooded", Status: models.ReviewFlagged}
+	db.WithContext(ctxA).Create(&review)
+
+	flagged, _, err := reviewService.WithContext(ctxB).GetReviews(ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, flagged)
+	_, err = reviewService.WithContext(ctxB).ModerateReview(review.ID, ModerateReviewRequest{Status: models.ReviewHidden})
+	assert.Error(t, err)
+	flagged, _, err = reviewService.WithContext(ctxA).GetReviews(ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, flagged, 1)
+
+	_, err = walletService.WithContext(ctxB).AdjustBalance(b.admin.ID, a.player.ID, WalletAdjustmentRequest{Amount: 10000, Reason: "free money"})
+	assert.Error(t, err)
+	_, err = walletService.WithContext(ctxA).AdjustBalance(a.admin.ID, a.player.ID, WalletAdjustmentRequest{Amount: 10000, Reason: "sorry"})
+	assert.NoError(t, err)
+}
+
+func TestTenantIsolation_SavedSearchAlerts(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+	savedSearchService := NewSavedSearchService(db)
+
+	a := newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+	watcher := models.User{Email: "watcher@example.com", Name: "Watcher", Role: models.RoleUser}
+	db.WithContext(ctxA).Create(&watcher)
+
+	// Both clubs have a player watching for any court in Bandung
+	search := CreateSavedSearchRequest{Location: "Bandung", TimeFrom: "00:00", TimeTo: "23:59"}
+	_, err := savedSearchService.WithContext(ctxA).CreateSavedSearch(watcher.ID, search)
+	assert.NoError(t, err)
+	_, err = savedSearchService.WithContext(ctxB).CreateSavedSearch(b.player.ID, search)
+	assert.NoError(t, err)
+
+	startTime := time.Now().Add(48 * time.Hour).Truncate(24 * time.Hour).Add(10 * time.Hour)
+	booking, err := bookingService.WithContext(ctxA).CreateBooking(a.player.ID, CreateBookingRequest{FieldID: a.field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	_, err = bookingService.WithContext(ctxA).CancelBooking(a.player.ID, booking.ID)
+	assert.NoError(t, err)
+
+	// The job runs without a tenant, but only alerts the club's own players
+	sent, err := savedSearchService.MatchFreedSlots(time.Now().Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	var count int64
+	db.Model(&models.Notification{}).Where("user_id = ?", watcher.ID).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Model(&models.Notification{}).Where("user_id = ?", b.player.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestTenantIsolation_SplitDeadlines(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+	splitService := NewSplitService(db)
+
+	newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.WithContext(ctxB).CreateBooking(b.player.ID, CreateBookingRequest{FieldID: b.field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	split, err := splitService.WithContext(ctxB).CreateSplit(b.player.ID, booking.ID, CreateSplitRequest{
+		Deadline:      time.Now().Add(time.Hour),
+		OnDeadline:    models.SplitChargeOwner,
+		PaymentMethod: "credit_card",
+		Participants: []SplitParticipant{
+			{UserID: b.player.ID, Amount: 60000},
+			{UserID: b.admin.ID, Amount: 40000},
+		},
+	})
+	assert.NoError(t, err)
+	_, err = paymentService.WithContext(ctxB).ProcessPayment(b.admin.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "e_wallet"})
+	assert.NoError(t, err)
+
+	db.Model(split).Update("deadline", time.Now().Add(-time.Minute))
+
+	// The job runs without a tenant, but charges the owner in their own club
+	settled, err := splitService.ProcessDeadlines()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, settled)
+
+	var remainder models.Payment
+	assert.NoError(t, db.Where("booking_id = ? AND user_id = ?", booking.ID, b.player.ID).First(&remainder).Error)
+	assert.Equal(t, b.field.TenantID, remainder.TenantID)
+
+	var invoice models.Invoice
+	assert.NoError(t, db.Where("payment_id = ?", remainder.ID).First(&invoice).Error)
+	assert.Equal(t, b.field.TenantID, invoice.TenantID)
+	assert.Equal(t, 2, invoice.Sequence, "follows the share's invoice in the same club")
+}
+
+func TestTenantIsolation_SplitShares(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+	splitService := NewSplitService(db)
+
+	a := newTenantFixture(ctxA, db)
+	newTenantFixture(ctxB, db)
+
+	// A friend is invited by email before they have an account anywhere
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.WithContext(ctxA).CreateBooking(a.player.ID, CreateBookingRequest{FieldID: a.field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	split, err := splitService.WithContext(ctxA).CreateSplit(a.player.ID, booking.ID, CreateSplitRequest{
+		Deadline:   time.Now().Add(time.Hour),
+		OnDeadline: models.SplitRelease,
+		Participants: []SplitParticipant{
+			{UserID: a.player.ID, Amount: 60000},
+			{Email: "friend@example.com", Amount: 40000},
+		},
+	})
+	assert.NoError(t, err)
+	for _, share := range split.Shares {
+		assert.Equal(t, booking.TenantID, share.TenantID)
+	}
+
+	// Signing up with that email at the other club does not reveal the share
+	stranger := models.User{Email: "friend@example.com", Name: "Stranger", Role: models.RoleUser}
+	db.WithContext(ctxB).Create(&stranger)
+	shares, _, err := splitService.WithContext(ctxB).GetUserShares(stranger.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, shares)
+	_, err = splitService.WithContext(ctxB).GetSplit(stranger.ID, booking.ID)
+	assert.Error(t, err)
+
+	friend := models.User{Email: "friend@example.com", Name: "Friend", Role: models.RoleUser}
+	db.WithContext(ctxA).Create(&friend)
+	shares, _, err = splitService.WithContext(ctxA).GetUserShares(friend.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, shares, 1)
+}
+
+func TestTenantIsolation_WaitlistAndNotifications(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+	waitlistService := NewWaitlistService(db)
+	notificationService := NewNotificationService(db)
+
+	newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+	waiting := models.User{Email: "waiting@example.com", Name: "Waiting", Role: models.RoleUser}
+	db.WithContext(ctxB).Create(&waiting)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	slot := CreateBookingRequest{FieldID: b.field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)}
+	booking, err := bookingService.WithContext(ctxB).CreateBooking(b.player.ID, slot)
+	assert.NoError(t, err)
+	entry, err := waitlistService.WithContext(ctxB).JoinWaitlist(waiting.ID, JoinWaitlistRequest{FieldID: b.field.ID, StartTime: slot.StartTime, EndTime: slot.EndTime})
+	assert.NoError(t, err)
+	assert.Equal(t, b.field.TenantID, entry.TenantID)
+
+	entries, _, err := waitlistService.WithContext(ctxA).GetUserWaitlist(waiting.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	assert.Error(t, waitlistService.WithContext(ctxA).LeaveWaitlist(waiting.ID, entry.ID))
+
+	// The job runs without a tenant, but files what it creates under the club
+	_, err = bookingService.WithContext(ctxB).CancelBooking(b.player.ID, booking.ID)
+	assert.NoError(t, err)
+	offered, err := waitlistService.ProcessWaitlist(30 * time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, offered)
+
+	var notification models.Notification
+	assert.NoError(t, db.Where("user_id = ?", waiting.ID).First(&notification).Error)
+	assert.Equal(t, b.field.TenantID, notification.TenantID)
+
+	notifications, _, err := notificationService.WithContext(ctxA).GetUserNotifications(waiting.ID, false, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, notifications)
+	_, err = notificationService.WithContext(ctxA).MarkAsRead(waiting.ID, notification.ID)
+	assert.Error(t, err)
+	notifications, _, err = notificationService.WithContext(ctxB).GetUserNotifications(waiting.ID, false, ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 1)
+
+	history, err := bookingService.WithContext(ctxA).GetBookingHistory(b.admin.ID, true, booking.ID)
+	assert.Error(t, err)
+	history, err = bookingService.WithContext(ctxB).GetBookingHistory(b.player.ID, false, booking.ID)
+	assert.NoError(t, err)
+	for _, event := range history {
+		assert.Equal(t, b.field.TenantID, event.TenantID)
+	}
+}
+
+func TestTenantIsolation_QuotasAndCheckIns(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	cfg := checkInTestConfig()
+	quotaService := NewQuotaService(db, cfg)
+	checkInService := NewCheckInService(db, cfg)
+
+	a := newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+
+	now := time.Now()
+	upcoming := models.Booking{UserID: b.player.ID, FieldID: b.field.ID, StartTime: now.Add(10 * time.Minute), EndTime: now.Add(70 * time.Minute), Status: models.StatusPaid}
+	missed := models.Booking{UserID: b.player.ID, FieldID: b.field.ID, StartTime: now.Add(-30 * time.Minute), EndTime: now.Add(30 * time.Minute), Status: models.StatusPaid}
+	db.WithContext(ctxB).Create(&upcoming)
+	db.WithContext(ctxB).Create(&missed)
+
+	quotas, err := quotaService.WithContext(ctxB).GetUserQuotas(b.player.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(2), quotas.ActiveBookings.Used)
+	quotas, err = quotaService.WithContext(ctxA).GetUserQuotas(b.player.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), quotas.ActiveBookings.Used)
+
+	code := checkInService.CheckInCode(upcoming.ID)
+	_, err = checkInService.WithContext(ctxA).CheckIn(a.admin.ID, CheckInRequest{Code: code})
+	assert.Error(t, err, "the other club's front desk")
+	_, err = checkInService.WithContext(ctxB).CheckIn(b.admin.ID, CheckInRequest{Code: code})
+	assert.NoError(t, err)
+
+	// No-shows are marked by a job without a tenant
+	marked, err := checkInService.MarkNoShows(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, marked)
+
+	var event models.BookingEvent
+	assert.NoError(t, db.Where("booking_id = ? AND to_status = ?", missed.ID, models.StatusNoShow).First(&event).Error)
+	assert.Equal(t, b.field.TenantID, event.TenantID)
+	var notification models.Notification
+	assert.NoError(t, db.Where("booking_id = ?", missed.ID).First(&notification).Error)
+	assert.Equal(t, b.field.TenantID, notification.TenantID)
+}
+
+func TestTenantIsolation_PayoutsAndSubscriptions(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	cfg := bookingTestConfig()
+	bookingService := NewBookingService(db, cfg)
+	paymentService := NewPaymentService(db)
+	membershipService := NewMembershipService(db, cfg)
+	payoutService := NewPayoutService(db, cfg)
+
+	newTenantFixture(ctxA, db)
+	b := newTenantFixture(ctxB, db)
+
+	plan, err := membershipService.WithContext(ctxB).CreatePlan(CreatePlanRequest{Name: "Gold", Type: models.PlanMembership, Price: 500000, DurationDays: 30})
+	assert.NoError(t, err)
+	subscription, err := membershipService.WithContext(ctxB).Subscribe(b.player.ID, SubscribeRequest{PlanID: plan.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	assert.Equal(t, b.field.TenantID, subscription.TenantID)
+	subscriptions, _, err := membershipService.WithContext(ctxA).GetUserSubscriptions(b.player.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, subscriptions)
+	_, err = membershipService.WithContext(ctxA).CancelRenewal(b.player.ID, subscription.ID)
+	assert.Error(t, err)
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleOwner}
+	db.WithContext(ctxB).Create(&owner)
+	owned := models.Field{Name: "Owner Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung", OwnerID: &owner.ID, PayoutSchedule: models.PayoutWeekly}
+	db.WithContext(ctxB).Create(&owned)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.WithContext(ctxB).CreateBooking(b.player.ID, CreateBookingRequest{FieldID: owned.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	_, err = paymentService.WithContext(ctxB).ProcessPayment(b.player.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	earnings, err := payoutService.WithContext(ctxA).GetEarnings(owner.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, earnings.Earnings)
+	earnings, err = payoutService.WithContext(ctxB).GetEarnings(owner.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, earnings.Earnings, 1) {
+		assert.Equal(t, owned.TenantID, earnings.Earnings[0].TenantID)
+	}
+
+	_, err = payoutService.WithContext(ctxB).SetPayoutAccount(owner.ID, PayoutAccountRequest{HolderName: "Owner", IBAN: "DE89370400440532013000"})
+	assert.NoError(t, err)
+
+	// Batches span the platform, but each payout belongs to its owner's club
+	batch, err := payoutService.GeneratePayoutBatch(time.Now().AddDate(0, 0, 7))
+	assert.NoError(t, err)
+	if assert.Len(t, batch.Payouts, 1) {
+		assert.Equal(t, owned.TenantID, batch.Payouts[0].TenantID)
+	}
+	payouts, _, err := payoutService.WithContext(ctxA).GetPayouts(owner.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, payouts)
+	payouts, _, err = payoutService.WithContext(ctxB).GetPayouts(owner.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, payouts, 1)
+}
+
+func TestTenantIsolation_Scoping(t *testing.T) {
+	db := setupTenantTestDB()
+	ctxA, ctxB := tenantContexts(t, db)
+	tenantA, _ := tenant.FromContext(ctxA)
+	tenantB, _ := tenant.FromContext(ctxB)
+
+	// Rows are stamped with the context's tenant, whatever the caller set
+	field := models.Field{TenantID: tenantB, Name: "Smuggled", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.WithContext(ctxA).Create(&field)
+	assert.Equal(t, tenantA, field.TenantID)
+
+	// Updates and deletes cannot reach across tenants either
+	result := db.WithContext(ctxB).Model(&models.Field{}).Where("id = ?", field.ID).Update("name", "Renamed")
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+	result = db.WithContext(ctxB).Delete(&models.Field{}, field.ID)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	var count int64
+	db.WithContext(ctxB).Model(&models.Field{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// Background jobs run without a tenant and see everyone
+	db.WithContext(ctxB).Create(&models.Field{Name: "South Court", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"})
+	db.Model(&models.Field{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
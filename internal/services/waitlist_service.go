@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+type WaitlistService struct {
+	db *gorm.DB
+}
+
+func NewWaitlistService(db *gorm.DB) *WaitlistService {
+	return &WaitlistService{db: db}
+}
+
+func (s *WaitlistService) WithContext(ctx context.Context) *WaitlistService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// JoinWaitlistRequest gives the slot like CreateBookingRequest, as instants
+// or as wall clock times in the field's timezone.
+type JoinWaitlistRequest struct {
+	FieldID        uint      `json:"field_id" validate:"required"`
+	StartTime      time.Time `json:"start_time"`
+	EndTime        time.Time `json:"end_time"`
+	LocalStartTime string    `json:"local_start_time"`
+	LocalEndTime   string    `json:"local_end_time"`
+}
+
+func (s *WaitlistService) JoinWaitlist(userID uint, req JoinWaitlistRequest) (*models.WaitlistEntry, error) {
+	var field models.Field
+	if err := s.db.First(&field, req.FieldID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("field not found")
+		}
+		return nil, err
+	}
+
+	if err := resolveSlotTimes(field, &req.StartTime, &req.EndTime, req.LocalStartTime, req.LocalEndTime); err != nil {
+		return nil, err
+	}
+	if !req.EndTime.After(req.StartTime) {
+		return nil, errors.New("end time must be after start time")
+	}
+	if !req.StartTime.After(time.Now()) {
+		return nil, errors.New("cannot join the waitlist for a slot in the past")
+	}
+
+	// The waitlist is only for slots that cannot be booked right now
+	if !isSlotTaken(s.db, req.FieldID, req.StartTime, req.EndTime) && !isSlotOffered(s.db, req.FieldID, req.StartTime, req.EndTime, userID) {
+		return nil, errors.New("time slot is available, book it directly")
+	}
+
+	var count int64
+	s.db.Model(&models.Booking{}).Where(
+		"user_id = ? AND field_id = ? AND status NOT IN ? AND start_time < ? AND end_time > ?",
+		userID, req.FieldID, models.InactiveBookingStatuses, req.EndTime, req.StartTime,
+	).Count(&count)
+	if count > 0 {
+		return nil, errors.New("you already have a booking for this time slot")
+	}
+
+	s.db.Model(&models.WaitlistEntry{}).Where(
+		"user_id = ? AND field_id = ? AND start_time = ? AND end_time = ? AND status IN ?",
+		userID, req.FieldID, req.StartTime, req.EndTime, []models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered},
+	).Count(&count)
+	if count > 0 {
+		return nil, errors.New("already on the waitlist for this time slot")
+	}
+
+	entry := models.WaitlistEntry{
+		UserID:    userID,
+		FieldID:   req.FieldID,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Status:    models.WaitlistWaiting,
+	}
+
+	if err := s.db.Create(&entry).Error; err != nil {
+		return nil, err
+	}
+
+	entry.Field = field
+	entry.Position = s.position(entry)
+
+	return &entry, nil
+}
+
+// waitlistListSpec lists entries soonest slot first. Their time is the start
+// of the slot waited for.
+var waitlistListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "start_time": "start_time", "created_at": "created_at"},
+	defaultSort: "start_time",
+	status:      "status",
+	time:        "start_time",
+	upcoming:    "end_time",
+}
+
+// GetUserWaitlist lists the user's waiting and offered entries, or those in
+// the statuses the query asks for.
+func (s *WaitlistService) GetUserWaitlist(userID uint, q ListQuery) ([]models.WaitlistEntry, *utils.Pagination, error) {
+	query := s.db.Preload("Field").Where("user_id = ?", userID)
+	if q.Status == "" {
+		query = query.Where("status IN ?", []models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered})
+	}
+
+	var entries []models.WaitlistEntry
+	page, err := q.list(query, waitlistListSpec, &entries)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range entries {
+		entries[i].Position = s.position(entries[i])
+	}
+
+	return entries, page, nil
+}
+
+func (s *WaitlistService) LeaveWaitlist(userID, id uint) error {
+	result := s.db.Model(&models.WaitlistEntry{}).
+		Where("id = ? AND user_id = ? AND status IN ?", id, userID, []models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered}).
+		Update("status", models.WaitlistCancelled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("waitlist entry not found")
+	}
+	return nil
+}
+
+// ProcessWaitlist lapses offers whose claim window has passed and offers
+// every freed slot to the next user in line. It returns the number of new
+// offers made.
+func (s *WaitlistService) ProcessWaitlist(claimWindow time.Duration) (int, error) {
+	now := time.Now()
+
+	if err := s.db.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND claim_expires_at < ?", models.WaitlistOffered, now).
+		Update("status", models.WaitlistLapsed).Error; err != nil {
+		return 0, err
+	}
+
+	// Entries for slots that already started are no longer useful
+	if err := s.db.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND start_time <= ?", models.WaitlistWaiting, now).
+		Update("status", models.WaitlistLapsed).Error; err != nil {
+		return 0, err
+	}
+
+	var waiting []models.WaitlistEntry
+	if err := s.db.Preload("Field").
+		Where("status = ?", models.WaitlistWaiting).
+		Order("id ASC").
+		Find(&waiting).Error; err != nil {
+		return 0, err
+	}
+
+	offered := 0
+	for _, entry := range waiting {
+		if isSlotTaken(s.db, entry.FieldID, entry.StartTime, entry.EndTime) ||
+			isSlotOffered(s.db, entry.FieldID, entry.StartTime, entry.EndTime, 0) {
+			continue
+		}
+
+		expiresAt := now.Add(claimWindow)
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.WaitlistEntry{}).
+				Where("id = ? AND status = ?", entry.ID, models.WaitlistWaiting).
+				Updates(map[string]interface{}{
+					"status":           models.WaitlistOffered,
+					"offered_at":       now,
+					"claim_expires_at": expiresAt,
+				})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			fieldID, start, end := entry.FieldID, entry.StartTime, entry.EndTime
+			key := fmt.Sprintf("waitlist-offer:%d", entry.ID)
+			loc := entry.Field.Zone()
+			return notify(tx, &models.Notification{
+				TenantID:  entry.TenantID,
+				UserID:    entry.UserID,
+				Type:      models.NotificationWaitlistOffer,
+				Message:   fmt.Sprintf("%s is free from %s to %s. Book it before %s to claim your waitlist spot", entry.Field.Name, start.In(loc).Format(time.RFC3339), end.In(loc).Format(time.RFC3339), expiresAt.In(loc).Format(time.RFC3339)),
+				FieldID:   &fieldID,
+				StartTime: &start,
+				EndTime:   &end,
+				DedupeKey: &key,
+			})
+		})
+		if err != nil {
+			return offered, err
+		}
+		offered++
+	}
+
+	return offered, nil
+}
+
+// position returns the 1-based place of an entry among the users still
+// waiting for the same slot. Offered entries are at the front of the line.
+func (s *WaitlistService) position(entry models.WaitlistEntry) int {
+	if entry.Status == models.WaitlistOffered {
+		return 1
+	}
+
+	var ahead int64
+	s.db.Model(&models.WaitlistEntry{}).Where(
+		"field_id = ? AND start_time = ? AND end_time = ? AND status IN ? AND id < ?",
+		entry.FieldID, entry.StartTime, entry.EndTime,
+		[]models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered}, entry.ID,
+	).Count(&ahead)
+	return int(ahead) + 1
+}
+
+// isSlotOffered reports whether an overlapping slot is currently reserved
+// for a waitlisted user other than exceptUserID.
+func isSlotOffered(db *gorm.DB, fieldID uint, start, end time.Time, exceptUserID uint) bool {
+	var count int64
+	db.Model(&models.WaitlistEntry{}).Where(
+		"field_id = ? AND status = ? AND claim_expires_at > ? AND user_id != ? AND start_time < ? AND end_time > ?",
+		fieldID, models.WaitlistOffered, time.Now(), exceptUserID, end, start,
+	).Count(&count)
+	return count > 0
+}
+
+// claimWaitlistOffer marks the booking user's offer for the same slot as
+// claimed, if they had one.
+func claimWaitlistOffer(tx *gorm.DB, booking *models.Booking) error {
+	return tx.Model(&models.WaitlistEntry{}).
+		Where("user_id = ? AND field_id = ? AND status = ? AND start_time < ? AND end_time > ?",
+			booking.UserID, booking.FieldID, models.WaitlistOffered, booking.EndTime, booking.StartTime).
+		Updates(map[string]interface{}{
+			"status":     models.WaitlistClaimed,
+			"booking_id": booking.ID,
+		}).Error
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupWaitlistTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.WaitlistEntry{}, &models.Notification{}, &models.PaymentSplit{}, &models.MembershipPlan{}, &models.Subscription{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestWaitlistService_Flow(t *testing.T) {
+	db := setupWaitlistTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+	waitlistService := NewWaitlistService(db)
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	db.Create(&owner)
+	first := models.User{Email: "first@example.com", Name: "First", Role: models.RoleUser}
+	db.Create(&first)
+	second := models.User{Email: "second@example.com", Name: "Second", Role: models.RoleUser}
+	db.Create(&second)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	endTime := startTime.Add(time.Hour)
+	slot := JoinWaitlistRequest{FieldID: field.ID, StartTime: startTime, EndTime: endTime}
+
+	// Free slots cannot be waitlisted
+	_, err := waitlistService.JoinWaitlist(first.ID, slot)
+	assert.Error(t, err)
+
+	booking, err := bookingService.CreateBooking(owner.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: endTime})
+	assert.NoError(t, err)
+
+	_, err = bookingService.CreateBooking(first.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: endTime})
+	assert.ErrorIs(t, err, ErrSlotTaken)
+
+	firstEntry, err := waitlistService.JoinWaitlist(first.ID, slot)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, firstEntry.Position)
+
+	secondEntry, err := waitlistService.JoinWaitlist(second.ID, slot)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, secondEntry.Position)
+
+	_, err = waitlistService.JoinWaitlist(second.ID, slot)
+	assert.Error(t, err, "duplicate entries are rejected")
+
+	// Nothing to offer while the slot is still booked
+	offered, err := waitlistService.ProcessWaitlist(30 * time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, offered)
+
+	_, err = bookingService.CancelBooking(owner.ID, booking.ID)
+	assert.NoError(t, err)
+
+	offered, err = waitlistService.ProcessWaitlist(30 * time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, offered)
+
+	entries, _, err := waitlistService.GetUserWaitlist(first.ID, ListQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, models.WaitlistOffered, entries[0].Status)
+
+	// The claim window is exclusive to the first user in line
+	_, err = bookingService.CreateBooking(second.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: endTime})
+	assert.Error(t, err)
+
+	// Let the first offer lapse so the next user gets the slot
+	db.Model(&models.WaitlistEntry{}).Where("id = ?", firstEntry.ID).Update("claim_expires_at", time.Now().Add(-time.Minute))
+
+	offered, err = waitlistService.ProcessWaitlist(30 * time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, offered)
+
+	claimed, err := bookingService.CreateBooking(second.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: endTime})
+	assert.NoError(t, err)
+
+	db.First(&firstEntry, firstEntry.ID)
+	db.First(&secondEntry, secondEntry.ID)
+	assert.Equal(t, models.WaitlistLapsed, firstEntry.Status)
+	assert.Equal(t, models.WaitlistClaimed, secondEntry.Status)
+	assert.Equal(t, claimed.ID, *secondEntry.BookingID)
+
+	var notifications int64
+	db.Model(&models.Notification{}).Where("type = ?", models.NotificationWaitlistOffer).Count(&notifications)
+	assert.Equal(t, int64(2), notifications)
+}
+
+func TestWaitlistService_RescheduledSlot(t *testing.T) {
+	db := setupWaitlistTestDB()
+	bookingService := NewBookingService(db, bookingTestConfig())
+	waitlistService := NewWaitlistService(db)
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	db.Create(&owner)
+	waiting := models.User{Email: "waiting@example.com", Name: "Waiting", Role: models.RoleUser}
+	db.Create(&waiting)
+
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Bandung"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(48 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(owner.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+	_, err = waitlistService.JoinWaitlist(waiting.ID, JoinWaitlistRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = bookingService.RescheduleBooking(owner.ID, booking.ID, RescheduleBookingRequest{StartTime: startTime.Add(2 * time.Hour), EndTime: startTime.Add(3 * time.Hour)})
+	assert.NoError(t, err)
+
+	offered, err := waitlistService.ProcessWaitlist(30 * time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, offered, "the slot the booking moved away from is offered")
+}
+
+func TestWaitlistService_LocalTimes(t *testing.T) {
+	db := setupWaitlistTestDB()
+	cfg := bookingTestConfig()
+	cfg.Booking.AdvanceWindow = 0
+	bookingService := NewBookingService(db, cfg)
+	waitlistService := NewWaitlistService(db)
+
+	owner := models.User{Email: "owner@example.com", Name: "Owner", Role: models.RoleUser}
+	db.Create(&owner)
+	waiting := models.User{Email: "waiting@example.com", Name: "Waiting", Role: models.RoleUser}
+	db.Create(&waiting)
+	field := models.Field{Name: "Court 1", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Berlin", Timezone: "Europe/Berlin"}
+	db.Create(&field)
+
+	booking, err := bookingService.CreateBooking(owner.ID, CreateBookingRequest{FieldID: field.ID, LocalStartTime: "2030-03-31T20:00", LocalEndTime: "2030-03-31T21:00"})
+	assert.NoError(t, err)
+
+	// Joined with the same wall clock times the slot was booked with
+	entry, err := waitlistService.JoinWaitlist(waiting.ID, JoinWaitlistRequest{FieldID: field.ID, LocalStartTime: "2030-03-31T20:00", LocalEndTime: "2030-03-31T21:00"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2030, 3, 31, 18, 0, 0, 0, time.UTC), entry.StartTime)
+	assert.Equal(t, time.Date(2030, 3, 31, 19, 0, 0, 0, time.UTC), entry.EndTime)
+
+	// 02:30 is skipped that night
+	_, err = waitlistService.JoinWaitlist(waiting.ID, JoinWaitlistRequest{FieldID: field.ID, LocalStartTime: "2030-03-31T02:30", LocalEndTime: "2030-03-31T03:30"})
+	var ruleErr *BookingRuleError
+	if assert.ErrorAs(t, err, &ruleErr) {
+		assert.Equal(t, "local_time", ruleErr.Violations[0].Rule)
+	}
+
+	// The offer tells the slot in the field's own time
+	_, err = bookingService.CancelBooking(owner.ID, booking.ID)
+	assert.NoError(t, err)
+	offered, err := waitlistService.ProcessWaitlist(30 * time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, offered)
+
+	var notification models.Notification
+	db.Where("user_id = ? AND type = ?", waiting.ID, models.NotificationWaitlistOffer).First(&notification)
+	assert.Contains(t, notification.Message, "from 2030-03-31T20:00:00+02:00 to 2030-03-31T21:00:00+02:00")
+}
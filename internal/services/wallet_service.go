@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/config"
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/qolby/sports-booking-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient wallet balance")
+
+type WalletService struct {
+	db  *gorm.DB
+	cfg *config.Config
+}
+
+func NewWalletService(db *gorm.DB, cfg *config.Config) *WalletService {
+	return &WalletService{db: db, cfg: cfg}
+}
+
+func (s *WalletService) WithContext(ctx context.Context) *WalletService {
+	scoped := *s
+	scoped.db = s.db.WithContext(ctx)
+	return &scoped
+}
+
+// TopUpRequest loads Amount minor units of Currency. The currency defaults to
+// the wallet's own, and a wallet only ever holds one currency.
+type TopUpRequest struct {
+	Amount        int64  `json:"amount" validate:"required,gt=0"`
+	Currency      string `json:"currency"`
+	PaymentMethod string `json:"payment_method" validate:"required"`
+}
+
+// WalletAdjustmentRequest moves the balance by Amount minor units of the
+// wallet's currency.
+type WalletAdjustmentRequest struct {
+	Amount int64  `json:"amount" validate:"required"`
+	Reason string `json:"reason" validate:"required"`
+}
+
+var walletTransactionListSpec = listSpec{
+	sorts:       map[string]string{"id": "id", "created_at": "created_at"},
+	defaultSort: "-id",
+	time:        "created_at",
+}
+
+// GetWallet returns the user's wallet and its balance. A wallet is opened on
+// first use.
+func (s *WalletService) GetWallet(userID uint) (*models.Wallet, error) {
+	return openWallet(s.db, userID)
+}
+
+// GetTransactions lists the ledger of the user's wallet, newest first. A user
+// without a wallet has no transactions.
+func (s *WalletService) GetTransactions(userID uint, q ListQuery) ([]models.WalletTransaction, *utils.Pagination, error) {
+	var transactions []models.WalletTransaction
+	query := s.db.Where("wallet_id IN (?)", s.db.Model(&models.Wallet{}).Select("id").Where("user_id = ?", userID))
+	page, err := q.list(query, walletTransactionListSpec, &transactions)
+	if err != nil {
+		return nil, nil, err
+	}
+	return transactions, page, nil
+}
+
+// TopUp loads credit into the user's wallet through the mock gateway.
+func (s *WalletService) TopUp(userID uint, req TopUpRequest) (*models.WalletTransaction, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("top-up amount must be positive")
+	}
+	if req.PaymentMethod == "" || req.PaymentMethod == models.PaymentMethodWallet {
+		return nil, errors.New("a gateway payment method is required")
+	}
+	currency, err := s.walletCurrency(userID, req.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *models.WalletTransaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = postWalletTransaction(tx, userID, walletEntry{
+			Type:        models.WalletTopUp,
+			Amount:      models.NewMoney(req.Amount, currency),
+			Description: fmt.Sprintf("Top-up via %s", req.PaymentMethod),
+		})
+		if err != nil {
+			return err
+		}
+		return postWalletJournal(tx, entry, models.AccountGatewayClearing)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// AdjustBalance credits (positive amount) or debits (negative amount) a
+// user's wallet by hand. Debits cannot take the balance below zero.
+func (s *WalletService) AdjustBalance(adminID, userID uint, req WalletAdjustmentRequest) (*models.WalletTransaction, error) {
+	if req.Amount == 0 {
+		return nil, errors.New("adjustment amount cannot be zero")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, errors.New("adjustment reason is required")
+	}
+	if err := s.db.First(&models.User{}, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	currency, err := s.walletCurrency(userID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *models.WalletTransaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = postWalletTransaction(tx, userID, walletEntry{
+			Type:        models.WalletAdminAdjustment,
+			Amount:      models.NewMoney(req.Amount, currency),
+			Description: req.Reason,
+			CreatedBy:   &adminID,
+		})
+		if err != nil {
+			return err
+		}
+		// Goodwill credits are discounts; debits take them back
+		return postWalletJournal(tx, entry, models.AccountDiscounts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// walletCurrency resolves the currency of an entry for the user's wallet:
+// the given code, else the currency the wallet already holds, else the
+// configured default.
+func (s *WalletService) walletCurrency(userID uint, code string) (string, error) {
+	if code != "" {
+		return models.NormalizeCurrency(code)
+	}
+	wallet, err := openWallet(s.db, userID)
+	if err != nil {
+		return "", err
+	}
+	if wallet.Balance.Currency != "" {
+		return wallet.Balance.Currency, nil
+	}
+	return s.cfg.Currency.Default, nil
+}
+
+// walletEntry describes a ledger entry to post against a user's wallet.
+type walletEntry struct {
+	Type        models.WalletTransactionType
+	Amount      models.Money
+	PaymentID   *uint
+	RefundID    *uint
+	Description string
+	CreatedBy   *uint
+}
+
+// openWallet returns the user's wallet, creating an empty one if needed.
+func openWallet(tx *gorm.DB, userID uint) (*models.Wallet, error) {
+	var wallet models.Wallet
+	err := tx.Where("user_id = ?", userID).First(&wallet).Error
+	if err == nil {
+		return &wallet, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// The wallet belongs to its user's tenant, also when a job opens it
+	var user models.User
+	if err := tx.Select("id", "tenant_id").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	wallet = models.Wallet{TenantID: user.TenantID, UserID: userID}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&wallet).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// postWalletTransaction appends an entry to the user's wallet ledger and
+// moves the balance. The wallet row is locked for the rest of the
+// transaction, so concurrent charges cannot overdraw it.
+func postWalletTransaction(tx *gorm.DB, userID uint, entry walletEntry) (*models.WalletTransaction, error) {
+	if _, err := openWallet(tx, userID); err != nil {
+		return nil, err
+	}
+
+	var wallet models.Wallet
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return nil, err
+	}
+
+	// The first entry fixes the wallet's currency
+	if !wallet.Balance.SameCurrency(entry.Amount) {
+		return nil, fmt.Errorf("%w: wallet holds %s", models.ErrCurrencyMismatch, wallet.Balance.Currency)
+	}
+	balance := wallet.Balance.Add(entry.Amount)
+	if balance.Amount < 0 {
+		return nil, ErrInsufficientBalance
+	}
+
+	var seq int64
+	tx.Model(&models.WalletTransaction{}).Where("wallet_id = ?", wallet.ID).Count(&seq)
+
+	transaction := models.WalletTransaction{
+		TenantID:     wallet.TenantID,
+		WalletID:     wallet.ID,
+		Type:         entry.Type,
+		Amount:       entry.Amount,
+		BalanceAfter: balance,
+		PaymentID:    entry.PaymentID,
+		RefundID:     entry.RefundID,
+		Reference:    fmt.Sprintf("WLT-%d-%d-%d", wallet.ID, seq+1, time.Now().Unix()),
+		Description:  entry.Description,
+		CreatedBy:    entry.CreatedBy,
+	}
+	if err := tx.Create(&transaction).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&wallet).Updates(map[string]interface{}{"balance_amount": balance.Amount, "balance_currency": balance.Currency}).Error; err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
+
+// postWalletJournal books a wallet entry that moves money between the
+// customer's wallet and the given account, e.g. a top-up from the gateway.
+func postWalletJournal(tx *gorm.DB, entry *models.WalletTransaction, from models.LedgerAccount) error {
+	j := newJournal(fmt.Sprintf("wallet:%d", entry.ID), fmt.Sprintf("Wallet %s: %s", entry.Reference, entry.Description))
+	j.entry.WalletTransactionID = &entry.ID
+	j.post(from, models.AccountCustomerWallets, entry.Amount)
+	return postJournal(tx, j)
+}
+
+// chargeWallet debits a wallet payment, or a follow-up charge on one, from
+// the payer's wallet.
+func chargeWallet(tx *gorm.DB, payment *models.Payment, amount models.Money, description string) error {
+	if payment.UserID == nil {
+		return errors.New("wallet payment has no payer")
+	}
+	_, err := postWalletTransaction(tx, *payment.UserID, walletEntry{
+		Type:        models.WalletBookingCharge,
+		Amount:      amount.Neg(),
+		PaymentID:   &payment.ID,
+		Description: description,
+	})
+	return err
+}
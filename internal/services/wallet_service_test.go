@@ -0,0 +1,144 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/qolby/sports-booking-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupWalletTestDB() *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect to test database")
+	}
+
+	db.AutoMigrate(&models.User{}, &models.Field{}, &models.Booking{}, &models.BookingEvent{}, &models.BookingReschedule{}, &models.WaitlistEntry{}, &models.Payment{}, &models.PaymentAdjustment{},
+		&models.Refund{}, &models.PaymentSplit{}, &models.Wallet{}, &models.WalletTransaction{}, &models.MembershipPlan{}, &models.Subscription{}, &models.Invoice{}, &models.InvoiceLine{}, &models.InvoiceSequence{}, &models.TaxRate{}, &models.JournalEntry{}, &models.JournalLine{}, &models.OwnerEarning{})
+
+	return db
+}
+
+func TestWalletService_TopUpAndAdjust(t *testing.T) {
+	db := setupWalletTestDB()
+	walletService := NewWalletService(db, bookingTestConfig())
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+
+	_, err := walletService.TopUp(user.ID, TopUpRequest{Amount: 0, PaymentMethod: "credit_card"})
+	assert.Error(t, err)
+	_, err = walletService.TopUp(user.ID, TopUpRequest{Amount: 100000, PaymentMethod: models.PaymentMethodWallet})
+	assert.Error(t, err)
+
+	entry, err := walletService.TopUp(user.ID, TopUpRequest{Amount: 100000, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100000), entry.BalanceAfter.Amount)
+
+	_, err = walletService.AdjustBalance(1, user.ID, WalletAdjustmentRequest{Amount: -150000, Reason: "chargeback"})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	entry, err = walletService.AdjustBalance(1, user.ID, WalletAdjustmentRequest{Amount: -30000, Reason: "chargeback"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(70000), entry.BalanceAfter.Amount)
+
+	wallet, err := walletService.GetWallet(user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(70000), wallet.Balance.Amount)
+
+	transactions, page, err := walletService.GetTransactions(user.ID, ListQuery{PerPage: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *page.Total)
+	if assert.Len(t, transactions, 1) {
+		assert.Equal(t, models.WalletAdminAdjustment, transactions[0].Type)
+	}
+
+	transactions, _, err = walletService.GetTransactions(user.ID+1, ListQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, transactions, "another user's wallet")
+}
+
+func TestPaymentService_PayWithWallet(t *testing.T) {
+	db := setupWalletTestDB()
+	walletService := NewWalletService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+
+	field := models.Field{Name: "Test Field", PricePerHour: models.NewMoney(100000, "IDR"), Location: "Test Location"}
+	db.Create(&field)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(2 * time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = walletService.TopUp(user.ID, TopUpRequest{Amount: 150000, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: models.PaymentMethodWallet})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	var payments int64
+	db.Model(&models.Payment{}).Count(&payments)
+	assert.Equal(t, int64(0), payments, "a failed wallet charge leaves no payment behind")
+
+	_, err = walletService.TopUp(user.ID, TopUpRequest{Amount: 50000, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+
+	payment, err := paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: models.PaymentMethodWallet})
+	assert.NoError(t, err)
+
+	wallet, _ := walletService.GetWallet(user.ID)
+	assert.Equal(t, int64(0), wallet.Balance.Amount)
+	transactions, _, _ := walletService.GetTransactions(user.ID, ListQuery{})
+	assert.Equal(t, models.WalletBookingCharge, transactions[0].Type)
+	assert.Equal(t, int64(-200000), transactions[0].Amount.Amount)
+
+	refund, err := paymentService.RefundPayment(1, payment.ID, RefundRequest{Amount: 80000, Reason: "court maintenance"})
+	assert.NoError(t, err)
+	assert.True(t, refund.ToWallet)
+
+	wallet, _ = walletService.GetWallet(user.ID)
+	assert.Equal(t, int64(80000), wallet.Balance.Amount)
+	transactions, _, _ = walletService.GetTransactions(user.ID, ListQuery{})
+	assert.Equal(t, models.WalletRefundCredit, transactions[0].Type)
+}
+
+func TestPaymentService_WalletCurrency(t *testing.T) {
+	db := setupWalletTestDB()
+	walletService := NewWalletService(db, bookingTestConfig())
+	paymentService := NewPaymentService(db)
+	bookingService := NewBookingService(db, bookingTestConfig())
+
+	user := models.User{Email: "player@example.com", Name: "Player", Role: models.RoleUser}
+	db.Create(&user)
+
+	field := models.Field{Name: "Singapore Court", PricePerHour: models.NewMoney(3000, "SGD"), Location: "Singapore"}
+	db.Create(&field)
+
+	_, err := walletService.TopUp(user.ID, TopUpRequest{Amount: 100000, Currency: "XYZ", PaymentMethod: "credit_card"})
+	assert.Error(t, err, "unknown currency")
+
+	// The wallet takes the default currency on its first top-up
+	entry, err := walletService.TopUp(user.ID, TopUpRequest{Amount: 500000, PaymentMethod: "credit_card"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(500000, "IDR"), entry.BalanceAfter)
+
+	_, err = walletService.TopUp(user.ID, TopUpRequest{Amount: 5000, Currency: "sgd", PaymentMethod: "credit_card"})
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+
+	startTime := time.Now().Add(72 * time.Hour).Truncate(time.Hour)
+	booking, err := bookingService.CreateBooking(user.ID, CreateBookingRequest{FieldID: field.ID, StartTime: startTime, EndTime: startTime.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	_, err = paymentService.ProcessPayment(user.ID, CreatePaymentRequest{BookingID: booking.ID, PaymentMethod: models.PaymentMethodWallet})
+	assert.ErrorIs(t, err, models.ErrCurrencyMismatch)
+
+	wallet, _ := walletService.GetWallet(user.ID)
+	assert.Equal(t, models.NewMoney(500000, "IDR"), wallet.Balance)
+}
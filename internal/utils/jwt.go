@@ -9,17 +9,19 @@ import (
 )
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID   uint   `json:"user_id"`
+	TenantID uint   `json:"tenant_id"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID uint, email, role string, cfg *config.Config) (string, error) {
+func GenerateToken(userID, tenantID uint, email, role string, cfg *config.Config) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:   userID,
+		TenantID: tenantID,
+		Email:    email,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(cfg.JWT.Expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
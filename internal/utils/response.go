@@ -3,10 +3,24 @@ package utils
 import "github.com/gofiber/fiber/v2"
 
 type Response struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success    bool        `json:"success"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	Details    interface{} `json:"details,omitempty"`
+}
+
+// Pagination tells clients where a page sits in its list. Offset pages
+// give their Page with the Total number of items and pages; any page with
+// more after it gives the NextCursor to fetch it with.
+type Pagination struct {
+	Page       int    `json:"page,omitempty"`
+	PerPage    int    `json:"per_page"`
+	Total      *int64 `json:"total,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func SuccessResponse(c *fiber.Ctx, status int, message string, data interface{}) error {
@@ -17,6 +31,16 @@ func SuccessResponse(c *fiber.Ctx, status int, message string, data interface{})
 	})
 }
 
+// PaginatedResponse is a SuccessResponse for one page of a list.
+func PaginatedResponse(c *fiber.Ctx, status int, message string, data interface{}, pagination *Pagination) error {
+	return c.Status(status).JSON(Response{
+		Success:    true,
+		Message:    message,
+		Data:       data,
+		Pagination: pagination,
+	})
+}
+
 func ErrorResponse(c *fiber.Ctx, status int, message string, err error) error {
 	errMsg := ""
 	if err != nil {
@@ -28,3 +52,20 @@ func ErrorResponse(c *fiber.Ctx, status int, message string, err error) error {
 		Error:   errMsg,
 	})
 }
+
+// ErrorDetailsResponse is an ErrorResponse that tells clients more about
+// what went wrong in details.
+func ErrorDetailsResponse(c *fiber.Ctx, status int, message string, err error, details interface{}) error {
+	return c.Status(status).JSON(Response{
+		Success: false,
+		Message: message,
+		Error:   err.Error(),
+		Details: details,
+	})
+}
+
+// ValidationErrorResponse rejects a request with 422 and a list of what is
+// wrong with it in details.
+func ValidationErrorResponse(c *fiber.Ctx, message string, err error, details interface{}) error {
+	return ErrorDetailsResponse(c, fiber.StatusUnprocessableEntity, message, err, details)
+}